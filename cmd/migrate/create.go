@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var migrationName = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// create scaffolds an empty up/down migration pair versioned with the
+// current UTC time, so branches developed in parallel cannot pick the same
+// version. --sequential continues the six-digit sequence of the existing
+// files instead.
+func create(defaultDir string, args []string) {
+	fset := flag.NewFlagSet("create", flag.ExitOnError)
+	sequential := fset.Bool("sequential", false, "version the migration with the next number instead of the current UTC time")
+	dir := fset.String("dir", defaultDir, "migrations directory")
+	_ = fset.Parse(args)
+
+	if fset.NArg() != 1 || !migrationName.MatchString(fset.Arg(0)) {
+		panic("usage: go run ./cmd/migrate create [--sequential] [--dir migrations] <snake_case_name>")
+	}
+	name := fset.Arg(0)
+
+	var version string
+	if *sequential {
+		latest, err := latestVersion(*dir)
+		if err != nil {
+			panic(err)
+		}
+		version = fmt.Sprintf("%06d", latest+1)
+	} else {
+		version = time.Now().UTC().Format("20060102150405")
+	}
+
+	var created []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(*dir, version+"_"+name+"."+direction+".sql")
+		// O_EXCL so an existing file is never overwritten.
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			panic(err)
+		}
+		if err := f.Close(); err != nil {
+			panic(err)
+		}
+		created = append(created, path)
+	}
+	if jsonOutput {
+		emit(map[string]any{"version": version, "created": created})
+		return
+	}
+	for _, path := range created {
+		fmt.Println("created " + path)
+	}
+}
+
+// latestVersion returns the highest version among the migration files in
+// dir, or 0 when there are none.
+func latestVersion(dir string) (uint64, error) {
+	files, err := listMigrations(os.DirFS(dir))
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+	return files[len(files)-1].Version, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// plan returns the migrations that up or down would run from the current
+// version, in the order they would run. limit caps how many; zero means
+// all.
+func plan(m *migrate.Migrate, fsys fs.FS, direction string, limit int) ([]migrationFile, error) {
+	files, err := listMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	current, dirty, err := m.Version()
+	applied := err == nil
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("database is dirty at version %d; fix it and run force first", current)
+	}
+
+	var out []migrationFile
+	if direction == "up" {
+		for _, f := range files {
+			if !applied || uint(f.Version) > current {
+				out = append(out, f)
+			}
+		}
+	} else {
+		for i := len(files) - 1; i >= 0; i-- {
+			if applied && uint(files[i].Version) <= current {
+				out = append(out, files[i])
+			}
+		}
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// printPlan prints the SQL of every migration in files without running
+// it, for review before the real up or down.
+func printPlan(fsys fs.FS, direction string, files []migrationFile) {
+	type step struct {
+		Version uint64 `json:"version"`
+		File    string `json:"file"`
+		SQL     string `json:"sql"`
+	}
+	steps := make([]step, 0, len(files))
+	for _, f := range files {
+		name := f.Up
+		if direction == "down" {
+			name = f.Down
+		}
+		if name == "" {
+			panic(fmt.Sprintf("migration %d has no %s file", f.Version, direction))
+		}
+		sql, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			panic(err)
+		}
+		steps = append(steps, step{f.Version, name, string(sql)})
+	}
+
+	if jsonOutput {
+		emit(struct {
+			Command    string `json:"command"`
+			DryRun     bool   `json:"dry_run"`
+			Migrations []step `json:"migrations"`
+		}{direction, true, steps})
+		return
+	}
+	if len(steps) == 0 {
+		fmt.Println("dry run: no migration changes")
+		return
+	}
+	for _, s := range steps {
+		fmt.Printf("-- %s\n%s\n", s.File, s.SQL)
+	}
+	fmt.Printf("dry run: %d migrations would run %s\n", len(steps), direction)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"backend/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// force records version as applied and clears the dirty flag without running
+// any SQL. It is the recovery path after a migration failed part-way: fix
+// the schema by hand, then force the version it now matches. Unless --yes is
+// given, the operator must type the version again to confirm.
+func force(m *migrate.Migrate, args []string) {
+	fset := flag.NewFlagSet("force", flag.ExitOnError)
+	yes := fset.Bool("yes", false, "skip the confirmation prompt")
+	_ = fset.Parse(args)
+
+	var version int
+	var err error
+	if fset.NArg() == 1 {
+		version, err = strconv.Atoi(fset.Arg(0))
+	}
+	if fset.NArg() != 1 || err != nil || version < -1 {
+		panic("usage: go run ./cmd/migrate force [--yes] <version>, where -1 means no migration applied")
+	}
+
+	current := "none"
+	if v, dirty, err := m.Version(); err == nil {
+		current = fmt.Sprintf("%d (dirty: %t)", v, dirty)
+	} else if !errors.Is(err, migrate.ErrNilVersion) {
+		panic(err)
+	}
+
+	if !*yes && jsonOutput {
+		panic("force --json cannot prompt; pass --yes")
+	}
+	if !*yes {
+		fmt.Printf("The database is at version %s. Forcing version %d runs no SQL;\n", current, version)
+		fmt.Print("the schema must already match it. Type the version again to confirm: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != strconv.Itoa(version) {
+			fmt.Println("aborted")
+			os.Exit(1)
+		}
+	}
+
+	if err := m.Force(version); err != nil {
+		panic(migrations.LockError(err))
+	}
+	log.Printf("migrate: forced version %d (was %s) by %s", version, current, forcedBy())
+	if jsonOutput {
+		emit(struct {
+			Command  string `json:"command"`
+			Previous string `json:"previous"`
+			versionState
+		}{"force", current, currentState(m)})
+	}
+}
+
+// forcedBy names who ran the command, for the log line.
+func forcedBy() string {
+	for _, env := range []string{"USER", "USERNAME"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return "unknown user"
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lintBaseline is the last migration written before lint existed. The
+// statement checks skip it and everything older, which already ran
+// everywhere; the file layout checks still cover them.
+const lintBaseline = 25
+
+// largeTables grow with traffic, so building an index on them without
+// CONCURRENTLY blocks writes for minutes.
+var largeTables = []string{"users", "user_events", "webhook_events", "webhook_deliveries", "memberships"}
+
+// lintRule flags statements matching pattern, unless they also match
+// unless, as taking long or strong locks.
+type lintRule struct {
+	pattern *regexp.Regexp
+	unless  *regexp.Regexp
+	message string
+}
+
+var (
+	concurrently = regexp.MustCompile(`(?i)\bconcurrently\b`)
+	notValid     = regexp.MustCompile(`(?i)\bnot\s+valid\b`)
+)
+
+var lintRules = []lintRule{
+	{
+		regexp.MustCompile(`(?is)^\s*create\s+(unique\s+)?index\b.*?\bon\s+(only\s+)?"?(` + strings.Join(largeTables, "|") + `)\b`),
+		concurrently,
+		"CREATE INDEX on a large table blocks writes while it builds; use CREATE INDEX CONCURRENTLY, alone in its own migration (it cannot run inside the migration's transaction)",
+	},
+	{
+		regexp.MustCompile(`(?is)\balter\s+column\s+\S+\s+(set\s+data\s+)?type\b`),
+		nil,
+		"ALTER COLUMN ... TYPE rewrites the table under an exclusive lock; add a new column, backfill it in batches and switch over instead",
+	},
+	{
+		regexp.MustCompile(`(?is)\balter\s+column\s+\S+\s+set\s+not\s+null\b`),
+		nil,
+		"SET NOT NULL scans the whole table under an exclusive lock; add a CHECK (col IS NOT NULL) NOT VALID constraint, VALIDATE it in a later migration, then set NOT NULL",
+	},
+	{
+		regexp.MustCompile(`(?is)\badd\s+(constraint\s+\S+\s+)?(foreign\s+key|check)\b`),
+		notValid,
+		"adding a FOREIGN KEY or CHECK constraint validates every row under lock; add it NOT VALID and VALIDATE CONSTRAINT in a later migration",
+	},
+	{
+		regexp.MustCompile(`(?is)\badd\s+(constraint\s+\S+\s+)?(primary\s+key|unique)\s*\(`),
+		nil,
+		"adding a PRIMARY KEY or UNIQUE constraint builds its index under an exclusive lock; CREATE UNIQUE INDEX CONCURRENTLY first, then ADD CONSTRAINT ... USING INDEX",
+	},
+	{
+		regexp.MustCompile(`(?is)\badd\s+column\b.*\bdefault\s+(now\(\)|clock_timestamp\(\)|random\(\)|gen_random_uuid\(\)|uuid_generate_v4\(\))`),
+		nil,
+		"ADD COLUMN with a volatile DEFAULT rewrites the table; add the column without a default, then backfill in batches",
+	},
+	{
+		regexp.MustCompile(`(?is)^\s*(vacuum\s+full|cluster|lock\s+table|reindex\s+(table|database))\b`),
+		nil,
+		"this statement holds an exclusive lock for its whole duration; run it as a maintenance task, not a migration",
+	},
+}
+
+// lintIgnore opts a whole file out of the statement checks, for changes
+// that are known to be safe (e.g. a table that is still empty).
+const lintIgnore = "-- lint:ignore"
+
+type lintIssue struct {
+	file    string
+	line    int
+	message string
+}
+
+// lint checks the migration files in dir and exits non-zero when any issue
+// is found: versions that are duplicated or lack an up or down file, and, in
+// migrations after lintBaseline, statements that take long locks.
+func lint(defaultDir string, args []string) {
+	fset := flag.NewFlagSet("lint", flag.ExitOnError)
+	dir := fset.String("dir", defaultDir, "migrations directory")
+	all := fset.Bool("all", false, "also check statements in migrations up to the baseline")
+	_ = fset.Parse(args)
+
+	fsys := os.DirFS(*dir)
+	issues, err := lintFiles(fsys, *all)
+	if err != nil {
+		panic(err)
+	}
+	if jsonOutput {
+		type issue struct {
+			File    string `json:"file"`
+			Line    int    `json:"line,omitempty"`
+			Message string `json:"message"`
+		}
+		out := make([]issue, len(issues))
+		for i, is := range issues {
+			out[i] = issue{is.file, is.line, is.message}
+		}
+		emit(map[string]any{"ok": len(issues) == 0, "issues": out})
+		if len(issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	for _, is := range issues {
+		if is.line > 0 {
+			fmt.Printf("%s:%d: %s\n", is.file, is.line, is.message)
+		} else {
+			fmt.Printf("%s: %s\n", is.file, is.message)
+		}
+	}
+	if len(issues) > 0 {
+		fmt.Printf("%d issues\n", len(issues))
+		os.Exit(1)
+	}
+	fmt.Println("migrations ok")
+}
+
+func lintFiles(fsys fs.FS, all bool) ([]lintIssue, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []lintIssue
+	names := make(map[uint64]map[string]bool)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || !(strings.HasSuffix(e.Name(), ".up.sql") || strings.HasSuffix(e.Name(), ".down.sql")) {
+			issues = append(issues, lintIssue{file: e.Name(), message: "not named <version>_<name>.up.sql or .down.sql, so it is never run"})
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(e.Name(), prefix+"_"), ".sql"), ".up")
+		name = strings.TrimSuffix(name, ".down")
+		if names[v] == nil {
+			names[v] = make(map[string]bool)
+		}
+		names[v][name] = true
+	}
+
+	files, err := listMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		label := fmt.Sprintf("%06d_%s", f.Version, f.Name)
+		if len(names[f.Version]) > 1 {
+			dup := make([]string, 0, len(names[f.Version]))
+			for n := range names[f.Version] {
+				dup = append(dup, n)
+			}
+			sort.Strings(dup)
+			issues = append(issues, lintIssue{file: label, message: fmt.Sprintf("version %d is used by several migrations (%s); renumber all but one", f.Version, strings.Join(dup, ", "))})
+		}
+		if f.Up == "" {
+			issues = append(issues, lintIssue{file: label, message: "missing .up.sql file"})
+		}
+		if f.Down == "" {
+			issues = append(issues, lintIssue{file: label, message: "missing .down.sql file; add one, even if it only documents why the change cannot be reverted"})
+		}
+		if f.Version <= lintBaseline && !all {
+			continue
+		}
+		for _, name := range []string{f.Up, f.Down} {
+			if name == "" {
+				continue
+			}
+			sql, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, lintStatements(name, string(sql))...)
+		}
+	}
+	return issues, nil
+}
+
+// lintStatements applies lintRules to each statement of one file.
+func lintStatements(file, sql string) []lintIssue {
+	if strings.Contains(sql, lintIgnore) {
+		return nil
+	}
+	var issues []lintIssue
+	line := 1
+	for _, stmt := range strings.Split(stripComments(sql), ";") {
+		start := line + strings.Count(stmt, "\n") - strings.Count(strings.TrimLeft(stmt, " \t\r\n"), "\n")
+		line += strings.Count(stmt, "\n")
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		for _, r := range lintRules {
+			if !r.pattern.MatchString(stmt) || r.unless != nil && r.unless.MatchString(stmt) {
+				continue
+			}
+			issues = append(issues, lintIssue{file: file, line: start, message: r.message})
+		}
+	}
+	return issues
+}
+
+// stripComments blanks out -- comments while keeping line breaks, so line
+// numbers still match the file. It does not understand string literals or
+// dollar-quoted bodies, which is good enough for flagging DDL.
+func stripComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, l := range lines {
+		if j := strings.Index(l, "--"); j >= 0 {
+			lines[i] = l[:j]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,34 +2,106 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
+
+	"backend/migrations"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
 )
 
+const usage = "usage: go run ./cmd/migrate [--json] [--lock-timeout 15s] [--table schema_migrations] [--statement-timeout 0]\n" +
+	"       [up [--dry-run]|down [--all --yes] [--dry-run]|steps <N>|goto <version>|force <version>|status|version|create <name>|lint|seed --env <env>]"
+
+// guardDown refuses to revert migrations in production unless the operator
+// passed --allow-production: down scripts drop columns and tables, and their
+// data with them.
+func guardDown(allowProduction bool) {
+	if inProduction() && !allowProduction {
+		panic("refusing to revert migrations with APP_ENV=production; pass --allow-production if this is intended")
+	}
+}
+
+func inProduction() bool {
+	return os.Getenv("APP_ENV") == "production"
+}
+
+// open connects to the database with the embedded migrations, or with
+// sourceURL when it is set. fsys lists the migration files for status; it
+// is nil for sources other than file://.
+func open(sourceURL, dsn string, opts migrations.Options) (*migrate.Migrate, fs.FS, error) {
+	var fsys fs.FS = migrations.FS
+	if sourceURL != "" {
+		fsys = nil
+		if dir, ok := strings.CutPrefix(sourceURL, "file://"); ok {
+			fsys = os.DirFS(dir)
+		}
+	}
+	m, err := migrations.Open(sourceURL, dsn, opts)
+	return m, fsys, err
+}
+
+// Migrations are read from the copy embedded in the binary, so deployments
+// do not need the migrations directory. MIGRATIONS_SOURCE overrides it with
+// any golang-migrate source URL, e.g. file://migrations while editing them.
+//
+// Runs take the postgres driver's advisory lock, so overlapping deploy jobs
+// cannot migrate at once; the later one fails after --lock-timeout
+// (MIGRATIONS_LOCK_TIMEOUT) instead of waiting forever.
 func main() {
+	defer recoverJSON()
 	_ = godotenv.Load()
 
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		panic("DATABASE_URL is required")
+	opts, err := migrations.LoadOptions()
+	if err != nil {
+		panic(err)
 	}
+	flag.DurationVar(&opts.LockTimeout, "lock-timeout", opts.LockTimeout, "how long to wait for a concurrent migration's lock")
+	flag.StringVar(&opts.Table, "table", opts.Table, "version table (default schema_migrations)")
+	flag.DurationVar(&opts.StatementTimeout, "statement-timeout", opts.StatementTimeout, "cap on each migration statement; 0 means none")
+	flag.BoolVar(&jsonOutput, "json", false, "print the result as one JSON object")
+	flag.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	flag.Parse()
 
 	sourceURL := os.Getenv("MIGRATIONS_SOURCE")
-	if sourceURL == "" {
-		sourceURL = "file://migrations"
-	}
 
 	command := "up"
-	if len(os.Args) > 1 {
-		command = os.Args[1]
+	var args []string
+	if flag.NArg() > 0 {
+		command, args = flag.Arg(0), flag.Args()[1:]
+	}
+	// create and lint work on the migration files being written, not the
+	// embedded copy.
+	if command == "create" || command == "lint" {
+		dir := "migrations"
+		if strings.HasPrefix(sourceURL, "file://") {
+			dir = strings.TrimPrefix(sourceURL, "file://")
+		}
+		if command == "create" {
+			create(dir, args)
+		} else {
+			lint(dir, args)
+		}
+		return
+	}
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		panic("DATABASE_URL is required")
 	}
 
-	m, err := migrate.New(sourceURL, dsn)
+	if command == "seed" {
+		seed(dsn, args)
+		return
+	}
+
+	m, fsys, err := open(sourceURL, dsn, opts)
 	if err != nil {
 		panic(err)
 	}
@@ -44,33 +116,121 @@ func main() {
 	}()
 
 	switch command {
-	case "up":
-		err = m.Up()
-	case "down":
-		err = m.Down()
-	case "version":
-		version, dirty, vErr := m.Version()
-		if vErr != nil {
-			if errors.Is(vErr, migrate.ErrNilVersion) {
-				fmt.Println("version: none")
-				return
+	case "up", "down":
+		// --dry-run prints the SQL that would run instead of running it.
+		// down reverts one migration unless --all, which also needs --yes.
+		fset := flag.NewFlagSet(command, flag.ExitOnError)
+		dryRun := fset.Bool("dry-run", false, "print the SQL of the migrations that would run, without running them")
+		var all, yes, allowProduction *bool
+		if command == "down" {
+			all = fset.Bool("all", false, "revert every applied migration instead of the last one")
+			yes = fset.Bool("yes", false, "confirm --all")
+			allowProduction = fset.Bool("allow-production", false, "allow reverting with APP_ENV=production")
+		}
+		_ = fset.Parse(args)
+
+		limit := 0
+		if command == "down" && !*all {
+			limit = 1
+		}
+		if *dryRun {
+			if fsys == nil {
+				panic("--dry-run needs the embedded migrations or a file:// MIGRATIONS_SOURCE")
 			}
-			panic(vErr)
+			files, err := plan(m, fsys, command, limit)
+			if err != nil {
+				panic(err)
+			}
+			printPlan(fsys, command, files)
+			return
+		}
+
+		switch {
+		case command == "up":
+			err = m.Up()
+		case *all:
+			guardDown(*allowProduction)
+			if !*yes {
+				panic("down --all reverts every migration and drops their data; add --yes to confirm")
+			}
+			err = m.Down()
+		default:
+			guardDown(*allowProduction)
+			err = m.Steps(-1)
+		}
+	case "steps":
+		// steps N applies the next N migrations, or reverts the last -N.
+		fset := flag.NewFlagSet(command, flag.ExitOnError)
+		allowProduction := fset.Bool("allow-production", false, "allow reverting with APP_ENV=production")
+		_ = fset.Parse(args)
+		var n int
+		if fset.NArg() == 1 {
+			n, err = strconv.Atoi(fset.Arg(0))
+		}
+		if fset.NArg() != 1 || err != nil || n == 0 {
+			panic("usage: go run ./cmd/migrate steps [--allow-production] <N>, where N is a non-zero integer (negative reverts)")
+		}
+		if n < 0 {
+			guardDown(*allowProduction)
+		}
+		err = m.Steps(n)
+	case "goto":
+		// goto V migrates up or down until version V is applied.
+		fset := flag.NewFlagSet(command, flag.ExitOnError)
+		allowProduction := fset.Bool("allow-production", false, "allow reverting with APP_ENV=production")
+		_ = fset.Parse(args)
+		var v uint64
+		if fset.NArg() == 1 {
+			v, err = strconv.ParseUint(fset.Arg(0), 10, 64)
+		}
+		if fset.NArg() != 1 || err != nil {
+			panic("usage: go run ./cmd/migrate goto [--allow-production] <version>")
+		}
+		// Going below the applied version reverts migrations.
+		if current, _, verr := m.Version(); verr == nil && uint(v) < current {
+			guardDown(*allowProduction)
+		}
+		err = m.Migrate(uint(v))
+	case "status":
+		if fsys == nil {
+			panic("status needs the embedded migrations or a file:// MIGRATIONS_SOURCE")
+		}
+		status(m, fsys)
+		return
+	case "force":
+		force(m, args)
+		return
+	case "version":
+		state := currentState(m)
+		switch {
+		case jsonOutput:
+			emit(state)
+		case state.Version == nil:
+			fmt.Println("version: none")
+		default:
+			fmt.Printf("version: %d dirty: %t\n", *state.Version, state.Dirty)
 		}
-		fmt.Printf("version: %d dirty: %t\n", version, dirty)
 		return
 	default:
-		panic("usage: go run ./cmd/migrate [up|down|version]")
+		panic(usage)
 	}
 
 	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		panic(err)
+		panic(migrations.LockError(err))
 	}
+	changed := !errors.Is(err, migrate.ErrNoChange)
 
-	if errors.Is(err, migrate.ErrNoChange) {
+	if jsonOutput {
+		emit(struct {
+			Command string `json:"command"`
+			Changed bool   `json:"changed"`
+			versionState
+		}{command, changed, currentState(m)})
+		return
+	}
+	if !changed {
 		fmt.Println("no migration changes")
 		return
 	}
-
 	fmt.Printf("migration command %q completed\n", command)
 }
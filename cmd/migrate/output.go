@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// jsonOutput is set by --json: every command then prints exactly one JSON
+// object on stdout, including failures ({"error": ...}, exit status 1), so
+// deploy pipelines can parse the result instead of scraping text.
+var jsonOutput bool
+
+func emit(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// recoverJSON turns a panic into the JSON error object. It must be
+// deferred first in main so it runs after every other deferred cleanup.
+func recoverJSON() {
+	if !jsonOutput {
+		return
+	}
+	if r := recover(); r != nil {
+		emit(map[string]string{"error": fmt.Sprint(r)})
+		os.Exit(1)
+	}
+}
+
+// versionState is the database's migration state as reported in JSON;
+// Version is nil before the first migration.
+type versionState struct {
+	Version *uint `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
+
+func currentState(m *migrate.Migrate) versionState {
+	v, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return versionState{}
+	}
+	if err != nil {
+		panic(err)
+	}
+	return versionState{Version: &v, Dirty: dirty}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"backend/seeds"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// seedEnvs are the environments with seed data. Production is deliberately
+// not one of them.
+var seedEnvs = map[string]bool{"dev": true, "staging": true}
+
+// seed applies the embedded seed files of one environment, each in its own
+// transaction. The files are idempotent, so seeding twice is harmless. The
+// environment must be named explicitly, and seeding is refused outright with
+// APP_ENV=production: the seeds create a demo superadmin and an API key
+// whose value is public.
+func seed(dsn string, args []string) {
+	fset := flag.NewFlagSet("seed", flag.ExitOnError)
+	env := fset.String("env", "", "environment to seed: dev or staging (required)")
+	_ = fset.Parse(args)
+	if !seedEnvs[*env] {
+		panic("usage: go run ./cmd/migrate seed --env dev|staging")
+	}
+	if inProduction() {
+		panic("refusing to seed with APP_ENV=production")
+	}
+
+	files, err := fs.Glob(seeds.FS, *env+"/*.sql")
+	if err != nil {
+		panic(err)
+	}
+	sort.Strings(files)
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close(ctx)
+
+	applied := make([]string, 0, len(files))
+	for _, name := range files {
+		sql, err := fs.ReadFile(seeds.FS, name)
+		if err != nil {
+			panic(err)
+		}
+		// Without arguments Exec uses the simple protocol, which runs
+		// every statement in the file.
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, string(sql))
+			return err
+		})
+		if err != nil {
+			panic(fmt.Errorf("seed %s: %w", name, err))
+		}
+		applied = append(applied, name)
+		if !jsonOutput {
+			fmt.Println("applied " + name)
+		}
+	}
+	if jsonOutput {
+		emit(map[string]any{"env": *env, "applied": applied})
+		return
+	}
+	fmt.Printf("seeded %s: %d files\n", *env, len(files))
+}
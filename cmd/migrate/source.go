@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFile is one version of the migrations directory.
+type migrationFile struct {
+	Version uint64
+	Name    string
+	Up      string // file names, empty when missing
+	Down    string
+}
+
+// listMigrations reads the migration files in fsys, ordered by version.
+// Files not named <version>_<name>.(up|down).sql are ignored.
+func listMigrations(fsys fs.FS) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint64]*migrationFile)
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".sql")
+		if !ok || e.IsDir() {
+			continue
+		}
+		prefix, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		name, direction, ok := cutLast(rest, ".")
+		if !ok || (direction != "up" && direction != "down") {
+			continue
+		}
+		mf := byVersion[v]
+		if mf == nil {
+			mf = &migrationFile{Version: v, Name: name}
+			byVersion[v] = mf
+		}
+		if direction == "up" {
+			mf.Up = e.Name()
+		} else {
+			mf.Down = e.Name()
+		}
+	}
+
+	files := make([]migrationFile, 0, len(byVersion))
+	for _, mf := range byVersion {
+		files = append(files, *mf)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Version < files[j].Version })
+	return files, nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// status prints every migration in the source with its state: applied,
+// dirty (the current version, which failed part-way) or pending.
+// schema_migrations only records the current version, so everything below
+// it counts as applied.
+func status(m *migrate.Migrate, fsys fs.FS) {
+	files, err := listMigrations(fsys)
+	if err != nil {
+		panic(err)
+	}
+	current := currentState(m)
+
+	type row struct {
+		Version uint64 `json:"version"`
+		Name    string `json:"name"`
+		State   string `json:"state"`
+	}
+	rows := make([]row, 0, len(files))
+	pending := 0
+	for _, f := range files {
+		state := "pending"
+		switch {
+		case current.Version != nil && uint(f.Version) == *current.Version && current.Dirty:
+			state = "dirty"
+		case current.Version != nil && uint(f.Version) <= *current.Version:
+			state = "applied"
+		default:
+			pending++
+		}
+		rows = append(rows, row{f.Version, f.Name, state})
+	}
+
+	if jsonOutput {
+		emit(struct {
+			versionState
+			Pending    int   `json:"pending"`
+			Migrations []row `json:"migrations"`
+		}{current, pending, rows})
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tNAME")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", r.Version, r.State, r.Name)
+	}
+	_ = w.Flush()
+
+	if current.Version != nil {
+		fmt.Printf("current version: %d dirty: %t, %d pending\n", *current.Version, current.Dirty, pending)
+	} else {
+		fmt.Printf("current version: none, %d pending\n", pending)
+	}
+}
@@ -0,0 +1,54 @@
+// Command openapi writes the API's OpenAPI spec, generated from the route
+// registry, to api/openapi.json for client generators and review. With
+// --check it writes nothing and fails if the file is out of date, for CI:
+//
+//	go run ./cmd/openapi --check
+//
+// The router is built with every optional route group enabled and without
+// connecting to anything.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	httpapi "backend/internal/http"
+	"backend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func main() {
+	out := flag.String("o", "api/openapi.json", "file to write")
+	check := flag.Bool("check", false, "fail if the file differs from the generated spec instead of writing it")
+	flag.Parse()
+
+	gin.SetMode(gin.ReleaseMode)
+	r := httpapi.NewRouter(httpapi.Config{
+		Storage:               &storage.S3{},
+		InternalSigningSecret: []byte("openapi"),
+		Metrics:               httpapi.MetricsConfig{Gatherer: prometheus.NewRegistry()},
+	})
+	defer r.Close()
+
+	spec, err := r.Registry.OpenAPI()
+	if err != nil {
+		panic(err)
+	}
+	spec = append(spec, '\n')
+
+	if *check {
+		current, err := os.ReadFile(*out)
+		if err != nil || !bytes.Equal(current, spec) {
+			fmt.Fprintf(os.Stderr, "%s is out of date; run go run ./cmd/openapi\n", *out)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,114 @@
+// Command rotate-pii re-encrypts every user's email and phone with the active
+// key of PII_ENCRYPTION_KEYS and fills in the email blind index. Run it after
+// enabling encryption, to encrypt rows written in plaintext, and after
+// putting a new key first, before the old one is removed. With --dry-run it
+// only counts the rows that would change.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+const pageSize = 500
+
+func main() {
+	_ = godotenv.Load()
+
+	dryRun := flag.Bool("dry-run", false, "count the rows to re-encrypt without writing them")
+	flag.Parse()
+
+	keys, err := crypto.LoadKeyring()
+	if err != nil {
+		panic(err)
+	}
+	if keys == nil {
+		panic("PII_ENCRYPTION_KEYS is not set")
+	}
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		panic("DATABASE_URL is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer pool.Close()
+	q := db.New(pool)
+
+	var scanned, rotated, skipped int
+	after := ""
+	for {
+		rows, err := q.ListUserPIIPage(ctx, db.ListUserPIIPageParams{After: after, RowLimit: pageSize})
+		if err != nil {
+			panic(err)
+		}
+		for _, row := range rows {
+			scanned++
+			after = row.ClerkID
+			if !keys.NeedsRotation(row.Email.String) && !keys.NeedsRotation(row.Phone.String) {
+				continue
+			}
+			if *dryRun {
+				rotated++
+				continue
+			}
+			params, err := reencrypt(keys, row)
+			if err != nil {
+				panic(fmt.Errorf("user %s: %w", row.ClerkID, err))
+			}
+			n, err := q.RewriteUserPII(ctx, params)
+			if err != nil {
+				panic(err)
+			}
+			// A concurrent write already stored values under the active key.
+			if n == 0 {
+				skipped++
+				continue
+			}
+			rotated++
+		}
+		if len(rows) < pageSize {
+			break
+		}
+	}
+
+	mode := "applied"
+	if *dryRun {
+		mode = "dry run"
+	}
+	fmt.Printf("%s: %d users scanned, %d re-encrypted with key %q, %d changed concurrently\n",
+		mode, scanned, rotated, keys.ActiveKeyID(), skipped)
+}
+
+// reencrypt decrypts row's values with whichever key sealed them and
+// encrypts them again with the active key.
+func reencrypt(keys *crypto.Keyring, row db.ListUserPIIPageRow) (db.RewriteUserPIIParams, error) {
+	params := db.RewriteUserPIIParams{ClerkID: row.ClerkID, OldEmail: row.Email, OldPhone: row.Phone}
+
+	email, err := keys.DecryptText(row.Email)
+	if err != nil {
+		return params, err
+	}
+	phone, err := keys.DecryptText(row.Phone)
+	if err != nil {
+		return params, err
+	}
+	if params.NewEmail, params.EmailBidx, err = webhooks.SealEmail(keys, email); err != nil {
+		return params, err
+	}
+	params.NewPhone, err = keys.EncryptText(phone)
+	return params, err
+}
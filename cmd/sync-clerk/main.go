@@ -0,0 +1,193 @@
+// Command sync-clerk pages through every user in Clerk's Backend API and
+// upserts them locally, for bootstrapping an environment or recovering from
+// missed webhooks. Changed users are written a page at a time with
+// db.BulkUpsertUsers. With --dry-run it only prints what would change.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	clerkSDK "github.com/clerk/clerk-sdk-go/v2"
+	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+const pageSize = 100
+
+type summary struct {
+	created, updated, restored, banned, unbanned, unchanged int
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	dryRun := flag.Bool("dry-run", false, "print the changes without writing them")
+	verbose := flag.Bool("v", false, "print every changed user")
+	flag.Parse()
+
+	clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
+	if clerkSecretKey == "" {
+		panic("CLERK_SECRET_KEY is not set")
+	}
+	clerkSDK.SetKey(clerkSecretKey)
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		panic("DATABASE_URL is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer pool.Close()
+	q := db.New(pool)
+
+	keys, err := crypto.LoadKeyring()
+	if err != nil {
+		panic(err)
+	}
+
+	var sum summary
+	seen := make(map[string]bool)
+	limit := int64(pageSize)
+	for offset := int64(0); ; offset += pageSize {
+		params := &clerkUser.ListParams{}
+		params.Limit = &limit
+		params.Offset = &offset
+		list, err := clerkUser.List(ctx, params)
+		if err != nil {
+			panic(err)
+		}
+
+		var upserts []db.UpsertUserWithRoleParams
+		bans := make(map[string]bool)
+		for _, cu := range list.Users {
+			u, err := webhooks.ClerkUserFromAPI(cu)
+			if err != nil {
+				panic(err)
+			}
+			seen[cu.ID] = true
+			upsert, banChange := diffUser(ctx, q, keys, u, cu.Banned, *verbose, &sum)
+			if upsert {
+				params := u.UpsertParams()
+				if params.Email, params.EmailBidx, err = webhooks.SealEmail(keys, params.Email); err != nil {
+					panic(err)
+				}
+				upserts = append(upserts, params)
+			}
+			if banChange {
+				bans[cu.ID] = cu.Banned
+			}
+		}
+		if !*dryRun {
+			apply(ctx, pool, q, upserts, bans)
+		}
+		if len(list.Users) < pageSize {
+			break
+		}
+	}
+
+	localIDs, err := q.ListActiveClerkIDs(ctx)
+	if err != nil {
+		panic(err)
+	}
+	var orphans []string
+	for _, id := range localIDs {
+		if !seen[id] {
+			orphans = append(orphans, id)
+		}
+	}
+
+	mode := "applied"
+	if *dryRun {
+		mode = "dry run"
+	}
+	fmt.Printf("%s: %d users in Clerk, %d created, %d updated, %d restored, %d banned, %d unbanned, %d unchanged\n",
+		mode, len(seen), sum.created, sum.updated, sum.restored, sum.banned, sum.unbanned, sum.unchanged)
+	if len(orphans) > 0 {
+		// Not deleted automatically: a missed user.deleted is rarer than a
+		// CLERK_SECRET_KEY pointing at the wrong Clerk instance.
+		fmt.Printf("%d local users no longer exist in Clerk:\n", len(orphans))
+		for _, id := range orphans {
+			fmt.Println("  " + id)
+		}
+	}
+}
+
+// diffUser compares u with its local row, counts the change in sum and
+// reports whether the user must be upserted and whether its ban changes.
+// The local email is decrypted with keys before it is compared.
+func diffUser(ctx context.Context, q *db.Queries, keys *crypto.Keyring, u webhooks.ClerkUser, banned, verbose bool, sum *summary) (upsert, banChanged bool) {
+	params := u.UpsertParams()
+
+	local, err := q.GetUserSyncState(ctx, params.ClerkID)
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		panic(err)
+	}
+	if local.Email, err = keys.DecryptText(local.Email); err != nil {
+		panic(err)
+	}
+
+	var change string
+	switch {
+	case !found:
+		change = "create"
+		sum.created++
+	case local.DeletedAt.Valid:
+		change = "restore"
+		sum.restored++
+	case local.Name != params.Name ||
+		local.Username != params.Username ||
+		local.FirstName != params.FirstName ||
+		local.LastName != params.LastName ||
+		(params.Email.Valid && local.Email != params.Email):
+		change = "update"
+		sum.updated++
+	}
+
+	banChange := ""
+	if found && banned != local.BannedAt.Valid || !found && banned {
+		if banned {
+			banChange = "ban"
+			sum.banned++
+		} else {
+			banChange = "unban"
+			sum.unbanned++
+		}
+	}
+
+	if change == "" && banChange == "" {
+		sum.unchanged++
+		return false, false
+	}
+	if verbose {
+		fmt.Printf("%-8s %-6s %s <%s>\n", change, banChange, params.ClerkID, params.Email.String)
+	}
+	return change != "", banChange != ""
+}
+
+// apply writes one page of changes. Bans are set after the upsert so users
+// created by it can be banned too.
+func apply(ctx context.Context, pool *pgxpool.Pool, q *db.Queries, upserts []db.UpsertUserWithRoleParams, bans map[string]bool) {
+	if _, err := db.BulkUpsertUsers(ctx, pool, upserts); err != nil {
+		panic(err)
+	}
+	for id, banned := range bans {
+		if _, err := q.SetUserBanned(ctx, db.SetUserBannedParams{ClerkID: id, Banned: banned}); err != nil {
+			panic(err)
+		}
+	}
+}
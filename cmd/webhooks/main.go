@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"backend/internal/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+const usage = "usage: go run ./cmd/webhooks requeue [--since 24h] [--type user.updated] [--dry-run]"
+
+func main() {
+	_ = godotenv.Load()
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		panic("DATABASE_URL is required")
+	}
+
+	if len(os.Args) < 2 {
+		panic(usage)
+	}
+
+	switch os.Args[1] {
+	case "requeue":
+		requeue(dsn, os.Args[2:])
+	default:
+		panic(usage)
+	}
+}
+
+// requeue moves dead-lettered events back onto the queue. The running API's
+// worker picks them up on its next poll.
+func requeue(dsn string, args []string) {
+	fs := flag.NewFlagSet("requeue", flag.ExitOnError)
+	since := fs.Duration("since", 24*time.Hour, "only events dead-lettered within this window")
+	eventType := fs.String("type", "", "only events of this Clerk event type")
+	dryRun := fs.Bool("dry-run", false, "report how many events match without requeueing them")
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer pool.Close()
+
+	q := db.New(pool)
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-*since), Valid: true}
+	typ := pgtype.Text{String: *eventType, Valid: *eventType != ""}
+
+	if *dryRun {
+		n, err := q.CountDeadWebhookEvents(ctx, db.CountDeadWebhookEventsParams{Since: cutoff, EventType: typ})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d dead-lettered events would be requeued\n", n)
+		return
+	}
+
+	n, err := q.RequeueDeadWebhookEvents(ctx, db.RequeueDeadWebhookEventsParams{Since: cutoff, EventType: typ})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("requeued %d dead-lettered events\n", n)
+}
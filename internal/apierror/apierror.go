@@ -0,0 +1,165 @@
+// Package apierror is the API's error type. Every error response carries a
+// machine-readable code, a message safe to show the caller, the fields that
+// failed validation and the request ID; the underlying cause is kept for
+// the server log and never sent to the client.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Code identifies the kind of error, for clients to branch on instead of
+// the message.
+type Code string
+
+const (
+	CodeBadRequest           Code = "bad_request"
+	CodeInvalid              Code = "invalid"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodePreconditionRequired Code = "precondition_required"
+	CodeTooLarge             Code = "too_large"
+	CodeUnsupportedMedia     Code = "unsupported_media_type"
+	CodeRateLimited          Code = "rate_limited"
+	CodeInternal             Code = "internal"
+	CodeNotImplemented       Code = "not_implemented"
+	CodeUpstream             Code = "upstream_failed"
+	CodeUnavailable          Code = "unavailable"
+	CodeMaintenance          Code = "maintenance"
+	CodeTimeout              Code = "timeout"
+)
+
+// FieldError is a problem with one request field: a JSON body field or a
+// query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error response.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// Details are further machine-readable facts, e.g. the missing scope.
+	Details map[string]any
+	// Err is the cause, for the log.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error answered with status.
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap records err as the cause.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// With adds a detail.
+func (e *Error) With(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Invalid reports a field that failed validation; message is shown as is,
+// e.g. "limit must be between 1 and 200".
+func Invalid(field, message string) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeInvalid, message)
+	e.Fields = []FieldError{{Field: field, Message: message}}
+	return e
+}
+
+// Validation reports every field that failed validation, each message
+// relative to its field, e.g. "must be a valid address".
+func Validation(fields ...FieldError) *Error {
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	e := New(http.StatusUnprocessableEntity, CodeInvalid, strings.Join(msgs, "; "))
+	e.Fields = fields
+	return e
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func TooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+// Internal hides err, which may be nil, behind message.
+func Internal(err error, message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message).Wrap(err)
+}
+
+// Upstream reports that a service the API depends on failed.
+func Upstream(err error, message string) *Error {
+	return New(http.StatusBadGateway, CodeUpstream, message).Wrap(err)
+}
+
+// From maps any error to an Error: an *Error anywhere in the chain is used
+// as is, and well-known causes get their status. Anything else is a 500
+// whose message reveals nothing of err.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var mbe *http.MaxBytesError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &mbe):
+		return TooLarge("request body too large").Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, CodeTimeout, "request timed out").Wrap(err)
+	case errors.Is(err, pgx.ErrNoRows):
+		return NotFound("not found").Wrap(err)
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return Conflict("already exists").Wrap(err)
+	}
+	return Internal(err, "internal error")
+}
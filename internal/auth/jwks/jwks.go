@@ -0,0 +1,135 @@
+// Package jwks caches Clerk's JSON Web Key Set so session tokens can be
+// verified without a round trip to Clerk on every request.
+//
+// Keys are refreshed in the background every TTL. A token signed with an
+// unknown kid triggers an on-demand refresh (at most once per MinRefresh) to
+// pick up key rotation. When Clerk is unreachable the last good key set keeps
+// being served, so auth stays up during Clerk outages.
+package jwks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	clerkjwks "github.com/clerk/clerk-sdk-go/v2/jwks"
+)
+
+var ErrKeyNotFound = errors.New("jwks: no key for kid")
+
+// Fetcher retrieves the current key set.
+type Fetcher func(ctx context.Context) (*clerk.JSONWebKeySet, error)
+
+// ClerkFetcher fetches the key set from the Clerk Backend API using the
+// globally configured secret key.
+func ClerkFetcher(ctx context.Context) (*clerk.JSONWebKeySet, error) {
+	return clerkjwks.Get(ctx, &clerkjwks.GetParams{})
+}
+
+type Cache struct {
+	fetch      Fetcher
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]*clerk.JSONWebKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// New returns a cache that considers keys fresh for ttl.
+func New(fetch Fetcher, ttl time.Duration) *Cache {
+	return &Cache{
+		fetch:      fetch,
+		ttl:        ttl,
+		minRefresh: 10 * time.Second,
+		keys:       make(map[string]*clerk.JSONWebKey),
+	}
+}
+
+// Start refreshes the key set every TTL until ctx is done. The first fetch
+// happens synchronously; its error is returned but the loop still starts so
+// the cache can recover once Clerk is reachable.
+func (c *Cache) Start(ctx context.Context) error {
+	err := c.refresh(ctx)
+
+	go func() {
+		t := time.NewTicker(c.ttl)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := c.refresh(ctx); err != nil {
+					log.Printf("jwks: background refresh failed, serving cached keys: %v", err)
+				}
+			}
+		}
+	}()
+
+	return err
+}
+
+// Key returns the key for kid. Stale keys are served if a refresh fails.
+func (c *Cache) Key(ctx context.Context, kid string) (*clerk.JSONWebKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.ttl
+	canRetry := time.Since(c.lastAttempt) > c.minRefresh
+	c.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if !canRetry {
+		if ok {
+			return key, nil
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	if err := c.refresh(ctx); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (c *Cache) refresh(ctx context.Context) error {
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	set, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if set == nil || len(set.Keys) == 0 {
+		return errors.New("jwks: empty key set")
+	}
+
+	keys := make(map[string]*clerk.JSONWebKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k != nil && k.KeyID != "" {
+			keys[k.KeyID] = k
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
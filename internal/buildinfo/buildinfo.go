@@ -0,0 +1,58 @@
+// Package buildinfo identifies the running build. Release builds set the
+// commit and build time with -ldflags:
+//
+//	go build -ldflags "-X backend/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X backend/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them the VCS stamp the go command embeds is used, when there is
+// one, with the commit time standing in for the build time.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X.
+var (
+	Commit    string
+	BuildTime string
+)
+
+// Info describes the running build.
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	// Modified reports a build from a working tree with uncommitted
+	// changes; it is only known from the VCS stamp.
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get returns the build's info, with "unknown" for what was not recorded.
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,694 @@
+// Package config reads every setting the server takes from the environment,
+// validates them together at startup and hands each package its typed
+// configuration. A misconfigured deploy fails before it serves anything, with
+// one error naming every variable that is missing or invalid.
+//
+// OTEL_* variables are the exception: the OpenTelemetry SDK reads them
+// itself (see tracing.Setup).
+package config
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+	httpapi "backend/internal/http"
+	"backend/internal/report"
+	"backend/internal/storage"
+	"backend/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// Config is the server's effective configuration.
+type Config struct {
+	// AppEnv is APP_ENV, e.g. production.
+	AppEnv string
+	// GinMode is GIN_MODE: debug, release or test. It defaults to release
+	// when AppEnv is production and to debug otherwise.
+	GinMode string
+
+	Log      Log
+	HTTP     HTTP
+	Database Database
+	Clerk    Clerk
+
+	// InternalSigningSecret enables the /internal routes when set.
+	InternalSigningSecret string
+	// RedisURL shares rate limits across replicas when set.
+	RedisURL string
+	// Storage enables avatars; nil when S3_BUCKET is unset.
+	Storage *storage.Config
+	// PIIKeys encrypts email and phone; nil when PII_ENCRYPTION_KEYS is
+	// unset.
+	PIIKeys *crypto.Keyring
+	// Sentry enables error reporting when its DSN is set.
+	Sentry report.Options
+	// Features is FEATURE_FLAGS, the feature flags enabled at startup.
+	Features []string
+	// Maintenance is MAINTENANCE_MODE, MAINTENANCE_MESSAGE and
+	// MAINTENANCE_RETRY_AFTER (5m by default).
+	Maintenance httpapi.Maintenance
+}
+
+// Log is LOG_LEVEL (debug, info, warn or error) and LOG_FORMAT (json or
+// text).
+type Log struct {
+	Level  slog.Level
+	Format string
+}
+
+// HTTP configures the API server.
+type HTTP struct {
+	// Port is PORT, 8080 by default.
+	Port int
+	// ReadHeaderTimeout and ReadTimeout bound reading the request headers
+	// and the whole request; WriteTimeout bounds the response, from the end
+	// of the headers; IdleTimeout closes idle keep-alive connections. They
+	// stop slow clients from holding connections open indefinitely.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// MaxHeaderBytes caps the size of the request headers.
+	MaxHeaderBytes int
+	// TLS serves HTTPS directly when enabled.
+	TLS httpapi.TLSConfig
+	// TrustedProxies may set the client IP through forwarding headers.
+	TrustedProxies []netip.Prefix
+
+	// AccessLog logs every request; 5xx responses are logged regardless.
+	AccessLog bool
+	// SlowRequestThreshold is the latency above which requests are logged
+	// as slow; zero disables it.
+	SlowRequestThreshold time.Duration
+	// MaxInFlight caps concurrent requests; more are shed with 503.
+	MaxInFlight int64
+	// RequestTimeouts is REQUEST_TIMEOUT (30s by default, 0 disables) and
+	// REQUEST_TIMEOUT_ROUTES overrides such as /api/v1/users=5s.
+	RequestTimeouts httpapi.RequestTimeouts
+	// Compression is COMPRESSION_MIN_BYTES (1024 by default, 0 disables),
+	// COMPRESSION_LEVEL (1-9) and COMPRESSION_SKIP_ROUTES, route patterns
+	// never compressed.
+	Compression httpapi.Compression
+
+	MaxBodyBytes        int64
+	WebhookMaxBodyBytes int64
+	AvatarMaxBodyBytes  int64
+
+	// DebugAddr serves pprof on a loopback listener when set.
+	DebugAddr string
+
+	// ShutdownDelay is how long readiness fails before the server stops
+	// accepting connections, 5s by default so load balancers stop routing
+	// here first (0 stops at once); ShutdownTimeout bounds the wait for
+	// in-flight requests after that.
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+
+	CORS       httpapi.CORSConfig
+	RateLimits httpapi.RateLimitConfig
+	Metrics    httpapi.MetricsConfig
+}
+
+// Database configures the Postgres pools and migrations.
+type Database struct {
+	URL string
+	// ReplicaURL serves read-only queries when set.
+	ReplicaURL string
+
+	// AutoMigrate applies pending migrations at startup.
+	AutoMigrate bool
+	Migrations  migrations.Options
+
+	// LogQueries logs every query; queries slower than SlowQueryThreshold
+	// are logged regardless, unless it is zero.
+	LogQueries         bool
+	SlowQueryThreshold time.Duration
+	// StatementTimeout caps every statement.
+	StatementTimeout time.Duration
+
+	Retry db.RetryConfig
+	Pool  db.PoolSettings
+}
+
+// Clerk configures the Clerk API client and its webhook.
+type Clerk struct {
+	SecretKey string
+	// WebhookSecrets are all accepted, so secrets can be rotated.
+	WebhookSecrets []string
+	// WebhookDevMode also accepts webhooks.DevSecret.
+	WebhookDevMode   bool
+	WebhookTolerance time.Duration
+}
+
+// Error lists every setting that is missing or invalid.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("config: %d invalid settings:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Load reads the configuration from the environment. When any setting is
+// missing or invalid it returns an *Error listing them all.
+func Load() (*Config, error) {
+	return load(os.LookupEnv)
+}
+
+func load(lookup func(string) (string, bool)) (*Config, error) {
+	e := &env{lookup: lookup}
+	cfg := &Config{
+		AppEnv:                e.str("APP_ENV"),
+		GinMode:               e.str("GIN_MODE"),
+		InternalSigningSecret: e.str("INTERNAL_SIGNING_SECRET"),
+		Features:              e.list("FEATURE_FLAGS"),
+	}
+	switch cfg.GinMode {
+	case "":
+		cfg.GinMode = "debug"
+		if cfg.AppEnv == "production" {
+			cfg.GinMode = "release"
+		}
+	case "debug", "release", "test":
+	default:
+		e.problem("GIN_MODE", "must be debug, release or test, got %q", cfg.GinMode)
+	}
+	cfg.Log = loadLog(e)
+	cfg.HTTP = loadHTTP(e)
+	cfg.Database = loadDatabase(e)
+	cfg.Clerk = loadClerk(e, cfg.GinMode)
+	cfg.Sentry = loadSentry(e, cfg.AppEnv)
+	cfg.Storage = loadStorage(e)
+	cfg.Maintenance = httpapi.Maintenance{
+		Enabled:    e.boolean("MAINTENANCE_MODE", false),
+		Message:    e.str("MAINTENANCE_MESSAGE"),
+		RetryAfter: e.duration("MAINTENANCE_RETRY_AFTER", 5*time.Minute, time.Second),
+	}
+
+	if v := e.str("REDIS_URL"); v != "" {
+		if _, err := redis.ParseURL(v); err != nil {
+			e.problem("REDIS_URL", "is invalid: %v", err)
+		}
+		cfg.RedisURL = v
+	}
+	keys, err := crypto.ParseKeyring(e.str("PII_ENCRYPTION_KEYS"), e.str("PII_BLIND_INDEX_KEY"))
+	if err != nil {
+		e.problems = append(e.problems, err.Error())
+	}
+	cfg.PIIKeys = keys
+
+	if len(e.problems) > 0 {
+		return nil, &Error{Problems: e.problems}
+	}
+	return cfg, nil
+}
+
+func loadLog(e *env) Log {
+	var l Log
+	if v := e.str("LOG_LEVEL"); v != "" {
+		if err := l.Level.UnmarshalText([]byte(v)); err != nil {
+			e.problem("LOG_LEVEL", "must be debug, info, warn or error, got %q", v)
+		}
+	}
+	switch l.Format = strings.ToLower(e.str("LOG_FORMAT")); l.Format {
+	case "":
+		l.Format = "json"
+	case "json", "text":
+	default:
+		e.problem("LOG_FORMAT", "must be json or text, got %q", l.Format)
+	}
+	return l
+}
+
+func loadHTTP(e *env) HTTP {
+	h := HTTP{
+		Port:                 int(e.positiveInt("PORT", 8080)),
+		ReadHeaderTimeout:    e.duration("READ_HEADER_TIMEOUT", 5*time.Second, time.Millisecond),
+		ReadTimeout:          e.duration("READ_TIMEOUT", 30*time.Second, time.Millisecond),
+		WriteTimeout:         e.duration("WRITE_TIMEOUT", 60*time.Second, time.Millisecond),
+		IdleTimeout:          e.duration("IDLE_TIMEOUT", 120*time.Second, time.Millisecond),
+		MaxHeaderBytes:       int(e.positiveInt("MAX_HEADER_BYTES", 64<<10)),
+		AccessLog:            e.boolean("ACCESS_LOG", true),
+		SlowRequestThreshold: e.duration("SLOW_REQUEST_THRESHOLD", time.Second, 0),
+		MaxInFlight:          e.positiveInt("MAX_IN_FLIGHT_REQUESTS", 512),
+		MaxBodyBytes:         e.positiveInt("MAX_BODY_BYTES", 1<<20),
+		WebhookMaxBodyBytes:  e.positiveInt("WEBHOOK_MAX_BODY_BYTES", 1<<20),
+		AvatarMaxBodyBytes:   e.positiveInt("AVATAR_MAX_BODY_BYTES", 5<<20),
+		DebugAddr:            e.str("DEBUG_ADDR"),
+		ShutdownDelay:        e.duration("SHUTDOWN_DELAY", 5*time.Second, 0),
+		ShutdownTimeout:      e.duration("SHUTDOWN_TIMEOUT", 30*time.Second, time.Millisecond),
+	}
+	if h.Port > 65535 {
+		e.problem("PORT", "must be between 1 and 65535, got %d", h.Port)
+	}
+	if h.ReadHeaderTimeout > h.ReadTimeout {
+		e.problem("READ_HEADER_TIMEOUT", "must not exceed READ_TIMEOUT (%s)", h.ReadTimeout)
+	}
+	h.RequestTimeouts = loadRequestTimeouts(e)
+	// A handler still running at WRITE_TIMEOUT can no longer answer 504.
+	if h.RequestTimeouts.Default >= h.WriteTimeout {
+		e.problem("REQUEST_TIMEOUT", "must be shorter than WRITE_TIMEOUT (%s)", h.WriteTimeout)
+	}
+	h.Compression = loadCompression(e)
+
+	// An empty origin list allows no cross-origin requests; "*" allows any.
+	h.CORS = httpapi.DefaultCORSConfig()
+	h.CORS.AllowedOrigins = e.list("CORS_ALLOWED_ORIGINS")
+	if v := e.list("CORS_ALLOWED_METHODS"); len(v) > 0 {
+		h.CORS.AllowedMethods = v
+	}
+	if v := e.list("CORS_ALLOWED_HEADERS"); len(v) > 0 {
+		h.CORS.AllowedHeaders = v
+	}
+
+	// TRUSTED_PROXIES (CIDRs or IPs) replaces the private ranges forwarding
+	// headers are trusted from; "none" trusts no proxy.
+	h.TrustedProxies = httpapi.DefaultTrustedProxies()
+	if v, ok := e.lookup("TRUSTED_PROXIES"); ok {
+		h.TrustedProxies = nil
+		if strings.TrimSpace(v) != "none" {
+			h.TrustedProxies = e.prefixes("TRUSTED_PROXIES")
+		}
+	}
+
+	// METRICS_ALLOWED_CIDRS replaces the private ranges /metrics is open
+	// to; "none" requires basic auth.
+	h.Metrics = httpapi.DefaultMetricsConfig()
+	h.Metrics.Username = e.str("METRICS_USERNAME")
+	h.Metrics.Password = e.str("METRICS_PASSWORD")
+	if (h.Metrics.Username == "") != (h.Metrics.Password == "") {
+		e.problem("METRICS_USERNAME", "must be set together with METRICS_PASSWORD")
+	}
+	if v, ok := e.lookup("METRICS_ALLOWED_CIDRS"); ok {
+		h.Metrics.AllowedPrefixes = nil
+		if strings.TrimSpace(v) != "none" {
+			h.Metrics.AllowedPrefixes = e.prefixes("METRICS_ALLOWED_CIDRS")
+		}
+	}
+
+	h.RateLimits = loadRateLimits(e)
+	h.TLS = loadTLS(e)
+	return h
+}
+
+// loadRequestTimeouts reads REQUEST_TIMEOUT and REQUEST_TIMEOUT_ROUTES, a
+// comma-separated list of path=duration overrides where 0 means no
+// deadline.
+func loadRequestTimeouts(e *env) httpapi.RequestTimeouts {
+	t := httpapi.DefaultRequestTimeouts()
+	t.Default = e.duration("REQUEST_TIMEOUT", t.Default, 0)
+	for _, entry := range e.list("REQUEST_TIMEOUT_ROUTES") {
+		path, v, ok := strings.Cut(entry, "=")
+		d, err := time.ParseDuration(v)
+		if !ok || path == "" || err != nil || d < 0 {
+			e.problem("REQUEST_TIMEOUT_ROUTES", "entries must look like /path=duration, got %q", entry)
+			continue
+		}
+		t.Routes[httpapi.CanonicalRoute(path)] = d
+	}
+	return t
+}
+
+func loadCompression(e *env) httpapi.Compression {
+	c := httpapi.DefaultCompression()
+	if v := e.str("COMPRESSION_MIN_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			e.problem("COMPRESSION_MIN_BYTES", "must be a byte count (0 disables), got %q", v)
+		} else {
+			c.MinBytes = n
+		}
+	}
+	if v := e.str("COMPRESSION_LEVEL"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < gzip.BestSpeed || n > gzip.BestCompression {
+			e.problem("COMPRESSION_LEVEL", "must be between 1 and 9, got %q", v)
+		} else {
+			c.Level = n
+		}
+	}
+	for _, path := range e.list("COMPRESSION_SKIP_ROUTES") {
+		c.Skip[httpapi.CanonicalRoute(path)] = true
+	}
+	return c
+}
+
+// loadTLS reads TLS_CERT_FILE and TLS_KEY_FILE, or TLS_AUTOCERT_DOMAINS
+// (comma-separated) with TLS_AUTOCERT_CACHE_DIR (default autocert-cache),
+// TLS_AUTOCERT_EMAIL and TLS_CHALLENGE_ADDR (default :80).
+func loadTLS(e *env) httpapi.TLSConfig {
+	t := httpapi.TLSConfig{
+		CertFile:        e.str("TLS_CERT_FILE"),
+		KeyFile:         e.str("TLS_KEY_FILE"),
+		AutocertDomains: e.list("TLS_AUTOCERT_DOMAINS"),
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		e.problem("TLS_CERT_FILE", "must be set together with TLS_KEY_FILE")
+	}
+	if t.CertFile != "" && len(t.AutocertDomains) > 0 {
+		e.problem("TLS_AUTOCERT_DOMAINS", "must not be set together with TLS_CERT_FILE")
+	}
+	if t.CertFile != "" && t.KeyFile != "" {
+		if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			e.problem("TLS_CERT_FILE", "cannot load the certificate: %v", err)
+		}
+	}
+	if len(t.AutocertDomains) > 0 {
+		t.AutocertCacheDir = e.str("TLS_AUTOCERT_CACHE_DIR")
+		if t.AutocertCacheDir == "" {
+			t.AutocertCacheDir = "autocert-cache"
+		}
+		t.AutocertEmail = e.str("TLS_AUTOCERT_EMAIL")
+		t.ChallengeAddr = e.str("TLS_CHALLENGE_ADDR")
+		if t.ChallengeAddr == "" {
+			t.ChallengeAddr = ":80"
+		}
+	}
+	return t
+}
+
+// loadRateLimits starts from httpapi.DefaultRateLimitConfig and applies
+// RATE_LIMIT_RPS, RATE_LIMIT_BURST, RATE_LIMIT_ALGORITHM, RATE_LIMIT_IDLE_TTL,
+// RATE_LIMIT_CLEANUP_INTERVAL, RATE_LIMIT_MAX_CLIENTS and RATE_LIMIT_ROUTES,
+// a comma-separated list of path=rps:burst[:algorithm] overrides such as
+// "/webhooks/clerk=50:500". Callers in RATE_LIMIT_EXEMPT_CIDRS (CIDRs or
+// IPs) or RATE_LIMIT_EXEMPT_API_KEYS (hex SHA-256 key hashes) are never
+// limited, nor are Svix's delivery ranges unless RATE_LIMIT_EXEMPT_SVIX is
+// false.
+func loadRateLimits(e *env) httpapi.RateLimitConfig {
+	cfg := httpapi.DefaultRateLimitConfig()
+
+	if v := e.str("RATE_LIMIT_RPS"); v != "" {
+		cfg.Default.Rate = parseRate(e, "RATE_LIMIT_RPS", v, cfg.Default.Rate)
+	}
+	if v := e.str("RATE_LIMIT_BURST"); v != "" {
+		cfg.Default.Burst = parseBurst(e, "RATE_LIMIT_BURST", v, cfg.Default.Burst)
+	}
+	cfg.MaxClients = int(e.positiveInt("RATE_LIMIT_MAX_CLIENTS", int64(cfg.MaxClients)))
+	if v := e.str("RATE_LIMIT_ALGORITHM"); v != "" {
+		cfg.Default.Algorithm = parseAlgorithm(e, "RATE_LIMIT_ALGORITHM", v)
+	}
+	cfg.IdleTTL = e.duration("RATE_LIMIT_IDLE_TTL", cfg.IdleTTL, time.Nanosecond)
+	cfg.CleanupInterval = e.duration("RATE_LIMIT_CLEANUP_INTERVAL", cfg.CleanupInterval, time.Nanosecond)
+
+	for _, entry := range e.list("RATE_LIMIT_ROUTES") {
+		path, policy, ok := strings.Cut(entry, "=")
+		parts := strings.Split(policy, ":")
+		if !ok || path == "" || len(parts) < 2 || len(parts) > 3 {
+			e.problem("RATE_LIMIT_ROUTES", "entries must look like /path=rps:burst[:algorithm], got %q", entry)
+			continue
+		}
+		p := httpapi.RateLimitPolicy{
+			Rate:  parseRate(e, "RATE_LIMIT_ROUTES", parts[0], 0),
+			Burst: parseBurst(e, "RATE_LIMIT_ROUTES", parts[1], 0),
+		}
+		if len(parts) == 3 {
+			p.Algorithm = parseAlgorithm(e, "RATE_LIMIT_ROUTES", parts[2])
+		}
+		cfg.Routes[httpapi.CanonicalRoute(path)] = p
+	}
+
+	if !e.boolean("RATE_LIMIT_EXEMPT_SVIX", true) {
+		cfg.Exempt.Prefixes = nil
+	}
+	cfg.Exempt.Prefixes = append(cfg.Exempt.Prefixes, e.prefixes("RATE_LIMIT_EXEMPT_CIDRS")...)
+	for _, h := range e.list("RATE_LIMIT_EXEMPT_API_KEYS") {
+		if len(h) != 64 || strings.Trim(strings.ToLower(h), "0123456789abcdef") != "" {
+			e.problem("RATE_LIMIT_EXEMPT_API_KEYS", "must list hex SHA-256 key hashes")
+			continue
+		}
+		if cfg.Exempt.APIKeyHashes == nil {
+			cfg.Exempt.APIKeyHashes = make(map[string]bool)
+		}
+		cfg.Exempt.APIKeyHashes[strings.ToLower(h)] = true
+	}
+	return cfg
+}
+
+func parseRate(e *env, name, v string, def rate.Limit) rate.Limit {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		e.problem(name, "rate must be a positive number of requests per second, got %q", v)
+		return def
+	}
+	return rate.Limit(f)
+}
+
+func parseBurst(e *env, name, v string, def int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		e.problem(name, "burst must be a positive integer, got %q", v)
+		return def
+	}
+	return n
+}
+
+func parseAlgorithm(e *env, name, v string) httpapi.RateLimitAlgorithm {
+	switch a := httpapi.RateLimitAlgorithm(v); a {
+	case httpapi.TokenBucket, httpapi.SlidingWindow:
+		return a
+	}
+	e.problem(name, "algorithm must be token_bucket or sliding_window, got %q", v)
+	return ""
+}
+
+func loadDatabase(e *env) Database {
+	d := Database{
+		URL:                e.required("DATABASE_URL"),
+		ReplicaURL:         e.str("DATABASE_REPLICA_URL"),
+		AutoMigrate:        e.boolean("AUTO_MIGRATE", false),
+		LogQueries:         e.boolean("DB_LOG_QUERIES", false),
+		SlowQueryThreshold: e.duration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond, 0),
+		StatementTimeout:   e.duration("DB_STATEMENT_TIMEOUT", 30*time.Second, time.Millisecond),
+		Retry:              db.DefaultRetryConfig(),
+	}
+	d.Retry.Attempts = int(e.positiveInt("DB_RETRY_ATTEMPTS", int64(d.Retry.Attempts)))
+	d.Retry.BaseBackoff = e.duration("DB_RETRY_BACKOFF", d.Retry.BaseBackoff, time.Nanosecond)
+
+	if v := e.str("DB_MAX_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			e.problem("DB_MAX_CONNS", "must be a positive integer, got %q", v)
+		} else {
+			d.Pool.MaxConns = int32(n)
+		}
+	}
+	if v := e.str("DB_MIN_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			e.problem("DB_MIN_CONNS", "must be a non-negative integer, got %q", v)
+		} else {
+			d.Pool.MinConns = int32(n)
+		}
+	}
+	d.Pool.MaxConnLifetime = e.duration("DB_MAX_CONN_LIFETIME", 0, time.Nanosecond)
+	d.Pool.MaxConnIdleTime = e.duration("DB_MAX_CONN_IDLE_TIME", 0, time.Nanosecond)
+
+	for name, dsn := range map[string]string{"DATABASE_URL": d.URL, "DATABASE_REPLICA_URL": d.ReplicaURL} {
+		if dsn == "" {
+			continue
+		}
+		poolCfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			// The parse error quotes the connection string, password
+			// included.
+			e.problem(name, "is not a valid connection string")
+			continue
+		}
+		if err := d.Pool.Apply(poolCfg); err != nil {
+			e.problems = append(e.problems, err.Error())
+		}
+	}
+
+	opts, err := migrations.LoadOptions()
+	if err != nil {
+		e.problems = append(e.problems, err.Error())
+	}
+	d.Migrations = opts
+	return d
+}
+
+func loadClerk(e *env, ginMode string) Clerk {
+	c := Clerk{
+		SecretKey:        e.required("CLERK_SECRET_KEY"),
+		WebhookSecrets:   e.list("CLERK_WEBHOOK_SECRET"),
+		WebhookDevMode:   e.boolean("WEBHOOK_DEV_MODE", false),
+		WebhookTolerance: e.duration("CLERK_WEBHOOK_TOLERANCE", 5*time.Minute, time.Nanosecond),
+	}
+	if c.WebhookDevMode && ginMode == "release" {
+		e.problem("WEBHOOK_DEV_MODE", "must not be enabled with GIN_MODE=release")
+	}
+	if len(c.WebhookSecrets) == 0 && !c.WebhookDevMode {
+		e.problem("CLERK_WEBHOOK_SECRET", "is required")
+	}
+	return c
+}
+
+func loadSentry(e *env, appEnv string) report.Options {
+	o := report.Options{
+		DSN:         e.str("SENTRY_DSN"),
+		Environment: e.str("SENTRY_ENVIRONMENT"),
+		Release:     e.str("SENTRY_RELEASE"),
+		SampleRate:  1,
+		SendPII:     e.boolean("SENTRY_SEND_PII", false),
+	}
+	if o.Environment == "" {
+		o.Environment = appEnv
+	}
+	if v := e.str("SENTRY_SAMPLE_RATE"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			e.problem("SENTRY_SAMPLE_RATE", "must be a number in (0, 1], got %q", v)
+		} else {
+			o.SampleRate = f
+		}
+	}
+	return o
+}
+
+// loadStorage reads S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY,
+// S3_REGION (default us-east-1) and S3_ENDPOINT (AWS for the region by
+// default).
+func loadStorage(e *env) *storage.Config {
+	bucket := e.str("S3_BUCKET")
+	if bucket == "" {
+		return nil
+	}
+	s := &storage.Config{
+		Endpoint:        strings.TrimRight(e.str("S3_ENDPOINT"), "/"),
+		Region:          e.str("S3_REGION"),
+		Bucket:          bucket,
+		AccessKeyID:     e.required("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: e.required("S3_SECRET_ACCESS_KEY"),
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	} else if u, err := url.Parse(s.Endpoint); err != nil || u.Host == "" {
+		e.problem("S3_ENDPOINT", "must be a URL, e.g. http://localhost:9000")
+	}
+	return s
+}
+
+// Runtime returns the settings that can be reloaded without a restart.
+func (c *Config) Runtime() httpapi.RuntimeSettings {
+	return httpapi.RuntimeSettings{
+		LogLevel:    c.Log.Level,
+		RateLimits:  c.HTTP.RateLimits,
+		Features:    c.Features,
+		Maintenance: c.Maintenance,
+	}
+}
+
+// LogValue is the configuration with secrets redacted, for the startup log.
+func (c *Config) LogValue() slog.Value {
+	h := c.HTTP
+	attrs := []slog.Attr{
+		slog.String("app_env", c.AppEnv),
+		slog.String("gin_mode", c.GinMode),
+		slog.Group("log", "level", c.Log.Level.String(), "format", c.Log.Format),
+		slog.Group("http",
+			"port", h.Port,
+			"read_header_timeout", h.ReadHeaderTimeout.String(),
+			"read_timeout", h.ReadTimeout.String(),
+			"write_timeout", h.WriteTimeout.String(),
+			"idle_timeout", h.IdleTimeout.String(),
+			"max_header_bytes", h.MaxHeaderBytes,
+			"tls_cert_file", h.TLS.CertFile,
+			"tls_autocert_domains", h.TLS.AutocertDomains,
+			"trusted_proxies", h.TrustedProxies,
+			"access_log", h.AccessLog,
+			"slow_request_threshold", h.SlowRequestThreshold.String(),
+			"max_in_flight", h.MaxInFlight,
+			"request_timeout", h.RequestTimeouts.Default.String(),
+			"compression_min_bytes", h.Compression.MinBytes,
+			"max_body_bytes", h.MaxBodyBytes,
+			"webhook_max_body_bytes", h.WebhookMaxBodyBytes,
+			"avatar_max_body_bytes", h.AvatarMaxBodyBytes,
+			"debug_addr", h.DebugAddr,
+			"shutdown_delay", h.ShutdownDelay.String(),
+			"shutdown_timeout", h.ShutdownTimeout.String(),
+			"cors_origins", h.CORS.AllowedOrigins,
+			"rate_limits", h.RateLimits.String(),
+			"metrics_allowed", len(h.Metrics.AllowedPrefixes),
+			"metrics_username", h.Metrics.Username,
+			"metrics_password", redact(h.Metrics.Password),
+		),
+		slog.Group("database",
+			"url", redactURL(c.Database.URL),
+			"replica_url", redactURL(c.Database.ReplicaURL),
+			"auto_migrate", c.Database.AutoMigrate,
+			"migrations_table", c.Database.Migrations.TableName(),
+			"log_queries", c.Database.LogQueries,
+			"slow_query_threshold", c.Database.SlowQueryThreshold.String(),
+			"statement_timeout", c.Database.StatementTimeout.String(),
+			"retry_attempts", c.Database.Retry.Attempts,
+		),
+		slog.Group("clerk",
+			"secret_key", redact(c.Clerk.SecretKey),
+			"webhook_secrets", len(c.Clerk.WebhookSecrets),
+			"webhook_dev_mode", c.Clerk.WebhookDevMode,
+			"webhook_tolerance", c.Clerk.WebhookTolerance.String(),
+		),
+		slog.String("internal_signing_secret", redact(c.InternalSigningSecret)),
+		slog.String("redis_url", redactURL(c.RedisURL)),
+		slog.Group("sentry",
+			"dsn", redact(c.Sentry.DSN),
+			"environment", c.Sentry.Environment,
+			"sample_rate", c.Sentry.SampleRate,
+			"send_pii", c.Sentry.SendPII,
+		),
+		slog.String("pii_active_key", c.PIIKeys.ActiveKeyID()),
+		slog.Any("features", c.Features),
+		slog.Bool("maintenance", c.Maintenance.Enabled),
+	}
+	if c.Storage != nil {
+		attrs = append(attrs, slog.Group("storage",
+			"endpoint", c.Storage.Endpoint,
+			"region", c.Storage.Region,
+			"bucket", c.Storage.Bucket,
+			"secret_access_key", redact(c.Storage.SecretAccessKey),
+		))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redact shows whether a secret is set, not its value.
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// redactURL masks the password of a URL, and all of a connection string
+// that is not one.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redact(s)
+	}
+	q := u.Query()
+	for _, k := range []string{"password", "sslpassword"} {
+		if q.Has(k) {
+			q.Set(k, "xxxxx")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.Redacted()
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// env reads variables through lookup and collects a problem for every one
+// that is missing or invalid, so Load can report them all at once.
+type env struct {
+	lookup   func(string) (string, bool)
+	problems []string
+}
+
+func (e *env) problem(name, format string, args ...any) {
+	e.problems = append(e.problems, name+": "+fmt.Sprintf(format, args...))
+}
+
+func (e *env) str(name string) string {
+	v, _ := e.lookup(name)
+	return strings.TrimSpace(v)
+}
+
+func (e *env) required(name string) string {
+	v := e.str(name)
+	if v == "" {
+		e.problem(name, "is required")
+	}
+	return v
+}
+
+// boolean accepts true/false, 1/0 and the other forms of strconv.ParseBool.
+func (e *env) boolean(name string, def bool) bool {
+	v := e.str(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.problem(name, "must be true or false, got %q", v)
+		return def
+	}
+	return b
+}
+
+// positiveInt reads an integer of at least 1.
+func (e *env) positiveInt(name string, def int64) int64 {
+	v := e.str(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 1 {
+		e.problem(name, "must be a positive integer, got %q", v)
+		return def
+	}
+	return n
+}
+
+// duration reads a duration of at least min, e.g. 0 where zero disables a
+// feature.
+func (e *env) duration(name string, def, min time.Duration) time.Duration {
+	v := e.str(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < min {
+		if min > 0 {
+			e.problem(name, "must be a positive duration, e.g. 30s, got %q", v)
+		} else {
+			e.problem(name, "must be a duration, e.g. 30s (0 disables), got %q", v)
+		}
+		return def
+	}
+	return d
+}
+
+// list reads a comma-separated list, dropping empty entries.
+func (e *env) list(name string) []string {
+	return splitList(e.str(name))
+}
+
+// prefixes reads a list of CIDRs or single IPs.
+func (e *env) prefixes(name string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, s := range e.list(name) {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(s)
+			if aerr != nil {
+				e.problem(name, "%q is not a CIDR or IP", s)
+				continue
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		out = append(out, p.Masked())
+	}
+	return out
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
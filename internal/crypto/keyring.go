@@ -0,0 +1,215 @@
+// Package crypto encrypts personal data (email addresses and phone numbers)
+// before it is written to the database, so the columns hold no plaintext at
+// rest. Values are sealed with AES-256-GCM under a key ID; any configured key
+// can decrypt, and only the first one encrypts, which lets keys be rotated
+// by adding a new key in front and re-encrypting with cmd/rotate-pii.
+//
+// Encrypted values cannot be searched, so exact lookups go through a blind
+// index: a keyed HMAC of the normalized value stored next to the ciphertext.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// prefix marks an encrypted value: "enc:v1:<key id>:<base64 nonce+ciphertext>".
+// Values without it are plaintext written before encryption was enabled and
+// are returned as-is, so rows can be migrated gradually.
+const prefix = "enc:v1:"
+
+// Key is one AES-256 key and the ID stored with values it encrypts.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring encrypts with its active key and decrypts with any of its keys. A
+// nil *Keyring is valid and leaves values in plaintext.
+type Keyring struct {
+	active   string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring builds a keyring whose first key is the active one. The blind
+// index key must never change: indexes computed with another key no longer
+// match.
+func NewKeyring(keys []Key, indexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("crypto: at least one key is required")
+	}
+	if len(indexKey) < 32 {
+		return nil, errors.New("crypto: the blind index key must be at least 32 bytes")
+	}
+	k := &Keyring{active: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys)), indexKey: indexKey}
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("crypto: key ID %q must be non-empty and must not contain ':'", key.ID)
+		}
+		if _, dup := k.aeads[key.ID]; dup {
+			return nil, fmt.Errorf("crypto: duplicate key ID %q", key.ID)
+		}
+		if len(key.Secret) != 32 {
+			return nil, fmt.Errorf("crypto: key %q must be 32 bytes, got %d", key.ID, len(key.Secret))
+		}
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.ID] = aead
+	}
+	return k, nil
+}
+
+// LoadKeyring reads PII_ENCRYPTION_KEYS and PII_BLIND_INDEX_KEY; see
+// ParseKeyring. It returns nil when PII_ENCRYPTION_KEYS is unset.
+func LoadKeyring() (*Keyring, error) {
+	return ParseKeyring(os.Getenv("PII_ENCRYPTION_KEYS"), os.Getenv("PII_BLIND_INDEX_KEY"))
+}
+
+// ParseKeyring builds a keyring from raw, a comma-separated list of
+// "id:base64key" with the active key first, and the base64 blind-index key.
+// It returns nil when raw is empty.
+func ParseKeyring(raw, blindIndexKey string) (*Keyring, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var keys []Key
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("PII_ENCRYPTION_KEYS: %q is not id:base64key", entry)
+		}
+		b, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("PII_ENCRYPTION_KEYS: key %q is not valid base64", id)
+		}
+		keys = append(keys, Key{ID: id, Secret: b})
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(blindIndexKey)
+	if err != nil {
+		return nil, errors.New("PII_BLIND_INDEX_KEY is not valid base64")
+	}
+	if len(indexKey) == 0 {
+		return nil, errors.New("PII_BLIND_INDEX_KEY is required with PII_ENCRYPTION_KEYS")
+	}
+	return NewKeyring(keys, indexKey)
+}
+
+// ActiveKeyID returns the ID of the key new values are encrypted with.
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.active
+}
+
+// Encrypt seals plaintext with the active key. Empty strings stay empty.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.active))
+	return prefix + k.active + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values that are not encrypted
+// are returned unchanged.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+	if k == nil {
+		return "", errors.New("crypto: value is encrypted but no keys are configured")
+	}
+	id, payload, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("crypto: malformed encrypted value")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("crypto: unknown key ID %q", id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("crypto: malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("crypto: decrypting with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// NeedsRotation reports whether value is plaintext or encrypted with a key
+// other than the active one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if k == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.active+":")
+}
+
+// BlindIndex returns the lookup key of value: a hex HMAC-SHA256 of the
+// trimmed, lowercased value. It is empty for an empty value or a nil
+// keyring.
+func (k *Keyring) BlindIndex(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if k == nil || value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptText is Encrypt for nullable columns.
+func (k *Keyring) EncryptText(t pgtype.Text) (pgtype.Text, error) {
+	if !t.Valid {
+		return t, nil
+	}
+	s, err := k.Encrypt(t.String)
+	return pgtype.Text{String: s, Valid: true}, err
+}
+
+// DecryptText is Decrypt for nullable columns.
+func (k *Keyring) DecryptText(t pgtype.Text) (pgtype.Text, error) {
+	if !t.Valid {
+		return t, nil
+	}
+	s, err := k.Decrypt(t.String)
+	return pgtype.Text{String: s, Valid: true}, err
+}
+
+// BlindIndexText is BlindIndex for nullable columns; it is NULL when there
+// is nothing to index.
+func (k *Keyring) BlindIndexText(t pgtype.Text) pgtype.Text {
+	idx := k.BlindIndex(t.String)
+	return pgtype.Text{String: idx, Valid: t.Valid && idx != ""}
+}
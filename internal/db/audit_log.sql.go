@@ -0,0 +1,120 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: audit_log.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countAuditEntries = `-- name: CountAuditEntries :one
+SELECT COUNT(*)
+FROM audit_log
+WHERE ($1::text IS NULL OR actor = $1)
+  AND ($2::text IS NULL OR entity_type = $2)
+  AND ($3::text IS NULL OR entity_id = $3)
+`
+
+type CountAuditEntriesParams struct {
+	Actor      pgtype.Text `json:"actor"`
+	EntityType pgtype.Text `json:"entity_type"`
+	EntityID   pgtype.Text `json:"entity_id"`
+}
+
+func (q *Queries) CountAuditEntries(ctx context.Context, arg CountAuditEntriesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countAuditEntries, arg.Actor, arg.EntityType, arg.EntityID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const insertAuditEntry = `-- name: InsertAuditEntry :exec
+INSERT INTO audit_log (actor, method, route, status, entity_type, entity_id, changes, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type InsertAuditEntryParams struct {
+	Actor      string      `json:"actor"`
+	Method     string      `json:"method"`
+	Route      string      `json:"route"`
+	Status     int32       `json:"status"`
+	EntityType pgtype.Text `json:"entity_type"`
+	EntityID   pgtype.Text `json:"entity_id"`
+	Changes    []byte      `json:"changes"`
+	RequestID  pgtype.Text `json:"request_id"`
+}
+
+func (q *Queries) InsertAuditEntry(ctx context.Context, arg InsertAuditEntryParams) error {
+	_, err := q.db.Exec(ctx, insertAuditEntry,
+		arg.Actor,
+		arg.Method,
+		arg.Route,
+		arg.Status,
+		arg.EntityType,
+		arg.EntityID,
+		arg.Changes,
+		arg.RequestID,
+	)
+	return err
+}
+
+const listAuditEntries = `-- name: ListAuditEntries :many
+SELECT id, actor, method, route, status, entity_type, entity_id, changes, request_id, created_at
+FROM audit_log
+WHERE ($1::text IS NULL OR actor = $1)
+  AND ($2::text IS NULL OR entity_type = $2)
+  AND ($3::text IS NULL OR entity_id = $3)
+  AND ($4::bigint IS NULL OR id < $4::bigint)
+ORDER BY id DESC
+LIMIT $5
+`
+
+type ListAuditEntriesParams struct {
+	Actor      pgtype.Text `json:"actor"`
+	EntityType pgtype.Text `json:"entity_type"`
+	EntityID   pgtype.Text `json:"entity_id"`
+	BeforeID   pgtype.Int8 `json:"before_id"`
+	RowLimit   int32       `json:"row_limit"`
+}
+
+// Newest first; before_id is the cursor from the previous page.
+func (q *Queries) ListAuditEntries(ctx context.Context, arg ListAuditEntriesParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditEntries,
+		arg.Actor,
+		arg.EntityType,
+		arg.EntityID,
+		arg.BeforeID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Method,
+			&i.Route,
+			&i.Status,
+			&i.EntityType,
+			&i.EntityID,
+			&i.Changes,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// mergeStagedUsersSQL applies UpsertUserWithRole to every staged row in one
+// statement. The NOT EXISTS check sees the table as it was before the
+// statement, so only the first staged row may claim the bootstrap superadmin
+// role. DISTINCT ON keeps the last row per clerk_id, since one statement
+// cannot update the same row twice.
+const mergeStagedUsersSQL = `
+INSERT INTO users (clerk_id, username, name, email, first_name, last_name, role, is_active, created_at, updated_at, clerk_updated_at, email_bidx)
+SELECT clerk_id, username, name, email, first_name, last_name,
+       CASE WHEN ord = 1 AND NOT EXISTS (SELECT 1 FROM users WHERE deleted_at IS NULL)
+            THEN 'superadmin'
+            ELSE COALESCE(initial_role, 'user')
+       END,
+       TRUE, NOW(), NOW(), clerk_updated_at, email_bidx
+FROM (
+    SELECT DISTINCT ON (clerk_id) *
+    FROM users_stage
+    ORDER BY clerk_id, ord DESC
+) s
+ORDER BY ord
+ON CONFLICT (clerk_id) DO UPDATE
+SET username         = EXCLUDED.username,
+    name             = EXCLUDED.name,
+    first_name       = EXCLUDED.first_name,
+    last_name        = EXCLUDED.last_name,
+    email            = COALESCE(EXCLUDED.email, users.email),
+    email_bidx       = CASE WHEN EXCLUDED.email IS NULL THEN users.email_bidx ELSE EXCLUDED.email_bidx END,
+    is_active        = users.merged_into IS NULL,
+    deleted_at       = CASE WHEN users.merged_into IS NULL THEN NULL ELSE users.deleted_at END,
+    clerk_updated_at = COALESCE(EXCLUDED.clerk_updated_at, users.clerk_updated_at),
+    version          = users.version + 1,
+    updated_at       = NOW()
+WHERE users.erased_at IS NULL
+  AND (EXCLUDED.clerk_updated_at IS NULL
+       OR users.clerk_updated_at IS NULL
+       OR EXCLUDED.clerk_updated_at >= users.clerk_updated_at)`
+
+// BulkUpsertUsers applies UpsertUserWithRole to every row of users with the
+// same semantics, but in three round trips: the rows are streamed into a
+// temporary table with COPY and merged with a single INSERT. It returns the
+// number of users inserted or updated.
+func BulkUpsertUsers(ctx context.Context, pool *pgxpool.Pool, users []UpsertUserWithRoleParams) (int64, error) {
+	if len(users) == 0 {
+		return 0, nil
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `
+CREATE TEMP TABLE users_stage (
+    ord              integer NOT NULL,
+    clerk_id         text    NOT NULL,
+    username         text,
+    name             text    NOT NULL,
+    email            text,
+    first_name       text,
+    last_name        text,
+    initial_role     text,
+    clerk_updated_at timestamptz,
+    email_bidx       text
+) ON COMMIT DROP`); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"users_stage"},
+		[]string{"ord", "clerk_id", "username", "name", "email", "first_name", "last_name", "initial_role", "clerk_updated_at", "email_bidx"},
+		pgx.CopyFromSlice(len(users), func(i int) ([]any, error) {
+			u := users[i]
+			return []any{int32(i + 1), u.ClerkID, u.Username, u.Name, u.Email, u.FirstName, u.LastName, u.InitialRole, u.ClerkUpdatedAt, u.EmailBidx}, nil
+		}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, mergeStagedUsersSQL)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
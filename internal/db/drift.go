@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schemaModels maps every table the queries use to its sqlc model. The
+// models are generated from the migrations, and their json tags are the
+// column names, so together they describe the schema this build expects.
+var schemaModels = map[string]any{
+	"api_keys":              ApiKey{},
+	"audit_log":             AuditLog{},
+	"erasure_requests":      ErasureRequest{},
+	"memberships":           Membership{},
+	"organizations":         Organization{},
+	"processed_webhooks":    ProcessedWebhook{},
+	"user_events":           UserEvent{},
+	"users":                 User{},
+	"webhook_deliveries":    WebhookDelivery{},
+	"webhook_events":        WebhookEvent{},
+	"webhook_subscriptions": WebhookSubscription{},
+}
+
+// SchemaDriftError lists the tables and columns the queries expect but the
+// database lacks, as "table" or "table.column".
+type SchemaDriftError struct {
+	Missing []string
+}
+
+func (e *SchemaDriftError) Error() string {
+	return fmt.Sprintf("db: schema drift, %d missing: %s (are migrations applied?)",
+		len(e.Missing), strings.Join(e.Missing, ", "))
+}
+
+// CheckSchema compares the current schema's tables and columns in
+// information_schema with schemaModels and returns a *SchemaDriftError when
+// any are missing. Extra tables and columns are fine: a migration may run
+// ahead of the code during a rolling deploy.
+func CheckSchema(ctx context.Context, conn DBTX) error {
+	rows, err := conn.Query(ctx, `
+SELECT table_name::text, column_name::text
+FROM information_schema.columns
+WHERE table_schema = current_schema()`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]bool)
+		}
+		actual[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for table, model := range schemaModels {
+		columns, ok := actual[table]
+		if !ok {
+			missing = append(missing, table)
+			continue
+		}
+		t := reflect.TypeOf(model)
+		for i := 0; i < t.NumField(); i++ {
+			column, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if column != "" && !columns[column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &SchemaDriftError{Missing: missing}
+	}
+	return nil
+}
@@ -8,17 +8,134 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type User struct {
+type ApiKey struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	KeyHash    string             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type AuditLog struct {
+	ID         int64              `json:"id"`
+	Actor      string             `json:"actor"`
+	Method     string             `json:"method"`
+	Route      string             `json:"route"`
+	Status     int32              `json:"status"`
+	EntityType pgtype.Text        `json:"entity_type"`
+	EntityID   pgtype.Text        `json:"entity_id"`
+	Changes    []byte             `json:"changes"`
+	RequestID  pgtype.Text        `json:"request_id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type ErasureRequest struct {
+	ID          int64              `json:"id"`
 	ClerkID     string             `json:"clerk_id"`
-	Name        string             `json:"name"`
-	Email       pgtype.Text        `json:"email"`
-	Username    pgtype.Text        `json:"username"`
+	RequestedBy string             `json:"requested_by"`
+	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	Error       pgtype.Text        `json:"error"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
-	Role        string             `json:"role"`
-	IsActive    bool               `json:"is_active"`
-	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
-	FirstName   pgtype.Text        `json:"first_name"`
-	LastName    pgtype.Text        `json:"last_name"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type Membership struct {
+	ClerkMembershipID string             `json:"clerk_membership_id"`
+	ClerkOrgID        string             `json:"clerk_org_id"`
+	ClerkUserID       string             `json:"clerk_user_id"`
+	Role              string             `json:"role"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Organization struct {
+	ClerkOrgID string             `json:"clerk_org_id"`
+	Name       string             `json:"name"`
+	Slug       pgtype.Text        `json:"slug"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type ProcessedWebhook struct {
+	SvixID      string             `json:"svix_id"`
+	EventType   string             `json:"event_type"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+}
+
+type User struct {
+	ClerkID        string             `json:"clerk_id"`
+	Name           string             `json:"name"`
+	Email          pgtype.Text        `json:"email"`
+	Username       pgtype.Text        `json:"username"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt      pgtype.Timestamptz `json:"deleted_at"`
+	Role           string             `json:"role"`
+	IsActive       bool               `json:"is_active"`
+	LastLoginAt    pgtype.Timestamptz `json:"last_login_at"`
+	FirstName      pgtype.Text        `json:"first_name"`
+	LastName       pgtype.Text        `json:"last_name"`
+	BannedAt       pgtype.Timestamptz `json:"banned_at"`
+	Locale         pgtype.Text        `json:"locale"`
+	Phone          pgtype.Text        `json:"phone"`
+	AvatarKey      pgtype.Text        `json:"avatar_key"`
+	Preferences    []byte             `json:"preferences"`
+	MergedInto     pgtype.Text        `json:"merged_into"`
+	ErasedAt       pgtype.Timestamptz `json:"erased_at"`
+	Version        int64              `json:"version"`
+	ClerkUpdatedAt pgtype.Timestamptz `json:"clerk_updated_at"`
+	EmailBidx      pgtype.Text        `json:"email_bidx"`
+}
+
+type UserEvent struct {
+	ID        int64              `json:"id"`
+	ClerkID   string             `json:"clerk_id"`
+	EventType string             `json:"event_type"`
+	Actor     string             `json:"actor"`
+	Details   []byte             `json:"details"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `json:"id"`
+	SubscriptionID int64              `json:"subscription_id"`
+	EventType      string             `json:"event_type"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	LastError      pgtype.Text        `json:"last_error"`
+	ResponseStatus pgtype.Int4        `json:"response_status"`
+	NextAttemptAt  pgtype.Timestamptz `json:"next_attempt_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+}
+
+type WebhookEvent struct {
+	ID            int64              `json:"id"`
+	SvixID        string             `json:"svix_id"`
+	EventType     string             `json:"event_type"`
+	Payload       []byte             `json:"payload"`
+	Status        string             `json:"status"`
+	Error         pgtype.Text        `json:"error"`
+	Attempts      int32              `json:"attempts"`
+	ReceivedAt    pgtype.Timestamptz `json:"received_at"`
+	ProcessedAt   pgtype.Timestamptz `json:"processed_at"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	ErrorCode     pgtype.Text        `json:"error_code"`
+	OrderingKey   pgtype.Text        `json:"ordering_key"`
+	OccurredAt    pgtype.Timestamptz `json:"occurred_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int64              `json:"id"`
+	Url        string             `json:"url"`
+	Secret     string             `json:"secret"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
 }
@@ -0,0 +1,89 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: organizations.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteMembership = `-- name: DeleteMembership :exec
+DELETE FROM memberships WHERE clerk_membership_id = $1
+`
+
+func (q *Queries) DeleteMembership(ctx context.Context, clerkMembershipID string) error {
+	_, err := q.db.Exec(ctx, deleteMembership, clerkMembershipID)
+	return err
+}
+
+const deleteMembershipsByOrganization = `-- name: DeleteMembershipsByOrganization :exec
+DELETE FROM memberships WHERE clerk_org_id = $1
+`
+
+func (q *Queries) DeleteMembershipsByOrganization(ctx context.Context, clerkOrgID string) error {
+	_, err := q.db.Exec(ctx, deleteMembershipsByOrganization, clerkOrgID)
+	return err
+}
+
+const softDeleteOrganization = `-- name: SoftDeleteOrganization :exec
+UPDATE organizations
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE clerk_org_id = $1
+`
+
+func (q *Queries) SoftDeleteOrganization(ctx context.Context, clerkOrgID string) error {
+	_, err := q.db.Exec(ctx, softDeleteOrganization, clerkOrgID)
+	return err
+}
+
+const upsertMembership = `-- name: UpsertMembership :exec
+INSERT INTO memberships (clerk_membership_id, clerk_org_id, clerk_user_id, role, created_at, updated_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW())
+ON CONFLICT (clerk_membership_id) DO UPDATE
+SET clerk_org_id  = EXCLUDED.clerk_org_id,
+    clerk_user_id = EXCLUDED.clerk_user_id,
+    role          = EXCLUDED.role,
+    updated_at    = NOW()
+`
+
+type UpsertMembershipParams struct {
+	ClerkMembershipID string `json:"clerk_membership_id"`
+	ClerkOrgID        string `json:"clerk_org_id"`
+	ClerkUserID       string `json:"clerk_user_id"`
+	Role              string `json:"role"`
+}
+
+func (q *Queries) UpsertMembership(ctx context.Context, arg UpsertMembershipParams) error {
+	_, err := q.db.Exec(ctx, upsertMembership,
+		arg.ClerkMembershipID,
+		arg.ClerkOrgID,
+		arg.ClerkUserID,
+		arg.Role,
+	)
+	return err
+}
+
+const upsertOrganization = `-- name: UpsertOrganization :exec
+INSERT INTO organizations (clerk_org_id, name, slug, created_at, updated_at)
+VALUES ($1, $2, $3, NOW(), NOW())
+ON CONFLICT (clerk_org_id) DO UPDATE
+SET name       = EXCLUDED.name,
+    slug       = EXCLUDED.slug,
+    deleted_at = NULL,
+    updated_at = NOW()
+`
+
+type UpsertOrganizationParams struct {
+	ClerkOrgID string      `json:"clerk_org_id"`
+	Name       string      `json:"name"`
+	Slug       pgtype.Text `json:"slug"`
+}
+
+func (q *Queries) UpsertOrganization(ctx context.Context, arg UpsertOrganizationParams) error {
+	_, err := q.db.Exec(ctx, upsertOrganization, arg.ClerkOrgID, arg.Name, arg.Slug)
+	return err
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/exaring/otelpgx"
+)
+
+// NewOTelTracer returns a pgx tracer that records a span for every query
+// under the span of the request that ran it. Spans are named after the sqlc
+// query, e.g. ListUsers, and hand-written SQL after its first keyword. Query
+// parameters are left out, since they carry personal data.
+//
+// It captures the global tracer provider, so create it after
+// tracing.Setup.
+func NewOTelTracer() *otelpgx.Tracer {
+	return otelpgx.NewTracer(otelpgx.WithSpanNameFunc(func(sql string) string {
+		if name := QueryName(sql); name != "" {
+			return name
+		}
+		if fields := strings.Fields(sql); len(fields) > 0 {
+			return strings.ToUpper(fields[0])
+		}
+		return "raw"
+	}))
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolSettings override a pool's sizing and connection lifetimes. Zero
+// fields keep pgxpool's defaults (or pool_* parameters in the connection
+// string).
+type PoolSettings struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// Apply sets the non-zero settings on cfg and validates the result.
+func (s PoolSettings) Apply(cfg *pgxpool.Config) error {
+	if s.MaxConns > 0 {
+		cfg.MaxConns = s.MaxConns
+	}
+	if s.MinConns > 0 {
+		cfg.MinConns = s.MinConns
+	}
+	if cfg.MinConns > cfg.MaxConns {
+		return fmt.Errorf("DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+	if s.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = s.MaxConnLifetime
+	}
+	if s.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = s.MaxConnIdleTime
+	}
+	return nil
+}
+
+// DescribePool summarizes the effective pool settings for the startup log.
+func DescribePool(cfg *pgxpool.Config) string {
+	return fmt.Sprintf("max %d conns, min %d, lifetime %s, idle time %s",
+		cfg.MaxConns, cfg.MinConns, cfg.MaxConnLifetime, cfg.MaxConnIdleTime)
+}
@@ -0,0 +1,307 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: privacy.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const anonymizeUser = `-- name: AnonymizeUser :one
+WITH old AS (
+    SELECT o.clerk_id, o.avatar_key FROM users o WHERE o.clerk_id = $1::text FOR UPDATE
+)
+UPDATE users u
+SET name        = 'Deleted user',
+    email       = NULL,
+    email_bidx  = NULL,
+    username    = NULL,
+    first_name  = NULL,
+    last_name   = NULL,
+    locale      = NULL,
+    phone       = NULL,
+    avatar_key  = NULL,
+    preferences = '{}'::jsonb,
+    is_active   = FALSE,
+    deleted_at  = COALESCE(u.deleted_at, NOW()),
+    erased_at   = NOW(),
+    updated_at  = NOW()
+FROM old
+WHERE u.clerk_id = old.clerk_id
+RETURNING old.avatar_key
+`
+
+// Returns the avatar key the row had, so the object can be deleted too.
+func (q *Queries) AnonymizeUser(ctx context.Context, clerkID string) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, anonymizeUser, clerkID)
+	var avatar_key pgtype.Text
+	err := row.Scan(&avatar_key)
+	return avatar_key, err
+}
+
+const claimErasureRequest = `-- name: ClaimErasureRequest :one
+SELECT id, clerk_id, attempts
+FROM erasure_requests
+WHERE status = 'pending'
+ORDER BY id
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimErasureRequestRow struct {
+	ID       int64  `json:"id"`
+	ClerkID  string `json:"clerk_id"`
+	Attempts int32  `json:"attempts"`
+}
+
+// Must run inside the transaction that performs the erasure; the row lock
+// keeps other workers away until it commits or rolls back.
+func (q *Queries) ClaimErasureRequest(ctx context.Context) (ClaimErasureRequestRow, error) {
+	row := q.db.QueryRow(ctx, claimErasureRequest)
+	var i ClaimErasureRequestRow
+	err := row.Scan(&i.ID, &i.ClerkID, &i.Attempts)
+	return i, err
+}
+
+const completeErasureRequest = `-- name: CompleteErasureRequest :exec
+UPDATE erasure_requests
+SET status = 'done', error = NULL, completed_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) CompleteErasureRequest(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, completeErasureRequest, id)
+	return err
+}
+
+const createErasureRequest = `-- name: CreateErasureRequest :one
+INSERT INTO erasure_requests (clerk_id, requested_by)
+VALUES ($1, $2)
+ON CONFLICT (clerk_id) WHERE status = 'pending'
+DO UPDATE SET clerk_id = EXCLUDED.clerk_id
+RETURNING id, status, created_at
+`
+
+type CreateErasureRequestParams struct {
+	ClerkID     string `json:"clerk_id"`
+	RequestedBy string `json:"requested_by"`
+}
+
+type CreateErasureRequestRow struct {
+	ID        int64              `json:"id"`
+	Status    string             `json:"status"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) CreateErasureRequest(ctx context.Context, arg CreateErasureRequestParams) (CreateErasureRequestRow, error) {
+	row := q.db.QueryRow(ctx, createErasureRequest, arg.ClerkID, arg.RequestedBy)
+	var i CreateErasureRequestRow
+	err := row.Scan(&i.ID, &i.Status, &i.CreatedAt)
+	return i, err
+}
+
+const failErasureRequest = `-- name: FailErasureRequest :exec
+UPDATE erasure_requests
+SET attempts     = attempts + 1,
+    error        = $1,
+    status       = CASE WHEN attempts + 1 >= $2::int THEN 'failed' ELSE 'pending' END,
+    completed_at = CASE WHEN attempts + 1 >= $2::int THEN NOW() ELSE NULL END
+WHERE id = $3
+`
+
+type FailErasureRequestParams struct {
+	Error       pgtype.Text `json:"error"`
+	MaxAttempts int32       `json:"max_attempts"`
+	ID          int64       `json:"id"`
+}
+
+// Requests stay pending for another attempt until max_attempts is reached.
+func (q *Queries) FailErasureRequest(ctx context.Context, arg FailErasureRequestParams) error {
+	_, err := q.db.Exec(ctx, failErasureRequest, arg.Error, arg.MaxAttempts, arg.ID)
+	return err
+}
+
+const getUserDataExport = `-- name: GetUserDataExport :one
+SELECT
+    clerk_id,
+    name,
+    email,
+    username,
+    first_name,
+    last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    locale,
+    phone,
+    avatar_key
+FROM users
+WHERE clerk_id = $1 AND erased_at IS NULL
+`
+
+type GetUserDataExportRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       pgtype.Text        `json:"email"`
+	Username    pgtype.Text        `json:"username"`
+	FirstName   pgtype.Text        `json:"first_name"`
+	LastName    pgtype.Text        `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      pgtype.Text        `json:"locale"`
+	Phone       pgtype.Text        `json:"phone"`
+	AvatarKey   pgtype.Text        `json:"avatar_key"`
+}
+
+func (q *Queries) GetUserDataExport(ctx context.Context, clerkID string) (GetUserDataExportRow, error) {
+	row := q.db.QueryRow(ctx, getUserDataExport, clerkID)
+	var i GetUserDataExportRow
+	err := row.Scan(
+		&i.ClerkID,
+		&i.Name,
+		&i.Email,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Role,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.LastLoginAt,
+		&i.BannedAt,
+		&i.Locale,
+		&i.Phone,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const listAllUserEvents = `-- name: ListAllUserEvents :many
+SELECT id, clerk_id, event_type, actor, details, created_at
+FROM user_events
+WHERE clerk_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAllUserEvents(ctx context.Context, clerkID string) ([]UserEvent, error) {
+	rows, err := q.db.Query(ctx, listAllUserEvents, clerkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserEvent
+	for rows.Next() {
+		var i UserEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClerkID,
+			&i.EventType,
+			&i.Actor,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMembershipsByUser = `-- name: ListMembershipsByUser :many
+SELECT m.clerk_membership_id, m.clerk_org_id, o.name AS org_name, m.role, m.created_at, m.updated_at
+FROM memberships m
+LEFT JOIN organizations o ON o.clerk_org_id = m.clerk_org_id
+WHERE m.clerk_user_id = $1
+ORDER BY m.created_at
+`
+
+type ListMembershipsByUserRow struct {
+	ClerkMembershipID string             `json:"clerk_membership_id"`
+	ClerkOrgID        string             `json:"clerk_org_id"`
+	OrgName           pgtype.Text        `json:"org_name"`
+	Role              string             `json:"role"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) ListMembershipsByUser(ctx context.Context, clerkUserID string) ([]ListMembershipsByUserRow, error) {
+	rows, err := q.db.Query(ctx, listMembershipsByUser, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMembershipsByUserRow
+	for rows.Next() {
+		var i ListMembershipsByUserRow
+		if err := rows.Scan(
+			&i.ClerkMembershipID,
+			&i.ClerkOrgID,
+			&i.OrgName,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scrubUserEventDetails = `-- name: ScrubUserEventDetails :exec
+UPDATE user_events SET details = '{}'::jsonb WHERE clerk_id = $1
+`
+
+func (q *Queries) ScrubUserEventDetails(ctx context.Context, clerkID string) error {
+	_, err := q.db.Exec(ctx, scrubUserEventDetails, clerkID)
+	return err
+}
+
+const scrubWebhookDeliveryPayloads = `-- name: ScrubWebhookDeliveryPayloads :execrows
+UPDATE webhook_deliveries
+SET payload = jsonb_set(payload, '{data}', jsonb_build_object('clerk_id', $1::text))
+WHERE payload->'data'->>'clerk_id' = $1::text
+`
+
+func (q *Queries) ScrubWebhookDeliveryPayloads(ctx context.Context, clerkID string) (int64, error) {
+	result, err := q.db.Exec(ctx, scrubWebhookDeliveryPayloads, clerkID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const scrubWebhookEventPayloads = `-- name: ScrubWebhookEventPayloads :execrows
+UPDATE webhook_events
+SET payload = jsonb_build_object('type', event_type)
+WHERE ordering_key = $1::text OR payload->'data'->>'id' = $1::text
+`
+
+// Raw Clerk payloads carry names and email addresses; only the type is kept.
+func (q *Queries) ScrubWebhookEventPayloads(ctx context.Context, clerkID string) (int64, error) {
+	result, err := q.db.Exec(ctx, scrubWebhookEventPayloads, clerkID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
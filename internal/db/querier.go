@@ -6,15 +6,138 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	DeleteUserByClerkID(ctx context.Context, clerkID string) error
+	// Returns the avatar key the row had, so the object can be deleted too.
+	AnonymizeUser(ctx context.Context, clerkID string) (pgtype.Text, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]ClaimDueWebhookDeliveriesRow, error)
+	// Claimed rows get a lease: if the worker dies mid-batch they become due
+	// again once next_attempt_at passes. An event is only claimed when no older
+	// event with the same ordering_key is still unfinished, which serializes
+	// processing per Clerk object.
+	ClaimDueWebhookEvents(ctx context.Context, arg ClaimDueWebhookEventsParams) ([]ClaimDueWebhookEventsRow, error)
+	// Must run inside the transaction that performs the erasure; the row lock
+	// keeps other workers away until it commits or rolls back.
+	ClaimErasureRequest(ctx context.Context) (ClaimErasureRequestRow, error)
+	CompleteErasureRequest(ctx context.Context, id int64) error
+	CountAuditEntries(ctx context.Context, arg CountAuditEntriesParams) (int64, error)
+	CountDeadWebhookEvents(ctx context.Context, arg CountDeadWebhookEventsParams) (int64, error)
+	CountUserEvents(ctx context.Context, clerkID string) (int64, error)
+	// Takes the same filters as ListUsersPage.
+	CountUsers(ctx context.Context, arg CountUsersParams) (int64, error)
+	// Takes the same filters as ListUsersPageIncludingDeleted.
+	CountUsersIncludingDeleted(ctx context.Context, arg CountUsersIncludingDeletedParams) (int64, error)
+	CountWebhookEvents(ctx context.Context, status pgtype.Text) (int64, error)
+	CreateErasureRequest(ctx context.Context, arg CreateErasureRequestParams) (CreateErasureRequestRow, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (CreateWebhookSubscriptionRow, error)
+	DeleteMembership(ctx context.Context, clerkMembershipID string) error
+	DeleteMembershipsByOrganization(ctx context.Context, clerkOrgID string) error
+	DeleteProcessedWebhooksBefore(ctx context.Context, processedAt pgtype.Timestamptz) (int64, error)
+	DeleteWebhookSubscription(ctx context.Context, id int64) (int64, error)
+	// Memberships of the duplicate in organizations the survivor already
+	// belongs to cannot be moved without violating memberships_org_user_uq.
+	DropOverlappingMemberships(ctx context.Context, arg DropOverlappingMembershipsParams) (int64, error)
+	// Plaintext rows match on the address, encrypted ones on the blind index.
+	EmailInUse(ctx context.Context, arg EmailInUseParams) (bool, error)
+	// Fans one event out to every active subscriber interested in its type.
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error)
+	// Requests stay pending for another attempt until max_attempts is reached.
+	FailErasureRequest(ctx context.Context, arg FailErasureRequestParams) error
+	GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (GetActiveAPIKeyByHashRow, error)
+	GetUserAvatarKey(ctx context.Context, clerkID string) (pgtype.Text, error)
+	GetUserByClerkID(ctx context.Context, clerkID string) (GetUserByClerkIDRow, error)
+	// Admin-only twin of GetUserByClerkID that also finds soft-deleted users.
+	GetUserByClerkIDIncludingDeleted(ctx context.Context, clerkID string) (GetUserByClerkIDIncludingDeletedRow, error)
+	GetUserDataExport(ctx context.Context, clerkID string) (GetUserDataExportRow, error)
+	GetUserPreferences(ctx context.Context, clerkID string) ([]byte, error)
 	GetUserRole(ctx context.Context, clerkID string) (string, error)
+	// Raw columns compared by cmd/sync-clerk, including soft-deleted rows.
+	GetUserSyncState(ctx context.Context, clerkID string) (GetUserSyncStateRow, error)
+	GetWebhookEvent(ctx context.Context, id int64) (GetWebhookEventRow, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (GetWebhookSubscriptionRow, error)
+	// Fills the survivor's empty optional fields from the duplicate.
+	InheritUserProfile(ctx context.Context, arg InheritUserProfileParams) error
+	InsertAuditEntry(ctx context.Context, arg InsertAuditEntryParams) error
+	InsertUserEvent(ctx context.Context, arg InsertUserEventParams) error
+	// Returns no row when the svix-id is already queued; the worker owns it.
+	InsertWebhookEvent(ctx context.Context, arg InsertWebhookEventParams) (int64, error)
+	IsWebhookProcessed(ctx context.Context, svixID string) (bool, error)
+	ListActiveClerkIDs(ctx context.Context) ([]string, error)
+	ListAllUserEvents(ctx context.Context, clerkID string) ([]UserEvent, error)
+	// Newest first; before_id is the cursor from the previous page.
+	ListAuditEntries(ctx context.Context, arg ListAuditEntriesParams) ([]AuditLog, error)
+	ListMembershipsByUser(ctx context.Context, clerkUserID string) ([]ListMembershipsByUserRow, error)
+	// Newest first; before_id is the cursor from the previous page.
+	ListUserEvents(ctx context.Context, arg ListUserEventsParams) ([]UserEvent, error)
+	// Raw encrypted columns for cmd/rotate-pii, keyset-paginated by clerk_id.
+	ListUserPIIPage(ctx context.Context, arg ListUserPIIPageParams) ([]ListUserPIIPageRow, error)
 	ListUsers(ctx context.Context) ([]ListUsersRow, error)
+	ListUsersByOrganization(ctx context.Context, clerkOrgID string) ([]ListUsersByOrganizationRow, error)
+	// Keyset pagination over (created_at, clerk_id) when a cursor is given;
+	// row_offset supports plain offset paging for clients that need page numbers.
+	// q is matched as a substring, so callers must escape LIKE wildcards.
+	// Encrypted emails only match exactly, through q_bidx (the blind index of
+	// the unescaped q).
+	ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]ListUsersPageRow, error)
+	// Admin-only twin of ListUsersPage that also returns soft-deleted users.
+	ListUsersPageIncludingDeleted(ctx context.Context, arg ListUsersPageIncludingDeletedParams) ([]ListUsersPageIncludingDeletedRow, error)
+	// Newest first; before_id is the cursor from the previous page.
+	ListWebhookEvents(ctx context.Context, arg ListWebhookEventsParams) ([]ListWebhookEventsRow, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]ListWebhookSubscriptionsRow, error)
+	// Locks both rows in a stable order so concurrent merges cannot deadlock.
+	LockUsersForMerge(ctx context.Context, clerkIds []string) ([]LockUsersForMergeRow, error)
+	MarkUserMerged(ctx context.Context, arg MarkUserMergedParams) error
+	MarkWebhookDeliveryDead(ctx context.Context, arg MarkWebhookDeliveryDeadParams) error
+	MarkWebhookDeliveryDelivered(ctx context.Context, arg MarkWebhookDeliveryDeliveredParams) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	MarkWebhookEventDead(ctx context.Context, arg MarkWebhookEventDeadParams) error
+	MarkWebhookEventFailed(ctx context.Context, arg MarkWebhookEventFailedParams) error
+	MarkWebhookEventProcessed(ctx context.Context, id int64) error
+	MarkWebhookProcessed(ctx context.Context, arg MarkWebhookProcessedParams) error
+	MoveMemberships(ctx context.Context, arg MoveMembershipsParams) (int64, error)
+	MoveUserEvents(ctx context.Context, arg MoveUserEventsParams) (int64, error)
+	RequeueDeadWebhookEvents(ctx context.Context, arg RequeueDeadWebhookEventsParams) (int64, error)
+	// Only settled events can be replayed; rows still queued or in flight are
+	// left to the worker.
+	RequeueWebhookEvent(ctx context.Context, id int64) (int64, error)
+	RestoreUser(ctx context.Context, clerkID string) (int64, error)
+	// Replaces email and phone with re-encrypted values, unless either changed
+	// since it was read. It is not a profile change, so version is left alone.
+	RewriteUserPII(ctx context.Context, arg RewriteUserPIIParams) (int64, error)
+	ScrubUserEventDetails(ctx context.Context, clerkID string) error
+	ScrubWebhookDeliveryPayloads(ctx context.Context, clerkID string) (int64, error)
+	// Raw Clerk payloads carry names and email addresses; only the type is kept.
+	ScrubWebhookEventPayloads(ctx context.Context, clerkID string) (int64, error)
+	SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (int64, error)
+	SetUserBanned(ctx context.Context, arg SetUserBannedParams) (int64, error)
+	SetUserPreferences(ctx context.Context, arg SetUserPreferencesParams) (int64, error)
+	SoftDeleteOrganization(ctx context.Context, clerkOrgID string) error
+	// Users are never hard-deleted; list and get queries skip soft-deleted rows.
 	SoftDeleteUserByClerkID(ctx context.Context, clerkID string) error
-	UpdateLastLogin(ctx context.Context, clerkID string) error
-	UpsertUserWithRole(ctx context.Context, arg UpsertUserWithRoleParams) error
+	TouchAPIKey(ctx context.Context, id int64) error
+	// Events can be processed late or out of order, so last_login_at only ever
+	// moves forward.
+	UpdateLastLogin(ctx context.Context, arg UpdateLastLoginParams) error
+	// Partial update: NULL leaves a column unchanged, an empty string clears the
+	// optional ones. With expected_version set, a row whose version differs is
+	// left alone and no row is returned.
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error)
+	UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (UpdateWebhookSubscriptionRow, error)
+	UpsertMembership(ctx context.Context, arg UpsertMembershipParams) error
+	UpsertOrganization(ctx context.Context, arg UpsertOrganizationParams) error
+	// initial_role only applies when the row is inserted; an existing user's
+	// role is never changed by a sync. Rows merged into another user stay
+	// deleted, and erased rows are not touched at all. A row older than the
+	// last applied one (by Clerk's updated_at) is stale and skipped.
+	// email_bidx must be the blind index of email and is kept with it.
+	UpsertUserWithRole(ctx context.Context, arg UpsertUserWithRoleParams) (int64, error)
+	UserExists(ctx context.Context, clerkID string) (bool, error)
+	// Cheap change detector for ETags: any insert, update or soft delete bumps
+	// either the count or the latest updated_at.
+	UsersFingerprint(ctx context.Context) (UsersFingerprintRow, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaRetryAfter is how long reads stay on the primary after the replica
+// failed before it is tried again.
+const replicaRetryAfter = 30 * time.Second
+
+// ReadPool is a DBTX for read-only queries. It sends them to a replica when
+// one is configured, and to the primary while the replica is unreachable.
+// Replicas lag behind the primary, so reads that must observe the caller's
+// own writes should keep using the primary.
+type ReadPool struct {
+	primary *pgxpool.Pool
+	replica *pgxpool.Pool
+	// downUntil is the unix-nano time until which the replica is skipped.
+	downUntil atomic.Int64
+}
+
+// NewReadPool routes reads to replica, or always to primary when replica is
+// nil.
+func NewReadPool(primary, replica *pgxpool.Pool) *ReadPool {
+	return &ReadPool{primary: primary, replica: replica}
+}
+
+func (p *ReadPool) target() *pgxpool.Pool {
+	if p.replica == nil || time.Now().UnixNano() < p.downUntil.Load() {
+		return p.primary
+	}
+	return p.replica
+}
+
+// fallback reports whether err means the replica could not be reached, in
+// which case it is marked down and the caller retries on the primary.
+func (p *ReadPool) fallback(ctx context.Context, err error) bool {
+	var connErr *pgconn.ConnectError
+	if ctx.Err() != nil || (!errors.As(err, &connErr) && !pgconn.SafeToRetry(err)) {
+		return false
+	}
+	if p.downUntil.Swap(time.Now().Add(replicaRetryAfter).UnixNano()) < time.Now().UnixNano() {
+		log.Printf("db: replica unavailable, reading from primary for %s: %v", replicaRetryAfter, err)
+	}
+	return true
+}
+
+func (p *ReadPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	pool := p.target()
+	tag, err := pool.Exec(ctx, sql, args...)
+	if err != nil && pool == p.replica && p.fallback(ctx, err) {
+		return p.primary.Exec(ctx, sql, args...)
+	}
+	return tag, err
+}
+
+func (p *ReadPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	pool := p.target()
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil && pool == p.replica && p.fallback(ctx, err) {
+		return p.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+func (p *ReadPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	pool := p.target()
+	if pool == p.primary {
+		return pool.QueryRow(ctx, sql, args...)
+	}
+	return &replicaRow{p: p, ctx: ctx, sql: sql, args: args, row: pool.QueryRow(ctx, sql, args...)}
+}
+
+// replicaRow defers the fallback decision to Scan, where QueryRow reports
+// its errors.
+type replicaRow struct {
+	p    *ReadPool
+	ctx  context.Context
+	sql  string
+	args []interface{}
+	row  pgx.Row
+}
+
+func (r *replicaRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && r.p.fallback(r.ctx, err) {
+		return r.p.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
+
+// BeginTx starts a transaction on the replica, or on the primary while the
+// replica is unreachable. Callers use it to set per-transaction options
+// such as SET LOCAL statement_timeout.
+func (p *ReadPool) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	pool := p.target()
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil && pool == p.replica && p.fallback(ctx, err) {
+		return p.primary.BeginTx(ctx, opts)
+	}
+	return tx, err
+}
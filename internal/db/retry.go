@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RetryConfig bounds how RetryPool retries a failed statement.
+type RetryConfig struct {
+	// Attempts is the total number of tries, including the first.
+	Attempts    int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{Attempts: 3, BaseBackoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+}
+
+// RetryPool is a DBTX that retries statements failing with transient
+// errors. It is meant for queries run directly on the pool: inside a
+// transaction a failed statement aborts the transaction, so Queries.WithTx
+// bypasses it.
+//
+// A statement is retried when
+//   - it never reached the server (pgconn.SafeToRetry), e.g. a failed dial;
+//   - the server rolled it back because of a serialization failure or
+//     deadlock, which in autocommit mode leaves nothing applied; or
+//   - the connection was lost mid-statement and the statement is a plain
+//     SELECT, the only case where running it twice is known to be harmless.
+type RetryPool struct {
+	pool *pgxpool.Pool
+	cfg  RetryConfig
+}
+
+func NewRetryPool(pool *pgxpool.Pool, cfg RetryConfig) *RetryPool {
+	return &RetryPool{pool: pool, cfg: cfg}
+}
+
+// readOnlySQL matches statements that start with SELECT once the sqlc
+// "-- name:" comment is skipped.
+var readOnlySQL = regexp.MustCompile(`(?is)^\s*(--[^\n]*\n\s*)*SELECT\b`)
+
+func retryable(ctx context.Context, err error, sql string) bool {
+	if ctx.Err() != nil || pgconn.Timeout(err) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		// The connection broke while the statement ran.
+		var netErr net.Error
+		lost := errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		return lost && readOnlySQL.MatchString(sql)
+	}
+	switch {
+	case pgErr.Code == "40001", pgErr.Code == "40P01": // serialization_failure, deadlock_detected
+		return true
+	case strings.HasPrefix(pgErr.Code, "08"), pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03":
+		// Connection exceptions and server shutdown during failover.
+		return readOnlySQL.MatchString(sql)
+	}
+	return false
+}
+
+// do runs fn until it succeeds, fails permanently or runs out of attempts.
+func (p *RetryPool) do(ctx context.Context, sql string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.cfg.Attempts || !retryable(ctx, err, sql) {
+			return err
+		}
+		wait := min(p.cfg.BaseBackoff<<(attempt-1), p.cfg.MaxBackoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (p *RetryPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := p.do(ctx, sql, func() (err error) {
+		tag, err = p.pool.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (p *RetryPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := p.do(ctx, sql, func() (err error) {
+		rows, err = p.pool.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (p *RetryPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &retryRow{p: p, ctx: ctx, sql: sql, args: args}
+}
+
+// retryRow runs the query on Scan, where QueryRow reports its errors.
+type retryRow struct {
+	p    *RetryPool
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (r *retryRow) Scan(dest ...any) error {
+	return r.p.do(r.ctx, r.sql, func() error {
+		return r.p.pool.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
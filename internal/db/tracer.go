@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// sqlcName extracts the query name from the "-- name: X :kind" comment sqlc
+// puts at the top of every generated statement.
+var sqlcName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// QueryTracer is a pgx.QueryTracer that logs queries slower than
+// SlowThreshold, and every query when Verbose is set. Queries are identified
+// by their sqlc name; hand-written SQL is logged as "raw".
+type QueryTracer struct {
+	SlowThreshold time.Duration
+	Verbose       bool
+}
+
+type traceStartKey struct{}
+
+type traceStart struct {
+	name string
+	at   time.Time
+}
+
+// QueryName returns the sqlc name of a generated statement, or "" for
+// hand-written SQL.
+func QueryName(sql string) string {
+	if m := sqlcName.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := QueryName(data.SQL)
+	if name == "" {
+		name = "raw"
+	}
+	return context.WithValue(ctx, traceStartKey{}, traceStart{name: name, at: time.Now()})
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(traceStartKey{}).(traceStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	slow := t.SlowThreshold > 0 && elapsed >= t.SlowThreshold
+
+	switch {
+	case data.Err != nil && (slow || t.Verbose):
+		log.Printf("db: %s failed after %s: %v", start.name, elapsed, data.Err)
+	case slow:
+		log.Printf("db: slow query %s took %s (threshold %s), %d rows", start.name, elapsed, t.SlowThreshold, data.CommandTag.RowsAffected())
+	case t.Verbose:
+		log.Printf("db: %s took %s, %d rows", start.name, elapsed, data.CommandTag.RowsAffected())
+	}
+}
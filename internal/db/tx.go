@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WithTx runs fn with Queries bound to a new transaction on pool. The
+// transaction is committed when fn returns nil and rolled back otherwise,
+// including when fn panics.
+func WithTx(ctx context.Context, pool *pgxpool.Pool, fn func(q *Queries) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: user_events.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const countUserEvents = `-- name: CountUserEvents :one
+SELECT COUNT(*) FROM user_events WHERE clerk_id = $1
+`
+
+func (q *Queries) CountUserEvents(ctx context.Context, clerkID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserEvents, clerkID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const insertUserEvent = `-- name: InsertUserEvent :exec
+INSERT INTO user_events (clerk_id, event_type, actor, details)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertUserEventParams struct {
+	ClerkID   string `json:"clerk_id"`
+	EventType string `json:"event_type"`
+	Actor     string `json:"actor"`
+	Details   []byte `json:"details"`
+}
+
+func (q *Queries) InsertUserEvent(ctx context.Context, arg InsertUserEventParams) error {
+	_, err := q.db.Exec(ctx, insertUserEvent,
+		arg.ClerkID,
+		arg.EventType,
+		arg.Actor,
+		arg.Details,
+	)
+	return err
+}
+
+const listUserEvents = `-- name: ListUserEvents :many
+SELECT id, clerk_id, event_type, actor, details, created_at
+FROM user_events
+WHERE clerk_id = $1
+  AND ($2::bigint IS NULL OR id < $2::bigint)
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListUserEventsParams struct {
+	ClerkID  string      `json:"clerk_id"`
+	BeforeID pgtype.Int8 `json:"before_id"`
+	RowLimit int32       `json:"row_limit"`
+}
+
+// Newest first; before_id is the cursor from the previous page.
+func (q *Queries) ListUserEvents(ctx context.Context, arg ListUserEventsParams) ([]UserEvent, error) {
+	rows, err := q.db.Query(ctx, listUserEvents, arg.ClerkID, arg.BeforeID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserEvent
+	for rows.Next() {
+		var i UserEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClerkID,
+			&i.EventType,
+			&i.Actor,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
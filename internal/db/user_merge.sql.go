@@ -0,0 +1,151 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: user_merge.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const dropOverlappingMemberships = `-- name: DropOverlappingMemberships :execrows
+DELETE FROM memberships d
+WHERE d.clerk_user_id = $1
+  AND EXISTS (
+      SELECT 1 FROM memberships s
+      WHERE s.clerk_user_id = $2 AND s.clerk_org_id = d.clerk_org_id
+  )
+`
+
+type DropOverlappingMembershipsParams struct {
+	DuplicateID string `json:"duplicate_id"`
+	SurvivorID  string `json:"survivor_id"`
+}
+
+// Memberships of the duplicate in organizations the survivor already
+// belongs to cannot be moved without violating memberships_org_user_uq.
+func (q *Queries) DropOverlappingMemberships(ctx context.Context, arg DropOverlappingMembershipsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, dropOverlappingMemberships, arg.DuplicateID, arg.SurvivorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const inheritUserProfile = `-- name: InheritUserProfile :exec
+UPDATE users s
+SET email      = COALESCE(s.email, d.email),
+    email_bidx = CASE WHEN s.email IS NULL THEN d.email_bidx ELSE s.email_bidx END,
+    avatar_key = COALESCE(s.avatar_key, d.avatar_key),
+    locale     = COALESCE(s.locale, d.locale),
+    phone      = COALESCE(s.phone, d.phone),
+    updated_at = NOW()
+FROM users d
+WHERE s.clerk_id = $1 AND d.clerk_id = $2
+`
+
+type InheritUserProfileParams struct {
+	SurvivorID  string `json:"survivor_id"`
+	DuplicateID string `json:"duplicate_id"`
+}
+
+// Fills the survivor's empty optional fields from the duplicate.
+func (q *Queries) InheritUserProfile(ctx context.Context, arg InheritUserProfileParams) error {
+	_, err := q.db.Exec(ctx, inheritUserProfile, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const lockUsersForMerge = `-- name: LockUsersForMerge :many
+SELECT clerk_id, deleted_at, merged_into
+FROM users
+WHERE clerk_id = ANY($1::text[])
+ORDER BY clerk_id
+FOR UPDATE
+`
+
+type LockUsersForMergeRow struct {
+	ClerkID    string             `json:"clerk_id"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	MergedInto pgtype.Text        `json:"merged_into"`
+}
+
+// Locks both rows in a stable order so concurrent merges cannot deadlock.
+func (q *Queries) LockUsersForMerge(ctx context.Context, clerkIds []string) ([]LockUsersForMergeRow, error) {
+	rows, err := q.db.Query(ctx, lockUsersForMerge, clerkIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LockUsersForMergeRow
+	for rows.Next() {
+		var i LockUsersForMergeRow
+		if err := rows.Scan(&i.ClerkID, &i.DeletedAt, &i.MergedInto); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserMerged = `-- name: MarkUserMerged :exec
+UPDATE users
+SET merged_into = $1::text,
+    deleted_at  = NOW(),
+    is_active   = FALSE,
+    updated_at  = NOW()
+WHERE clerk_id = $2
+`
+
+type MarkUserMergedParams struct {
+	SurvivorID  string `json:"survivor_id"`
+	DuplicateID string `json:"duplicate_id"`
+}
+
+func (q *Queries) MarkUserMerged(ctx context.Context, arg MarkUserMergedParams) error {
+	_, err := q.db.Exec(ctx, markUserMerged, arg.SurvivorID, arg.DuplicateID)
+	return err
+}
+
+const moveMemberships = `-- name: MoveMemberships :execrows
+UPDATE memberships
+SET clerk_user_id = $1, updated_at = NOW()
+WHERE clerk_user_id = $2
+`
+
+type MoveMembershipsParams struct {
+	SurvivorID  string `json:"survivor_id"`
+	DuplicateID string `json:"duplicate_id"`
+}
+
+func (q *Queries) MoveMemberships(ctx context.Context, arg MoveMembershipsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveMemberships, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveUserEvents = `-- name: MoveUserEvents :execrows
+UPDATE user_events
+SET clerk_id = $1
+WHERE clerk_id = $2
+`
+
+type MoveUserEventsParams struct {
+	SurvivorID  string `json:"survivor_id"`
+	DuplicateID string `json:"duplicate_id"`
+}
+
+func (q *Queries) MoveUserEvents(ctx context.Context, arg MoveUserEventsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveUserEvents, arg.SurvivorID, arg.DuplicateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
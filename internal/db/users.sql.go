@@ -11,17 +11,251 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const deleteUserByClerkID = `-- name: DeleteUserByClerkID :exec
-DELETE FROM users WHERE clerk_id = $1
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+WHERE deleted_at IS NULL
+  AND ($1::text IS NULL
+       OR name ILIKE '%' || $1::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || $1::text || '%')
+       OR email_bidx = $2::text
+       OR username ILIKE '%' || $1::text || '%')
+  AND ($3::boolean IS NULL OR (COALESCE(email, '') <> '') = $3::boolean)
+  AND ($4::timestamptz IS NULL OR created_at > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
 `
 
-func (q *Queries) DeleteUserByClerkID(ctx context.Context, clerkID string) error {
-	_, err := q.db.Exec(ctx, deleteUserByClerkID, clerkID)
-	return err
+type CountUsersParams struct {
+	Q             pgtype.Text        `json:"q"`
+	QBidx         pgtype.Text        `json:"q_bidx"`
+	HasEmail      pgtype.Bool        `json:"has_email"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+}
+
+// Takes the same filters as ListUsersPage.
+func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers,
+		arg.Q,
+		arg.QBidx,
+		arg.HasEmail,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsersIncludingDeleted = `-- name: CountUsersIncludingDeleted :one
+SELECT COUNT(*) FROM users
+WHERE ($1::text IS NULL
+       OR name ILIKE '%' || $1::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || $1::text || '%')
+       OR email_bidx = $2::text
+       OR username ILIKE '%' || $1::text || '%')
+  AND ($3::boolean IS NULL OR (COALESCE(email, '') <> '') = $3::boolean)
+  AND ($4::timestamptz IS NULL OR created_at > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+`
+
+type CountUsersIncludingDeletedParams struct {
+	Q             pgtype.Text        `json:"q"`
+	QBidx         pgtype.Text        `json:"q_bidx"`
+	HasEmail      pgtype.Bool        `json:"has_email"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+}
+
+// Takes the same filters as ListUsersPageIncludingDeleted.
+func (q *Queries) CountUsersIncludingDeleted(ctx context.Context, arg CountUsersIncludingDeletedParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsersIncludingDeleted,
+		arg.Q,
+		arg.QBidx,
+		arg.HasEmail,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const emailInUse = `-- name: EmailInUse :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE deleted_at IS NULL
+      AND (lower(email) = lower($1::text) OR email_bidx = $2::text)
+)
+`
+
+type EmailInUseParams struct {
+	Email     string      `json:"email"`
+	EmailBidx pgtype.Text `json:"email_bidx"`
+}
+
+// Plaintext rows match on the address, encrypted ones on the blind index.
+func (q *Queries) EmailInUse(ctx context.Context, arg EmailInUseParams) (bool, error) {
+	row := q.db.QueryRow(ctx, emailInUse, arg.Email, arg.EmailBidx)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const getUserAvatarKey = `-- name: GetUserAvatarKey :one
+SELECT avatar_key FROM users WHERE clerk_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserAvatarKey(ctx context.Context, clerkID string) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, getUserAvatarKey, clerkID)
+	var avatar_key pgtype.Text
+	err := row.Scan(&avatar_key)
+	return avatar_key, err
+}
+
+const getUserByClerkID = `-- name: GetUserByClerkID :one
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version
+FROM users
+WHERE clerk_id = $1 AND deleted_at IS NULL
+`
+
+type GetUserByClerkIDRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+	Version     int64              `json:"version"`
+}
+
+func (q *Queries) GetUserByClerkID(ctx context.Context, clerkID string) (GetUserByClerkIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserByClerkID, clerkID)
+	var i GetUserByClerkIDRow
+	err := row.Scan(
+		&i.ClerkID,
+		&i.Name,
+		&i.Email,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Role,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.LastLoginAt,
+		&i.BannedAt,
+		&i.Locale,
+		&i.Phone,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserByClerkIDIncludingDeleted = `-- name: GetUserByClerkIDIncludingDeleted :one
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version
+FROM users
+WHERE clerk_id = $1
+`
+
+type GetUserByClerkIDIncludingDeletedRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+	Version     int64              `json:"version"`
+}
+
+// Admin-only twin of GetUserByClerkID that also finds soft-deleted users.
+func (q *Queries) GetUserByClerkIDIncludingDeleted(ctx context.Context, clerkID string) (GetUserByClerkIDIncludingDeletedRow, error) {
+	row := q.db.QueryRow(ctx, getUserByClerkIDIncludingDeleted, clerkID)
+	var i GetUserByClerkIDIncludingDeletedRow
+	err := row.Scan(
+		&i.ClerkID,
+		&i.Name,
+		&i.Email,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Role,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.LastLoginAt,
+		&i.BannedAt,
+		&i.Locale,
+		&i.Phone,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserPreferences = `-- name: GetUserPreferences :one
+SELECT preferences FROM users WHERE clerk_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserPreferences(ctx context.Context, clerkID string) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getUserPreferences, clerkID)
+	var preferences []byte
+	err := row.Scan(&preferences)
+	return preferences, err
 }
 
 const getUserRole = `-- name: GetUserRole :one
-SELECT role FROM users WHERE clerk_id = $1 AND is_active = TRUE AND deleted_at IS NULL
+SELECT role FROM users WHERE clerk_id = $1 AND is_active = TRUE AND deleted_at IS NULL AND banned_at IS NULL
 `
 
 func (q *Queries) GetUserRole(ctx context.Context, clerkID string) (string, error) {
@@ -31,6 +265,105 @@ func (q *Queries) GetUserRole(ctx context.Context, clerkID string) (string, erro
 	return role, err
 }
 
+const getUserSyncState = `-- name: GetUserSyncState :one
+SELECT clerk_id, name, email, username, first_name, last_name, deleted_at, banned_at
+FROM users
+WHERE clerk_id = $1
+`
+
+type GetUserSyncStateRow struct {
+	ClerkID   string             `json:"clerk_id"`
+	Name      string             `json:"name"`
+	Email     pgtype.Text        `json:"email"`
+	Username  pgtype.Text        `json:"username"`
+	FirstName pgtype.Text        `json:"first_name"`
+	LastName  pgtype.Text        `json:"last_name"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	BannedAt  pgtype.Timestamptz `json:"banned_at"`
+}
+
+// Raw columns compared by cmd/sync-clerk, including soft-deleted rows.
+func (q *Queries) GetUserSyncState(ctx context.Context, clerkID string) (GetUserSyncStateRow, error) {
+	row := q.db.QueryRow(ctx, getUserSyncState, clerkID)
+	var i GetUserSyncStateRow
+	err := row.Scan(
+		&i.ClerkID,
+		&i.Name,
+		&i.Email,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.DeletedAt,
+		&i.BannedAt,
+	)
+	return i, err
+}
+
+const listActiveClerkIDs = `-- name: ListActiveClerkIDs :many
+SELECT clerk_id FROM users WHERE deleted_at IS NULL ORDER BY clerk_id
+`
+
+func (q *Queries) ListActiveClerkIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listActiveClerkIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var clerk_id string
+		if err := rows.Scan(&clerk_id); err != nil {
+			return nil, err
+		}
+		items = append(items, clerk_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserPIIPage = `-- name: ListUserPIIPage :many
+SELECT clerk_id, email, phone
+FROM users
+WHERE clerk_id > $1::text
+  AND (email IS NOT NULL OR phone IS NOT NULL)
+ORDER BY clerk_id
+LIMIT $2
+`
+
+type ListUserPIIPageParams struct {
+	After    string `json:"after"`
+	RowLimit int32  `json:"row_limit"`
+}
+
+type ListUserPIIPageRow struct {
+	ClerkID string      `json:"clerk_id"`
+	Email   pgtype.Text `json:"email"`
+	Phone   pgtype.Text `json:"phone"`
+}
+
+// Raw encrypted columns for cmd/rotate-pii, keyset-paginated by clerk_id.
+func (q *Queries) ListUserPIIPage(ctx context.Context, arg ListUserPIIPageParams) ([]ListUserPIIPageRow, error) {
+	rows, err := q.db.Query(ctx, listUserPIIPage, arg.After, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserPIIPageRow
+	for rows.Next() {
+		var i ListUserPIIPageRow
+		if err := rows.Scan(&i.ClerkID, &i.Email, &i.Phone); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
 SELECT
     COALESCE(clerk_id, '')::text      AS clerk_id,
@@ -44,7 +377,8 @@ SELECT
     created_at,
     updated_at,
     deleted_at,
-    last_login_at
+    last_login_at,
+    banned_at
 FROM users
 WHERE deleted_at IS NULL
 ORDER BY created_at DESC, clerk_id DESC
@@ -63,6 +397,7 @@ type ListUsersRow struct {
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
 	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
 }
 
 func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
@@ -87,6 +422,78 @@ func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.LastLoginAt,
+			&i.BannedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByOrganization = `-- name: ListUsersByOrganization :many
+SELECT
+    COALESCE(u.clerk_id, '')::text      AS clerk_id,
+    u.name,
+    COALESCE(u.email, '')::text         AS email,
+    COALESCE(u.username, '')::text      AS username,
+    COALESCE(u.first_name, '')::text    AS first_name,
+    COALESCE(u.last_name, '')::text     AS last_name,
+    u.role,
+    u.is_active,
+    u.created_at,
+    u.updated_at,
+    u.deleted_at,
+    u.last_login_at,
+    u.banned_at
+FROM users u
+JOIN memberships m ON m.clerk_user_id = u.clerk_id
+WHERE m.clerk_org_id = $1 AND u.deleted_at IS NULL
+ORDER BY u.created_at DESC, u.clerk_id DESC
+`
+
+type ListUsersByOrganizationRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+}
+
+func (q *Queries) ListUsersByOrganization(ctx context.Context, clerkOrgID string) ([]ListUsersByOrganizationRow, error) {
+	rows, err := q.db.Query(ctx, listUsersByOrganization, clerkOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersByOrganizationRow
+	for rows.Next() {
+		var i ListUsersByOrganizationRow
+		if err := rows.Scan(
+			&i.ClerkID,
+			&i.Name,
+			&i.Email,
+			&i.Username,
+			&i.FirstName,
+			&i.LastName,
+			&i.Role,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.LastLoginAt,
+			&i.BannedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -98,12 +505,344 @@ func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
 	return items, nil
 }
 
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone
+FROM users
+WHERE deleted_at IS NULL
+  AND ($1::text IS NULL
+       OR name ILIKE '%' || $1::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || $1::text || '%')
+       OR email_bidx = $2::text
+       OR username ILIKE '%' || $1::text || '%')
+  AND ($3::boolean IS NULL OR (COALESCE(email, '') <> '') = $3::boolean)
+  AND ($4::timestamptz IS NULL OR created_at > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+  AND ($6::timestamptz IS NULL
+       OR (created_at, clerk_id) < ($6::timestamptz, $7::text))
+ORDER BY created_at DESC, clerk_id DESC
+LIMIT $9 OFFSET $8
+`
+
+type ListUsersPageParams struct {
+	Q               pgtype.Text        `json:"q"`
+	QBidx           pgtype.Text        `json:"q_bidx"`
+	HasEmail        pgtype.Bool        `json:"has_email"`
+	CreatedAfter    pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore   pgtype.Timestamptz `json:"created_before"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorClerkID   pgtype.Text        `json:"cursor_clerk_id"`
+	RowOffset       int32              `json:"row_offset"`
+	RowLimit        int32              `json:"row_limit"`
+}
+
+type ListUsersPageRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+}
+
+// Keyset pagination over (created_at, clerk_id) when a cursor is given;
+// row_offset supports plain offset paging for clients that need page numbers.
+// q is matched as a substring, so callers must escape LIKE wildcards.
+// Encrypted emails only match exactly, through q_bidx (the blind index of
+// the unescaped q).
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]ListUsersPageRow, error) {
+	rows, err := q.db.Query(ctx, listUsersPage,
+		arg.Q,
+		arg.QBidx,
+		arg.HasEmail,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.CursorCreatedAt,
+		arg.CursorClerkID,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersPageRow
+	for rows.Next() {
+		var i ListUsersPageRow
+		if err := rows.Scan(
+			&i.ClerkID,
+			&i.Name,
+			&i.Email,
+			&i.Username,
+			&i.FirstName,
+			&i.LastName,
+			&i.Role,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.LastLoginAt,
+			&i.BannedAt,
+			&i.Locale,
+			&i.Phone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersPageIncludingDeleted = `-- name: ListUsersPageIncludingDeleted :many
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone
+FROM users
+WHERE ($1::text IS NULL
+       OR name ILIKE '%' || $1::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || $1::text || '%')
+       OR email_bidx = $2::text
+       OR username ILIKE '%' || $1::text || '%')
+  AND ($3::boolean IS NULL OR (COALESCE(email, '') <> '') = $3::boolean)
+  AND ($4::timestamptz IS NULL OR created_at > $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+  AND ($6::timestamptz IS NULL
+       OR (created_at, clerk_id) < ($6::timestamptz, $7::text))
+ORDER BY created_at DESC, clerk_id DESC
+LIMIT $9 OFFSET $8
+`
+
+type ListUsersPageIncludingDeletedParams struct {
+	Q               pgtype.Text        `json:"q"`
+	QBidx           pgtype.Text        `json:"q_bidx"`
+	HasEmail        pgtype.Bool        `json:"has_email"`
+	CreatedAfter    pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore   pgtype.Timestamptz `json:"created_before"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorClerkID   pgtype.Text        `json:"cursor_clerk_id"`
+	RowOffset       int32              `json:"row_offset"`
+	RowLimit        int32              `json:"row_limit"`
+}
+
+type ListUsersPageIncludingDeletedRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+}
+
+// Admin-only twin of ListUsersPage that also returns soft-deleted users.
+func (q *Queries) ListUsersPageIncludingDeleted(ctx context.Context, arg ListUsersPageIncludingDeletedParams) ([]ListUsersPageIncludingDeletedRow, error) {
+	rows, err := q.db.Query(ctx, listUsersPageIncludingDeleted,
+		arg.Q,
+		arg.QBidx,
+		arg.HasEmail,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.CursorCreatedAt,
+		arg.CursorClerkID,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersPageIncludingDeletedRow
+	for rows.Next() {
+		var i ListUsersPageIncludingDeletedRow
+		if err := rows.Scan(
+			&i.ClerkID,
+			&i.Name,
+			&i.Email,
+			&i.Username,
+			&i.FirstName,
+			&i.LastName,
+			&i.Role,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.LastLoginAt,
+			&i.BannedAt,
+			&i.Locale,
+			&i.Phone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreUser = `-- name: RestoreUser :execrows
+UPDATE users
+SET deleted_at = NULL, merged_into = NULL, is_active = TRUE, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, clerkID string) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreUser, clerkID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rewriteUserPII = `-- name: RewriteUserPII :execrows
+UPDATE users
+SET email      = $1::text,
+    email_bidx = $2::text,
+    phone      = $3::text
+WHERE clerk_id = $4
+  AND email IS NOT DISTINCT FROM $5::text
+  AND phone IS NOT DISTINCT FROM $6::text
+`
+
+type RewriteUserPIIParams struct {
+	NewEmail  pgtype.Text `json:"new_email"`
+	EmailBidx pgtype.Text `json:"email_bidx"`
+	NewPhone  pgtype.Text `json:"new_phone"`
+	ClerkID   string      `json:"clerk_id"`
+	OldEmail  pgtype.Text `json:"old_email"`
+	OldPhone  pgtype.Text `json:"old_phone"`
+}
+
+// Replaces email and phone with re-encrypted values, unless either changed
+// since it was read. It is not a profile change, so version is left alone.
+func (q *Queries) RewriteUserPII(ctx context.Context, arg RewriteUserPIIParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rewriteUserPII,
+		arg.NewEmail,
+		arg.EmailBidx,
+		arg.NewPhone,
+		arg.ClerkID,
+		arg.OldEmail,
+		arg.OldPhone,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :execrows
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NULL
+`
+
+type SetUserAvatarParams struct {
+	ClerkID   string      `json:"clerk_id"`
+	AvatarKey pgtype.Text `json:"avatar_key"`
+}
+
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserAvatar, arg.ClerkID, arg.AvatarKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserBanned = `-- name: SetUserBanned :execrows
+UPDATE users
+SET banned_at  = CASE WHEN $2::boolean THEN COALESCE(banned_at, NOW()) ELSE NULL END,
+    updated_at = NOW()
+WHERE clerk_id = $1
+`
+
+type SetUserBannedParams struct {
+	ClerkID string `json:"clerk_id"`
+	Banned  bool   `json:"banned"`
+}
+
+func (q *Queries) SetUserBanned(ctx context.Context, arg SetUserBannedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserBanned, arg.ClerkID, arg.Banned)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserPreferences = `-- name: SetUserPreferences :execrows
+UPDATE users
+SET preferences = $2, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NULL
+`
+
+type SetUserPreferencesParams struct {
+	ClerkID     string `json:"clerk_id"`
+	Preferences []byte `json:"preferences"`
+}
+
+func (q *Queries) SetUserPreferences(ctx context.Context, arg SetUserPreferencesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserPreferences, arg.ClerkID, arg.Preferences)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const softDeleteUserByClerkID = `-- name: SoftDeleteUserByClerkID :exec
 UPDATE users
 SET deleted_at = NOW(), is_active = FALSE, updated_at = NOW()
 WHERE clerk_id = $1
 `
 
+// Users are never hard-deleted; list and get queries skip soft-deleted rows.
 func (q *Queries) SoftDeleteUserByClerkID(ctx context.Context, clerkID string) error {
 	_, err := q.db.Exec(ctx, softDeleteUserByClerkID, clerkID)
 	return err
@@ -111,53 +850,206 @@ func (q *Queries) SoftDeleteUserByClerkID(ctx context.Context, clerkID string) e
 
 const updateLastLogin = `-- name: UpdateLastLogin :exec
 UPDATE users
-SET last_login_at = NOW(), updated_at = NOW()
-WHERE clerk_id = $1
+SET last_login_at = GREATEST(last_login_at, $1::timestamptz),
+    updated_at    = NOW()
+WHERE clerk_id = $2
 `
 
-func (q *Queries) UpdateLastLogin(ctx context.Context, clerkID string) error {
-	_, err := q.db.Exec(ctx, updateLastLogin, clerkID)
+type UpdateLastLoginParams struct {
+	LoggedInAt pgtype.Timestamptz `json:"logged_in_at"`
+	ClerkID    string             `json:"clerk_id"`
+}
+
+// Events can be processed late or out of order, so last_login_at only ever
+// moves forward.
+func (q *Queries) UpdateLastLogin(ctx context.Context, arg UpdateLastLoginParams) error {
+	_, err := q.db.Exec(ctx, updateLastLogin, arg.LoggedInAt, arg.ClerkID)
 	return err
 }
 
-const upsertUserWithRole = `-- name: UpsertUserWithRole :exec
-INSERT INTO users (clerk_id, username, name, email, first_name, last_name, role, is_active, created_at, updated_at)
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET name       = COALESCE($1::text, name),
+    username   = CASE WHEN $2::text IS NULL THEN username ELSE NULLIF($2::text, '') END,
+    locale     = CASE WHEN $3::text IS NULL THEN locale ELSE NULLIF($3::text, '') END,
+    phone      = CASE WHEN $4::text IS NULL THEN phone ELSE NULLIF($4::text, '') END,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE clerk_id = $5 AND deleted_at IS NULL
+  AND ($6::bigint IS NULL OR version = $6::bigint)
+RETURNING
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version
+`
+
+type UpdateUserProfileParams struct {
+	Name            pgtype.Text `json:"name"`
+	Username        pgtype.Text `json:"username"`
+	Locale          pgtype.Text `json:"locale"`
+	Phone           pgtype.Text `json:"phone"`
+	ClerkID         string      `json:"clerk_id"`
+	ExpectedVersion pgtype.Int8 `json:"expected_version"`
+}
+
+type UpdateUserProfileRow struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+	Version     int64              `json:"version"`
+}
+
+// Partial update: NULL leaves a column unchanged, an empty string clears the
+// optional ones. With expected_version set, a row whose version differs is
+// left alone and no row is returned.
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile,
+		arg.Name,
+		arg.Username,
+		arg.Locale,
+		arg.Phone,
+		arg.ClerkID,
+		arg.ExpectedVersion,
+	)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.ClerkID,
+		&i.Name,
+		&i.Email,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Role,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.LastLoginAt,
+		&i.BannedAt,
+		&i.Locale,
+		&i.Phone,
+		&i.Version,
+	)
+	return i, err
+}
+
+const upsertUserWithRole = `-- name: UpsertUserWithRole :execrows
+INSERT INTO users (clerk_id, username, name, email, first_name, last_name, role, is_active, created_at, updated_at, clerk_updated_at, email_bidx)
 VALUES (
   $1, $2, $3, $4, $5, $6,
   CASE WHEN NOT EXISTS (SELECT 1 FROM users WHERE deleted_at IS NULL)
        THEN 'superadmin'
-       ELSE 'user'
+       ELSE COALESCE($7::text, 'user')
   END,
-  TRUE, NOW(), NOW()
+  TRUE, NOW(), NOW(), $8, $9
 )
 ON CONFLICT (clerk_id) DO UPDATE
-SET username   = EXCLUDED.username,
-    name       = EXCLUDED.name,
-    first_name = EXCLUDED.first_name,
-    last_name  = EXCLUDED.last_name,
-    email      = COALESCE(EXCLUDED.email, users.email),
-    is_active  = TRUE,
-    deleted_at = NULL,
-    updated_at = NOW()
+SET username         = EXCLUDED.username,
+    name             = EXCLUDED.name,
+    first_name       = EXCLUDED.first_name,
+    last_name        = EXCLUDED.last_name,
+    email            = COALESCE(EXCLUDED.email, users.email),
+    email_bidx       = CASE WHEN EXCLUDED.email IS NULL THEN users.email_bidx ELSE EXCLUDED.email_bidx END,
+    is_active        = users.merged_into IS NULL,
+    deleted_at       = CASE WHEN users.merged_into IS NULL THEN NULL ELSE users.deleted_at END,
+    clerk_updated_at = COALESCE(EXCLUDED.clerk_updated_at, users.clerk_updated_at),
+    version          = users.version + 1,
+    updated_at       = NOW()
+WHERE users.erased_at IS NULL
+  AND (EXCLUDED.clerk_updated_at IS NULL
+       OR users.clerk_updated_at IS NULL
+       OR EXCLUDED.clerk_updated_at >= users.clerk_updated_at)
 `
 
 type UpsertUserWithRoleParams struct {
-	ClerkID   string      `json:"clerk_id"`
-	Username  pgtype.Text `json:"username"`
-	Name      string      `json:"name"`
-	Email     pgtype.Text `json:"email"`
-	FirstName pgtype.Text `json:"first_name"`
-	LastName  pgtype.Text `json:"last_name"`
+	ClerkID        string             `json:"clerk_id"`
+	Username       pgtype.Text        `json:"username"`
+	Name           string             `json:"name"`
+	Email          pgtype.Text        `json:"email"`
+	FirstName      pgtype.Text        `json:"first_name"`
+	LastName       pgtype.Text        `json:"last_name"`
+	InitialRole    pgtype.Text        `json:"initial_role"`
+	ClerkUpdatedAt pgtype.Timestamptz `json:"clerk_updated_at"`
+	EmailBidx      pgtype.Text        `json:"email_bidx"`
 }
 
-func (q *Queries) UpsertUserWithRole(ctx context.Context, arg UpsertUserWithRoleParams) error {
-	_, err := q.db.Exec(ctx, upsertUserWithRole,
+// initial_role only applies when the row is inserted; an existing user's
+// role is never changed by a sync. Rows merged into another user stay
+// deleted, and erased rows are not touched at all. A row older than the
+// last applied one (by Clerk's updated_at) is stale and skipped. Skipped
+// rows report 0 rows affected.
+// email_bidx must be the blind index of email and is kept with it.
+func (q *Queries) UpsertUserWithRole(ctx context.Context, arg UpsertUserWithRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertUserWithRole,
 		arg.ClerkID,
 		arg.Username,
 		arg.Name,
 		arg.Email,
 		arg.FirstName,
 		arg.LastName,
+		arg.InitialRole,
+		arg.ClerkUpdatedAt,
+		arg.EmailBidx,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userExists = `-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE clerk_id = $1)
+`
+
+func (q *Queries) UserExists(ctx context.Context, clerkID string) (bool, error) {
+	row := q.db.QueryRow(ctx, userExists, clerkID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const usersFingerprint = `-- name: UsersFingerprint :one
+SELECT
+    COUNT(*) FILTER (WHERE deleted_at IS NULL) AS active_count,
+    COALESCE(MAX(updated_at), 'epoch'::timestamptz)::timestamptz AS last_updated_at
+FROM users
+`
+
+type UsersFingerprintRow struct {
+	ActiveCount   int64              `json:"active_count"`
+	LastUpdatedAt pgtype.Timestamptz `json:"last_updated_at"`
+}
+
+// Cheap change detector for ETags: any insert, update or soft delete bumps
+// either the count or the latest updated_at.
+func (q *Queries) UsersFingerprint(ctx context.Context) (UsersFingerprintRow, error) {
+	row := q.db.QueryRow(ctx, usersFingerprint)
+	var i UsersFingerprintRow
+	err := row.Scan(&i.ActiveCount, &i.LastUpdatedAt)
+	return i, err
 }
@@ -0,0 +1,313 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhook_subscriptions.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET status          = 'delivering',
+    attempts        = d.attempts + 1,
+    next_attempt_at = NOW() + ($1::int * INTERVAL '1 second')
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id
+  AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status IN ('pending', 'delivering', 'failed') AND next_attempt_at <= NOW()
+    ORDER BY id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event_type, d.payload, d.attempts, s.url, s.secret
+`
+
+type ClaimDueWebhookDeliveriesParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	BatchSize    int32 `json:"batch_size"`
+}
+
+type ClaimDueWebhookDeliveriesRow struct {
+	ID        int64  `json:"id"`
+	EventType string `json:"event_type"`
+	Payload   []byte `json:"payload"`
+	Attempts  int32  `json:"attempts"`
+	Url       string `json:"url"`
+	Secret    string `json:"secret"`
+}
+
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]ClaimDueWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimDueWebhookDeliveries, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDueWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, secret, event_types, active, created_at, updated_at)
+VALUES ($1, $2, $3, TRUE, NOW(), NOW())
+RETURNING id, url, event_types, active, created_at, updated_at
+`
+
+type CreateWebhookSubscriptionParams struct {
+	Url        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+type CreateWebhookSubscriptionRow struct {
+	ID         int64              `json:"id"`
+	Url        string             `json:"url"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (CreateWebhookSubscriptionRow, error) {
+	row := q.db.QueryRow(ctx, createWebhookSubscription, arg.Url, arg.Secret, arg.EventTypes)
+	var i CreateWebhookSubscriptionRow
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookSubscription, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueWebhookDeliveries = `-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+SELECT id, $1::text, $2::jsonb
+FROM webhook_subscriptions
+WHERE active AND (cardinality(event_types) = 0 OR $1::text = ANY(event_types))
+`
+
+type EnqueueWebhookDeliveriesParams struct {
+	EventType string `json:"event_type"`
+	Payload   []byte `json:"payload"`
+}
+
+// Fans one event out to every active subscriber interested in its type.
+func (q *Queries) EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enqueueWebhookDeliveries, arg.EventType, arg.Payload)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, url, event_types, active, created_at, updated_at
+FROM webhook_subscriptions
+WHERE id = $1
+`
+
+type GetWebhookSubscriptionRow struct {
+	ID         int64              `json:"id"`
+	Url        string             `json:"url"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id int64) (GetWebhookSubscriptionRow, error) {
+	row := q.db.QueryRow(ctx, getWebhookSubscription, id)
+	var i GetWebhookSubscriptionRow
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT id, url, event_types, active, created_at, updated_at
+FROM webhook_subscriptions
+ORDER BY id
+`
+
+type ListWebhookSubscriptionsRow struct {
+	ID         int64              `json:"id"`
+	Url        string             `json:"url"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context) ([]ListWebhookSubscriptionsRow, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWebhookSubscriptionsRow
+	for rows.Next() {
+		var i ListWebhookSubscriptionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.EventTypes,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookDeliveryDead = `-- name: MarkWebhookDeliveryDead :exec
+UPDATE webhook_deliveries
+SET status = 'dead', last_error = $2, response_status = $3
+WHERE id = $1
+`
+
+type MarkWebhookDeliveryDeadParams struct {
+	ID             int64       `json:"id"`
+	LastError      pgtype.Text `json:"last_error"`
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+}
+
+func (q *Queries) MarkWebhookDeliveryDead(ctx context.Context, arg MarkWebhookDeliveryDeadParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliveryDead, arg.ID, arg.LastError, arg.ResponseStatus)
+	return err
+}
+
+const markWebhookDeliveryDelivered = `-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', last_error = NULL, response_status = $2, delivered_at = NOW()
+WHERE id = $1
+`
+
+type MarkWebhookDeliveryDeliveredParams struct {
+	ID             int64       `json:"id"`
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+}
+
+func (q *Queries) MarkWebhookDeliveryDelivered(ctx context.Context, arg MarkWebhookDeliveryDeliveredParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliveryDelivered, arg.ID, arg.ResponseStatus)
+	return err
+}
+
+const markWebhookDeliveryFailed = `-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = 'failed', last_error = $2, response_status = $3, next_attempt_at = $4
+WHERE id = $1
+`
+
+type MarkWebhookDeliveryFailedParams struct {
+	ID             int64              `json:"id"`
+	LastError      pgtype.Text        `json:"last_error"`
+	ResponseStatus pgtype.Int4        `json:"response_status"`
+	NextAttemptAt  pgtype.Timestamptz `json:"next_attempt_at"`
+}
+
+func (q *Queries) MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliveryFailed,
+		arg.ID,
+		arg.LastError,
+		arg.ResponseStatus,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url         = COALESCE($1, url),
+    event_types = COALESCE($2::text[], event_types),
+    active      = COALESCE($3, active),
+    secret      = COALESCE($4, secret),
+    updated_at  = NOW()
+WHERE id = $5
+RETURNING id, url, event_types, active, created_at, updated_at
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	Url        pgtype.Text `json:"url"`
+	EventTypes []string    `json:"event_types"`
+	Active     pgtype.Bool `json:"active"`
+	Secret     pgtype.Text `json:"secret"`
+	ID         int64       `json:"id"`
+}
+
+type UpdateWebhookSubscriptionRow struct {
+	ID         int64              `json:"id"`
+	Url        string             `json:"url"`
+	EventTypes []string           `json:"event_types"`
+	Active     bool               `json:"active"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (UpdateWebhookSubscriptionRow, error) {
+	row := q.db.QueryRow(ctx, updateWebhookSubscription,
+		arg.Url,
+		arg.EventTypes,
+		arg.Active,
+		arg.Secret,
+		arg.ID,
+	)
+	var i UpdateWebhookSubscriptionRow
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.EventTypes,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,367 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhooks.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimDueWebhookEvents = `-- name: ClaimDueWebhookEvents :many
+UPDATE webhook_events
+SET status          = 'processing',
+    attempts        = attempts + 1,
+    next_attempt_at = NOW() + ($1::int * INTERVAL '1 second')
+WHERE id IN (
+    SELECT e.id FROM webhook_events e
+    WHERE e.status IN ('received', 'processing', 'failed') AND e.next_attempt_at <= NOW()
+      AND (e.ordering_key IS NULL OR NOT EXISTS (
+          SELECT 1 FROM webhook_events prev
+          WHERE prev.ordering_key = e.ordering_key
+            AND prev.status IN ('received', 'processing', 'failed')
+            AND (prev.occurred_at, prev.id) < (e.occurred_at, e.id)
+      ))
+    ORDER BY e.occurred_at, e.id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, svix_id, event_type, payload, attempts
+`
+
+type ClaimDueWebhookEventsParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	BatchSize    int32 `json:"batch_size"`
+}
+
+type ClaimDueWebhookEventsRow struct {
+	ID        int64  `json:"id"`
+	SvixID    string `json:"svix_id"`
+	EventType string `json:"event_type"`
+	Payload   []byte `json:"payload"`
+	Attempts  int32  `json:"attempts"`
+}
+
+// Claimed rows get a lease: if the worker dies mid-batch they become due
+// again once next_attempt_at passes. An event is only claimed when no older
+// event with the same ordering_key is still unfinished, which serializes
+// processing per Clerk object.
+func (q *Queries) ClaimDueWebhookEvents(ctx context.Context, arg ClaimDueWebhookEventsParams) ([]ClaimDueWebhookEventsRow, error) {
+	rows, err := q.db.Query(ctx, claimDueWebhookEvents, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDueWebhookEventsRow
+	for rows.Next() {
+		var i ClaimDueWebhookEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SvixID,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countDeadWebhookEvents = `-- name: CountDeadWebhookEvents :one
+SELECT COUNT(*) FROM webhook_events
+WHERE status = 'dead'
+  AND processed_at >= $1
+  AND ($2::text IS NULL OR event_type = $2)
+`
+
+type CountDeadWebhookEventsParams struct {
+	Since     pgtype.Timestamptz `json:"since"`
+	EventType pgtype.Text        `json:"event_type"`
+}
+
+func (q *Queries) CountDeadWebhookEvents(ctx context.Context, arg CountDeadWebhookEventsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countDeadWebhookEvents, arg.Since, arg.EventType)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countWebhookEvents = `-- name: CountWebhookEvents :one
+SELECT COUNT(*)
+FROM webhook_events
+WHERE ($1::text IS NULL OR status = $1)
+`
+
+func (q *Queries) CountWebhookEvents(ctx context.Context, status pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, countWebhookEvents, status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteProcessedWebhooksBefore = `-- name: DeleteProcessedWebhooksBefore :execrows
+DELETE FROM processed_webhooks WHERE processed_at < $1
+`
+
+func (q *Queries) DeleteProcessedWebhooksBefore(ctx context.Context, processedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProcessedWebhooksBefore, processedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getWebhookEvent = `-- name: GetWebhookEvent :one
+SELECT id, svix_id, event_type, payload, status, error, error_code, attempts, received_at, processed_at, next_attempt_at
+FROM webhook_events
+WHERE id = $1
+`
+
+type GetWebhookEventRow struct {
+	ID            int64              `json:"id"`
+	SvixID        string             `json:"svix_id"`
+	EventType     string             `json:"event_type"`
+	Payload       []byte             `json:"payload"`
+	Status        string             `json:"status"`
+	Error         pgtype.Text        `json:"error"`
+	ErrorCode     pgtype.Text        `json:"error_code"`
+	Attempts      int32              `json:"attempts"`
+	ReceivedAt    pgtype.Timestamptz `json:"received_at"`
+	ProcessedAt   pgtype.Timestamptz `json:"processed_at"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+}
+
+func (q *Queries) GetWebhookEvent(ctx context.Context, id int64) (GetWebhookEventRow, error) {
+	row := q.db.QueryRow(ctx, getWebhookEvent, id)
+	var i GetWebhookEventRow
+	err := row.Scan(
+		&i.ID,
+		&i.SvixID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.ErrorCode,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ProcessedAt,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const insertWebhookEvent = `-- name: InsertWebhookEvent :one
+INSERT INTO webhook_events (svix_id, event_type, payload, ordering_key, occurred_at, status, received_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, 'received', NOW(), NOW())
+ON CONFLICT (svix_id) DO NOTHING
+RETURNING id
+`
+
+type InsertWebhookEventParams struct {
+	SvixID      string             `json:"svix_id"`
+	EventType   string             `json:"event_type"`
+	Payload     []byte             `json:"payload"`
+	OrderingKey pgtype.Text        `json:"ordering_key"`
+	OccurredAt  pgtype.Timestamptz `json:"occurred_at"`
+}
+
+// Returns no row when the svix-id is already queued; the worker owns it.
+func (q *Queries) InsertWebhookEvent(ctx context.Context, arg InsertWebhookEventParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertWebhookEvent,
+		arg.SvixID,
+		arg.EventType,
+		arg.Payload,
+		arg.OrderingKey,
+		arg.OccurredAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const isWebhookProcessed = `-- name: IsWebhookProcessed :one
+SELECT EXISTS (SELECT 1 FROM processed_webhooks WHERE svix_id = $1)
+`
+
+func (q *Queries) IsWebhookProcessed(ctx context.Context, svixID string) (bool, error) {
+	row := q.db.QueryRow(ctx, isWebhookProcessed, svixID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listWebhookEvents = `-- name: ListWebhookEvents :many
+SELECT id, svix_id, event_type, status, error, error_code, attempts, received_at, processed_at, next_attempt_at
+FROM webhook_events
+WHERE ($1::text IS NULL OR status = $1)
+  AND ($2::bigint IS NULL OR id < $2::bigint)
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListWebhookEventsParams struct {
+	Status   pgtype.Text `json:"status"`
+	BeforeID pgtype.Int8 `json:"before_id"`
+	RowLimit int32       `json:"row_limit"`
+}
+
+type ListWebhookEventsRow struct {
+	ID            int64              `json:"id"`
+	SvixID        string             `json:"svix_id"`
+	EventType     string             `json:"event_type"`
+	Status        string             `json:"status"`
+	Error         pgtype.Text        `json:"error"`
+	ErrorCode     pgtype.Text        `json:"error_code"`
+	Attempts      int32              `json:"attempts"`
+	ReceivedAt    pgtype.Timestamptz `json:"received_at"`
+	ProcessedAt   pgtype.Timestamptz `json:"processed_at"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+}
+
+// Newest first; before_id is the cursor from the previous page.
+func (q *Queries) ListWebhookEvents(ctx context.Context, arg ListWebhookEventsParams) ([]ListWebhookEventsRow, error) {
+	rows, err := q.db.Query(ctx, listWebhookEvents, arg.Status, arg.BeforeID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWebhookEventsRow
+	for rows.Next() {
+		var i ListWebhookEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SvixID,
+			&i.EventType,
+			&i.Status,
+			&i.Error,
+			&i.ErrorCode,
+			&i.Attempts,
+			&i.ReceivedAt,
+			&i.ProcessedAt,
+			&i.NextAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookEventDead = `-- name: MarkWebhookEventDead :exec
+UPDATE webhook_events
+SET status = 'dead', error = $2, error_code = $3, processed_at = NOW()
+WHERE id = $1
+`
+
+type MarkWebhookEventDeadParams struct {
+	ID        int64       `json:"id"`
+	Error     pgtype.Text `json:"error"`
+	ErrorCode pgtype.Text `json:"error_code"`
+}
+
+func (q *Queries) MarkWebhookEventDead(ctx context.Context, arg MarkWebhookEventDeadParams) error {
+	_, err := q.db.Exec(ctx, markWebhookEventDead, arg.ID, arg.Error, arg.ErrorCode)
+	return err
+}
+
+const markWebhookEventFailed = `-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET status = 'failed', error = $2, next_attempt_at = $3
+WHERE id = $1
+`
+
+type MarkWebhookEventFailedParams struct {
+	ID            int64              `json:"id"`
+	Error         pgtype.Text        `json:"error"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+}
+
+func (q *Queries) MarkWebhookEventFailed(ctx context.Context, arg MarkWebhookEventFailedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookEventFailed, arg.ID, arg.Error, arg.NextAttemptAt)
+	return err
+}
+
+const markWebhookEventProcessed = `-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET status = 'processed', error = NULL, error_code = NULL, processed_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookEventProcessed(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markWebhookEventProcessed, id)
+	return err
+}
+
+const markWebhookProcessed = `-- name: MarkWebhookProcessed :exec
+INSERT INTO processed_webhooks (svix_id, event_type, processed_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (svix_id) DO NOTHING
+`
+
+type MarkWebhookProcessedParams struct {
+	SvixID    string `json:"svix_id"`
+	EventType string `json:"event_type"`
+}
+
+func (q *Queries) MarkWebhookProcessed(ctx context.Context, arg MarkWebhookProcessedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookProcessed, arg.SvixID, arg.EventType)
+	return err
+}
+
+const requeueDeadWebhookEvents = `-- name: RequeueDeadWebhookEvents :execrows
+UPDATE webhook_events
+SET status          = 'received',
+    attempts        = 0,
+    error           = NULL,
+    error_code      = NULL,
+    processed_at    = NULL,
+    next_attempt_at = NOW()
+WHERE status = 'dead'
+  AND processed_at >= $1
+  AND ($2::text IS NULL OR event_type = $2)
+`
+
+type RequeueDeadWebhookEventsParams struct {
+	Since     pgtype.Timestamptz `json:"since"`
+	EventType pgtype.Text        `json:"event_type"`
+}
+
+func (q *Queries) RequeueDeadWebhookEvents(ctx context.Context, arg RequeueDeadWebhookEventsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueDeadWebhookEvents, arg.Since, arg.EventType)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const requeueWebhookEvent = `-- name: RequeueWebhookEvent :execrows
+UPDATE webhook_events
+SET status          = 'received',
+    attempts        = 0,
+    error           = NULL,
+    error_code      = NULL,
+    processed_at    = NULL,
+    next_attempt_at = NOW()
+WHERE id = $1 AND status IN ('failed', 'dead', 'processed')
+`
+
+// Only settled events can be replayed; rows still queued or in flight are
+// left to the worker.
+func (q *Queries) RequeueWebhookEvent(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueWebhookEvent, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// actor identifies the caller in the activity log.
+func actor(c *gin.Context) string {
+	if id := c.GetString("clerk_id"); id != "" {
+		return id
+	}
+	if id := c.GetInt64("api_key_id"); id != 0 {
+		return "api_key:" + strconv.FormatInt(id, 10)
+	}
+	return "unknown"
+}
+
+// recordUserEvent appends to clerkID's activity log. The log is advisory:
+// a failure is logged and never fails the request that caused it.
+func recordUserEvent(c *gin.Context, q *db.Queries, clerkID, eventType string, details any) {
+	doc := []byte("{}")
+	if details != nil {
+		if b, err := json.Marshal(details); err == nil {
+			doc = b
+		}
+	}
+	if err := q.InsertUserEvent(c.Request.Context(), db.InsertUserEventParams{
+		ClerkID:   clerkID,
+		EventType: eventType,
+		Actor:     actor(c),
+		Details:   doc,
+	}); err != nil {
+		slog.WarnContext(c.Request.Context(), "activity: recording event failed",
+			"event_type", eventType, "clerk_id", clerkID, "error", err)
+	}
+}
+
+type userEventResponse struct {
+	ID        int64           `json:"id"`
+	EventType string          `json:"event_type"`
+	Actor     string          `json:"actor"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Activity returns the user's activity log, newest first, paginated with
+// ?limit= and ?cursor=.
+func (h *UserHandler) Activity(c *gin.Context) {
+	ctx := c.Request.Context()
+	clerkID := c.Param("id")
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			writeError(c, apierror.Invalid("limit", "limit must be between 1 and 200"))
+			return
+		}
+		limit = n
+	}
+	params := db.ListUserEventsParams{ClerkID: clerkID, RowLimit: int32(limit)}
+	if v := c.Query("cursor"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(c, errBadCursor)
+			return
+		}
+		params.BeforeID = pgtype.Int8{Int64: id, Valid: true}
+	}
+
+	events, err := h.q.ListUserEvents(ctx, params)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve activity"))
+		return
+	}
+	total, err := h.q.CountUserEvents(ctx, clerkID)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve activity"))
+		return
+	}
+
+	data := make([]userEventResponse, 0, len(events))
+	for _, e := range events {
+		data = append(data, userEventResponse{
+			ID:        e.ID,
+			EventType: e.EventType,
+			Actor:     e.Actor,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt.Time,
+		})
+	}
+	c.JSON(http.StatusOK, newPage(data, total, limit, c.Query("cursor"), func(last userEventResponse) string {
+		return strconv.FormatInt(last.ID, 10)
+	}))
+}
@@ -0,0 +1,221 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/crypto"
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportUsersSQL mirrors ListUsersPage without pagination. sqlc's :many
+// buffers every row, so the export runs this through pgx directly and
+// streams rows as they arrive.
+const exportUsersSQL = `
+SELECT
+    clerk_id,
+    name,
+    COALESCE(email, ''),
+    COALESCE(username, ''),
+    COALESCE(first_name, ''),
+    COALESCE(last_name, ''),
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, ''),
+    COALESCE(phone, '')
+FROM users u
+WHERE deleted_at IS NULL
+  AND ($1::text IS NULL
+       OR name ILIKE '%' || $1::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || $1::text || '%')
+       OR email_bidx = $6::text
+       OR username ILIKE '%' || $1::text || '%')
+  AND ($2::boolean IS NULL OR (COALESCE(email, '') <> '') = $2::boolean)
+  AND ($3::timestamptz IS NULL OR created_at > $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz)
+  AND ($5::text IS NULL OR EXISTS (
+      SELECT 1 FROM memberships m WHERE m.clerk_user_id = u.clerk_id AND m.clerk_org_id = $5::text))
+ORDER BY created_at DESC, clerk_id DESC`
+
+type exportedUser struct {
+	ClerkID     string             `json:"clerk_id"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+	Username    string             `json:"username"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Role        string             `json:"role"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	BannedAt    pgtype.Timestamptz `json:"banned_at"`
+	Locale      string             `json:"locale"`
+	Phone       string             `json:"phone"`
+}
+
+var exportCSVHeader = []string{
+	"clerk_id", "name", "email", "username", "first_name", "last_name", "role",
+	"is_active", "created_at", "updated_at", "last_login_at", "banned_at", "locale", "phone",
+}
+
+func (u exportedUser) csvRecord() []string {
+	ts := func(t pgtype.Timestamptz) string {
+		if !t.Valid {
+			return ""
+		}
+		return t.Time.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		u.ClerkID, u.Name, u.Email, u.Username, u.FirstName, u.LastName, u.Role,
+		strconv.FormatBool(u.IsActive), ts(u.CreatedAt), ts(u.UpdatedAt), ts(u.LastLoginAt), ts(u.BannedAt),
+		u.Locale, u.Phone,
+	}
+}
+
+// UserExportHandler streams the user directory as CSV or JSON, reading from
+// the replica when one is configured. Email and phone are decrypted with
+// pii.
+type UserExportHandler struct {
+	pool *db.ReadPool
+	pii  *crypto.Keyring
+}
+
+func NewUserExportHandler(pool *db.ReadPool, pii *crypto.Keyring) *UserExportHandler {
+	return &UserExportHandler{pool: pool, pii: pii}
+}
+
+// Export streams every active user matching the /users filters (plus
+// ?org_id=) as ?format=csv (default) or ?format=json. Rows are written as
+// they are read, so memory use does not grow with the table.
+func (h *UserExportHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		writeError(c, apierror.Invalid("format", "format must be csv or json"))
+		return
+	}
+	filters, err := parseUserFilters(c, h.pii)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	orgID := pgtype.Text{String: c.Query("org_id"), Valid: c.Query("org_id") != ""}
+
+	// The pools' statement_timeout would cancel a large export part way
+	// through, so it runs in a read-only transaction with the timeout lifted.
+	// The request context still ends it when the client goes away.
+	ctx := c.Request.Context()
+	tx, err := h.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to export users"))
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+		writeError(c, apierror.Internal(err, "failed to export users"))
+		return
+	}
+
+	rows, err := tx.Query(ctx, exportUsersSQL,
+		filters.Q, filters.HasEmail, filters.CreatedAfter, filters.CreatedBefore, orgID, filters.QBidx)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to export users"))
+		return
+	}
+	defer rows.Close()
+
+	filename := "users-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json")
+	}
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure past this point can only be
+	// logged and the response cut short.
+	csvw := csv.NewWriter(c.Writer)
+	if format == "csv" {
+		_ = csvw.Write(exportCSVHeader)
+	} else {
+		_, _ = c.Writer.WriteString("[")
+	}
+
+	n := 0
+	for rows.Next() {
+		var u exportedUser
+		if err := rows.Scan(&u.ClerkID, &u.Name, &u.Email, &u.Username, &u.FirstName, &u.LastName, &u.Role,
+			&u.IsActive, &u.CreatedAt, &u.UpdatedAt, &u.LastLoginAt, &u.BannedAt, &u.Locale, &u.Phone); err != nil {
+			slog.ErrorContext(ctx, "users export: scanning row failed", "error", err)
+			return
+		}
+		if err := revealPII(h.pii, &u.Email, &u.Phone); err != nil {
+			return
+		}
+
+		if format == "csv" {
+			if err := csvw.Write(u.csvRecord()); err != nil {
+				slog.ErrorContext(ctx, "users export: writing row failed", "error", err)
+				return
+			}
+		} else {
+			b, err := json.Marshal(u)
+			if err != nil {
+				slog.ErrorContext(ctx, "users export: encoding row failed", "error", err)
+				return
+			}
+			if n > 0 {
+				_, _ = c.Writer.WriteString(",")
+			}
+			if _, err := c.Writer.Write(b); err != nil {
+				slog.ErrorContext(ctx, "users export: writing row failed", "error", err)
+				return
+			}
+		}
+
+		n++
+		if n%500 == 0 {
+			csvw.Flush()
+			c.Writer.Flush()
+			extendWriteDeadline(c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(ctx, "users export: reading rows failed", "rows", n, "error", err)
+		return
+	}
+
+	if format == "csv" {
+		csvw.Flush()
+	} else {
+		_, _ = c.Writer.WriteString("]")
+	}
+	c.Writer.Flush()
+}
+
+// extendWriteDeadline gives a streaming response another WRITE_TIMEOUT from
+// now. The server's write timeout covers the whole response, which a large
+// export outlasts; renewing it per batch still cuts off a client that stops
+// reading.
+func extendWriteDeadline(c *gin.Context) {
+	srv, ok := c.Request.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok || srv.WriteTimeout <= 0 {
+		return
+	}
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(srv.WriteTimeout))
+}
@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mergeUsersRequest struct {
+	// DuplicateID is the Clerk ID of the account that is merged away.
+	DuplicateID string `json:"duplicate_id" validate:"required"`
+}
+
+type mergeResult struct {
+	SurvivorID         string `json:"survivor_id"`
+	DuplicateID        string `json:"duplicate_id"`
+	MovedMemberships   int64  `json:"moved_memberships"`
+	DroppedMemberships int64  `json:"dropped_memberships"`
+	MovedEvents        int64  `json:"moved_events"`
+}
+
+// Merge folds a duplicate account into the user at :id. Inside one
+// transaction it re-points memberships and activity to the survivor, fills
+// the survivor's empty profile fields from the duplicate and soft-deletes the
+// duplicate, which stays deleted even if Clerk keeps syncing it. Both users
+// get an activity record describing what moved. The duplicate's Clerk
+// account is left alone.
+func (h *AdminUserHandler) Merge(c *gin.Context) {
+	ctx := c.Request.Context()
+	survivorID := c.Param("id")
+
+	var req mergeUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+	if req.DuplicateID == survivorID {
+		writeError(c, apierror.BadRequest("cannot merge a user into itself"))
+		return
+	}
+
+	tx, err := h.pool.Begin(ctx)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to merge users"))
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	qtx := h.q.WithTx(tx)
+
+	locked, err := qtx.LockUsersForMerge(ctx, []string{survivorID, req.DuplicateID})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to merge users"))
+		return
+	}
+	if len(locked) != 2 {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	for _, u := range locked {
+		if u.ClerkID == survivorID && u.DeletedAt.Valid {
+			writeError(c, apierror.Conflict("the surviving user is deleted"))
+			return
+		}
+		if u.ClerkID == req.DuplicateID && u.MergedInto.Valid {
+			writeError(c, apierror.Conflict("duplicate was already merged into "+u.MergedInto.String))
+			return
+		}
+	}
+
+	res := mergeResult{SurvivorID: survivorID, DuplicateID: req.DuplicateID}
+	if err := mergeUsers(ctx, qtx, &res); err != nil {
+		writeError(c, apierror.Internal(err, "failed to merge users"))
+		return
+	}
+
+	details, _ := json.Marshal(res)
+	for _, e := range []db.InsertUserEventParams{
+		{ClerkID: survivorID, EventType: "user.merged", Actor: actor(c), Details: details},
+		{ClerkID: req.DuplicateID, EventType: "user.merged_away", Actor: actor(c), Details: details},
+	} {
+		if err := qtx.InsertUserEvent(ctx, e); err != nil {
+			writeError(c, apierror.Internal(err, "failed to merge users"))
+			return
+		}
+	}
+	// Subscribers see the survivor's inherited profile and the duplicate
+	// go away.
+	err = announceUser(ctx, qtx, h.outbox, h.pii, "user.updated", survivorID)
+	if err == nil {
+		err = h.outbox.WithTx(qtx).Emit(ctx, "user.deleted", webhooks.UserEventData{ClerkID: req.DuplicateID})
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to merge users"))
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		writeError(c, apierror.Internal(err, "failed to merge users"))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// mergeUsers runs the data moves of Merge and records their counts in res.
+func mergeUsers(ctx context.Context, q *db.Queries, res *mergeResult) error {
+	var err error
+	res.DroppedMemberships, err = q.DropOverlappingMemberships(ctx, db.DropOverlappingMembershipsParams{
+		SurvivorID: res.SurvivorID, DuplicateID: res.DuplicateID,
+	})
+	if err != nil {
+		return err
+	}
+	res.MovedMemberships, err = q.MoveMemberships(ctx, db.MoveMembershipsParams{
+		SurvivorID: res.SurvivorID, DuplicateID: res.DuplicateID,
+	})
+	if err != nil {
+		return err
+	}
+	res.MovedEvents, err = q.MoveUserEvents(ctx, db.MoveUserEventsParams{
+		SurvivorID: res.SurvivorID, DuplicateID: res.DuplicateID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := q.InheritUserProfile(ctx, db.InheritUserProfileParams{
+		SurvivorID: res.SurvivorID, DuplicateID: res.DuplicateID,
+	}); err != nil {
+		return err
+	}
+	return q.MarkUserMerged(ctx, db.MarkUserMergedParams{
+		SurvivorID: res.SurvivorID, DuplicateID: res.DuplicateID,
+	})
+}
@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AdminSubscriptionHandler manages subscribers of our outgoing user
+// lifecycle webhooks.
+type AdminSubscriptionHandler struct {
+	q *db.Queries
+}
+
+func NewAdminSubscriptionHandler(q *db.Queries) *AdminSubscriptionHandler {
+	return &AdminSubscriptionHandler{q: q}
+}
+
+type subscriptionRequest struct {
+	// URL is required on create.
+	URL        *string   `json:"url" validate:"omitnil,subscriber_url"`
+	EventTypes *[]string `json:"event_types" validate:"omitnil,dive,required"`
+	Active     *bool     `json:"active"`
+	// RotateSecret issues a new signing secret on update.
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+func (r *subscriptionRequest) normalize() {
+	if r.URL != nil {
+		*r.URL = strings.TrimSpace(*r.URL)
+	}
+}
+
+func validSubscriberURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+func newSubscriptionSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (h *AdminSubscriptionHandler) List(c *gin.Context) {
+	subs, err := h.q.ListWebhookSubscriptions(c.Request.Context())
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve subscriptions"))
+		return
+	}
+	c.JSON(http.StatusOK, wholePage(subs))
+}
+
+func (h *AdminSubscriptionHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.Invalid("id", "invalid id"))
+		return
+	}
+	sub, err := h.q.GetWebhookSubscription(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("subscription not found"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve subscription"))
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// Create registers a subscriber. The signing secret is generated here and
+// only ever returned in this response.
+func (h *AdminSubscriptionHandler) Create(c *gin.Context) {
+	var req subscriptionRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+	if req.URL == nil {
+		writeError(c, apierror.Validation(apierror.FieldError{Field: "url", Message: "is required"}))
+		return
+	}
+	target := *req.URL
+	eventTypes := []string{}
+	if req.EventTypes != nil {
+		eventTypes = *req.EventTypes
+	}
+
+	secret, err := newSubscriptionSecret()
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to generate secret"))
+		return
+	}
+
+	sub, err := h.q.CreateWebhookSubscription(c.Request.Context(), db.CreateWebhookSubscriptionParams{
+		Url:        target,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to create subscription"))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"subscription": sub, "secret": secret})
+}
+
+// Update applies a partial update; omitted fields are left unchanged.
+func (h *AdminSubscriptionHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.Invalid("id", "invalid id"))
+		return
+	}
+	var req subscriptionRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	params := db.UpdateWebhookSubscriptionParams{ID: id}
+	if req.URL != nil {
+		params.Url = pgtype.Text{String: *req.URL, Valid: true}
+	}
+	if req.EventTypes != nil {
+		params.EventTypes = *req.EventTypes
+		if params.EventTypes == nil {
+			params.EventTypes = []string{}
+		}
+	}
+	if req.Active != nil {
+		params.Active = pgtype.Bool{Bool: *req.Active, Valid: true}
+	}
+	var secret string
+	if req.RotateSecret {
+		if secret, err = newSubscriptionSecret(); err != nil {
+			writeError(c, apierror.Internal(err, "failed to generate secret"))
+			return
+		}
+		params.Secret = pgtype.Text{String: secret, Valid: true}
+	}
+
+	sub, err := h.q.UpdateWebhookSubscription(c.Request.Context(), params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("subscription not found"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to update subscription"))
+		return
+	}
+
+	if secret != "" {
+		c.JSON(http.StatusOK, gin.H{"subscription": sub, "secret": secret})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscription": sub})
+}
+
+func (h *AdminSubscriptionHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.Invalid("id", "invalid id"))
+		return
+	}
+	n, err := h.q.DeleteWebhookSubscription(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to delete subscription"))
+		return
+	}
+	if n == 0 {
+		writeError(c, apierror.NotFound("subscription not found"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,279 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"backend/internal/apierror"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/invitation"
+	"github.com/clerk/clerk-sdk-go/v2/session"
+	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminUserHandler serves user management endpoints under /admin.
+// Creating, restoring and merging users is announced to webhook
+// subscribers through outbox.
+type AdminUserHandler struct {
+	q      *db.Queries
+	pool   *pgxpool.Pool
+	outbox *webhooks.Outbox
+	pii    *crypto.Keyring
+}
+
+func NewAdminUserHandler(q *db.Queries, pool *pgxpool.Pool, outbox *webhooks.Outbox, pii *crypto.Keyring) *AdminUserHandler {
+	return &AdminUserHandler{q: q, pool: pool, outbox: outbox, pii: pii}
+}
+
+// Ban suspends a user locally, which makes authMiddleware reject their
+// tokens, and then revokes their active Clerk sessions so the suspension
+// also applies to anything else trusting Clerk.
+func (h *AdminUserHandler) Ban(c *gin.Context) {
+	clerkID := c.Param("id")
+
+	n, err := h.q.SetUserBanned(c.Request.Context(), db.SetUserBannedParams{ClerkID: clerkID, Banned: true})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to ban user"))
+		return
+	}
+	if n == 0 {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.banned", nil)
+
+	revoked, err := revokeClerkSessions(c.Request.Context(), clerkID)
+	if err != nil {
+		writeError(c, apierror.Upstream(err, "user banned locally but revoking Clerk sessions failed").
+			With("revoked_sessions", revoked))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "clerk_id": clerkID, "revoked_sessions": revoked})
+}
+
+// Unban lifts a local suspension. Revoked Clerk sessions stay revoked; the
+// user has to sign in again.
+func (h *AdminUserHandler) Unban(c *gin.Context) {
+	clerkID := c.Param("id")
+
+	n, err := h.q.SetUserBanned(c.Request.Context(), db.SetUserBannedParams{ClerkID: clerkID, Banned: false})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to unban user"))
+		return
+	}
+	if n == 0 {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.unbanned", nil)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "clerk_id": clerkID})
+}
+
+// Restore undoes a soft delete. It does not recreate the user in Clerk, so
+// it is meant for rows removed locally by mistake or by a replayed event.
+func (h *AdminUserHandler) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+	clerkID := c.Param("id")
+
+	var n int64
+	err := db.WithTx(ctx, h.pool, func(q *db.Queries) error {
+		var err error
+		if n, err = q.RestoreUser(ctx, clerkID); err != nil || n == 0 {
+			return err
+		}
+		return announceUser(ctx, q, h.outbox, h.pii, "user.updated", clerkID)
+	})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to restore user"))
+		return
+	}
+	if n == 0 {
+		exists, err := h.q.UserExists(ctx, clerkID)
+		if err != nil {
+			writeError(c, apierror.Internal(err, "failed to restore user"))
+			return
+		}
+		if exists {
+			writeError(c, apierror.Conflict("user is not deleted"))
+			return
+		}
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.restored", nil)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "clerk_id": clerkID})
+}
+
+type createUserRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username" validate:"omitempty,username"`
+	// Role defaults to user.
+	Role string `json:"role" validate:"oneof=user admin superadmin"`
+	// Invite sends a Clerk invitation instead of creating the account
+	// directly; the local row appears once the invitee signs up.
+	Invite      bool   `json:"invite"`
+	RedirectURL string `json:"redirect_url" validate:"omitempty,http_url"`
+}
+
+func (r *createUserRequest) normalize() {
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	if r.Role == "" {
+		r.Role = "user"
+	}
+}
+
+// Create onboards a user that never signs up through the public flow. The
+// intended role is stored in the Clerk user's public metadata, so whichever
+// of this handler and the user.created webhook inserts the row first, the
+// user ends up with that role; later webhook upserts never change it.
+func (h *AdminUserHandler) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req createUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+	if req.Role == "superadmin" && c.GetString("role") != "superadmin" {
+		writeError(c, apierror.Forbidden("only superadmins may create superadmins"))
+		return
+	}
+
+	inUse, err := h.q.EmailInUse(ctx, db.EmailInUseParams{
+		Email:     req.Email,
+		EmailBidx: h.pii.BlindIndexText(webhooks.ToText(req.Email)),
+	})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to create user"))
+		return
+	}
+	if inUse {
+		writeError(c, apierror.Conflict("a user with this email already exists"))
+		return
+	}
+
+	metadata := json.RawMessage(`{"role":"` + req.Role + `"}`)
+
+	if req.Invite {
+		params := &invitation.CreateParams{EmailAddress: req.Email, PublicMetadata: &metadata}
+		if req.RedirectURL != "" {
+			params.RedirectURL = &req.RedirectURL
+		}
+		inv, err := invitation.Create(ctx, params)
+		if err != nil {
+			clerkError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"invitation_id": inv.ID, "email": inv.EmailAddress, "role": req.Role})
+		return
+	}
+
+	skipPassword := true
+	params := &clerkUser.CreateParams{
+		EmailAddresses:          &[]string{req.Email},
+		PublicMetadata:          &metadata,
+		SkipPasswordRequirement: &skipPassword,
+	}
+	if req.FirstName != "" {
+		params.FirstName = &req.FirstName
+	}
+	if req.LastName != "" {
+		params.LastName = &req.LastName
+	}
+	if req.Username != "" {
+		params.Username = &req.Username
+	}
+	created, err := clerkUser.Create(ctx, params)
+	if err != nil {
+		clerkError(c, err)
+		return
+	}
+
+	upsert := webhooks.ClerkUser{
+		ID:        created.ID,
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}.UpsertParams()
+	upsert.InitialRole = webhooks.ToText(req.Role)
+	upsert.Email, upsert.EmailBidx, err = webhooks.SealEmail(h.pii, webhooks.ToText(req.Email))
+	if err == nil {
+		err = db.WithTx(ctx, h.pool, func(q *db.Queries) error {
+			n, err := q.UpsertUserWithRole(ctx, upsert)
+			if err != nil || n == 0 {
+				// 0 rows: the webhook already applied a newer version and
+				// announced it.
+				return err
+			}
+			return announceUser(ctx, q, h.outbox, h.pii, "user.created", created.ID)
+		})
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "user created in Clerk but saving it locally failed; the webhook will sync it").
+			With("clerk_id", created.ID))
+		return
+	}
+	recordUserEvent(c, h.q, created.ID, "user.created_by_admin", gin.H{"role": req.Role})
+
+	user, err := h.q.GetUserByClerkID(ctx, created.ID)
+	if err == nil {
+		err = revealPII(h.pii, &user.Email, &user.Phone)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve user"))
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// clerkError maps a Clerk API error onto a response: identifier conflicts
+// become 409, everything else 502.
+func clerkError(c *gin.Context, err error) {
+	var apiErr *clerk.APIErrorResponse
+	if errors.As(err, &apiErr) {
+		for _, e := range apiErr.Errors {
+			if e.Code == "form_identifier_exists" || e.Code == "duplicate_record" {
+				writeError(c, apierror.Conflict(e.Message))
+				return
+			}
+		}
+		if apiErr.HTTPStatusCode == http.StatusUnprocessableEntity && len(apiErr.Errors) > 0 {
+			writeError(c, apierror.BadRequest(apiErr.Errors[0].Message))
+			return
+		}
+	}
+	writeError(c, apierror.Upstream(err, "clerk request failed"))
+}
+
+// revokeClerkSessions revokes every active Clerk session of the user and
+// returns how many were revoked before any error.
+func revokeClerkSessions(ctx context.Context, clerkID string) (int, error) {
+	status := "active"
+	list, err := session.List(ctx, &session.ListParams{UserID: &clerkID, Status: &status})
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, s := range list.Sessions {
+		if _, err := session.Revoke(ctx, &session.RevokeParams{ID: s.ID}); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend/internal/apierror"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AdminWebhookHandler lets operators inspect and replay stored Clerk webhook
+// events without re-sending signed requests. Stored payloads are opened
+// with pii for display.
+type AdminWebhookHandler struct {
+	q      *db.Queries
+	worker *webhooks.Worker
+	pii    *crypto.Keyring
+}
+
+func NewAdminWebhookHandler(q *db.Queries, worker *webhooks.Worker, pii *crypto.Keyring) *AdminWebhookHandler {
+	return &AdminWebhookHandler{q: q, worker: worker, pii: pii}
+}
+
+var webhookStatuses = map[string]bool{
+	"received": true, "processing": true, "processed": true, "failed": true, "dead": true,
+}
+
+// List returns the most recent events, optionally filtered by ?status=,
+// paginated with ?limit= and ?cursor= (from next_cursor).
+func (h *AdminWebhookHandler) List(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !webhookStatuses[status] {
+		writeError(c, apierror.Invalid("status", "invalid status"))
+		return
+	}
+
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			writeError(c, apierror.Invalid("limit", "limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	params := db.ListWebhookEventsParams{
+		Status:   pgtype.Text{String: status, Valid: status != ""},
+		RowLimit: int32(limit),
+	}
+	if v := c.Query("cursor"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(c, errBadCursor)
+			return
+		}
+		params.BeforeID = pgtype.Int8{Int64: id, Valid: true}
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.q.ListWebhookEvents(ctx, params)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve webhook events"))
+		return
+	}
+	total, err := h.q.CountWebhookEvents(ctx, params.Status)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve webhook events"))
+		return
+	}
+	c.JSON(http.StatusOK, newPage(events, total, limit, c.Query("cursor"), func(last db.ListWebhookEventsRow) string {
+		return strconv.FormatInt(last.ID, 10)
+	}))
+}
+
+// Get returns a single event including its raw payload.
+func (h *AdminWebhookHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.Invalid("id", "invalid id"))
+		return
+	}
+
+	evt, err := h.q.GetWebhookEvent(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("webhook event not found"))
+		return
+	}
+	if err == nil {
+		evt.Payload, err = webhooks.OpenPayload(h.pii, evt.Payload)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve webhook event"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              evt.ID,
+		"svix_id":         evt.SvixID,
+		"event_type":      evt.EventType,
+		"payload":         json.RawMessage(evt.Payload),
+		"status":          evt.Status,
+		"error":           evt.Error,
+		"error_code":      evt.ErrorCode,
+		"attempts":        evt.Attempts,
+		"received_at":     evt.ReceivedAt,
+		"processed_at":    evt.ProcessedAt,
+		"next_attempt_at": evt.NextAttemptAt,
+	})
+}
+
+// Replay puts a failed, dead-lettered or already processed event back on the
+// queue with a fresh attempt budget.
+func (h *AdminWebhookHandler) Replay(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, apierror.Invalid("id", "invalid id"))
+		return
+	}
+
+	n, err := h.q.RequeueWebhookEvent(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to replay webhook event"))
+		return
+	}
+	if n == 0 {
+		if _, err := h.q.GetWebhookEvent(c.Request.Context(), id); errors.Is(err, pgx.ErrNoRows) {
+			writeError(c, apierror.NotFound("webhook event not found"))
+			return
+		}
+		writeError(c, apierror.Conflict("webhook event is still queued or processing"))
+		return
+	}
+
+	h.worker.Notify()
+	c.JSON(http.StatusAccepted, gin.H{"ok": true, "id": id, "status": "received"})
+}
@@ -0,0 +1,274 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// auditEntity describes what a mutating route acts on. param names the
+// route parameter holding the entity's ID; snapshot, when set, loads the
+// entity so its before and after states can be diffed.
+type auditEntity struct {
+	kind     string
+	param    string
+	snapshot func(ctx context.Context, q *db.Queries, id string) (any, error)
+}
+
+func snapshotUser(ctx context.Context, q *db.Queries, id string) (any, error) {
+	return q.GetUserByClerkIDIncludingDeleted(ctx, id)
+}
+
+func snapshotPreferences(ctx context.Context, q *db.Queries, id string) (any, error) {
+	doc, err := q.GetUserPreferences(ctx, id)
+	return json.RawMessage(doc), err
+}
+
+func snapshotSubscription(ctx context.Context, q *db.Queries, id string) (any, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, pgx.ErrNoRows
+	}
+	return q.GetWebhookSubscription(ctx, n)
+}
+
+// auditEntities maps route templates to their entity. Mutating routes not
+// listed are still audited, without an entity.
+var auditEntities = map[string]auditEntity{
+	APIV1 + "/users/:id":                       {"user", "id", snapshotUser},
+	APIV1 + "/users/:id/preferences":           {"user_preferences", "id", snapshotPreferences},
+	APIV1 + "/users/:id/avatar":                {"user", "id", snapshotUser},
+	APIV1 + "/users/:id/personal-data":         {"user", "id", nil},
+	APIV1 + "/admin/users":                     {"user", "", nil},
+	APIV1 + "/admin/users/:id/ban":             {"user", "id", snapshotUser},
+	APIV1 + "/admin/users/:id/unban":           {"user", "id", snapshotUser},
+	APIV1 + "/admin/users/:id/restore":         {"user", "id", snapshotUser},
+	APIV1 + "/admin/users/:id/merge":           {"user", "id", snapshotUser},
+	APIV1 + "/admin/webhooks/:id/replay":       {"webhook_event", "id", nil},
+	APIV1 + "/admin/webhook-subscriptions":     {"webhook_subscription", "", nil},
+	APIV1 + "/admin/webhook-subscriptions/:id": {"webhook_subscription", "id", snapshotSubscription},
+	APIV1 + "/admin/settings":                  {"settings", "", nil},
+	APIV1 + "/admin/settings/reload":           {"settings", "", nil},
+}
+
+// auditRedacted are personal fields whose values never enter the audit log:
+// it is not encrypted, and being append-only it could not honour an erasure
+// request. It only records that they changed. email_bidx is included
+// because it is derived from the address and identifies it, and
+// preferences because they are free-form user data. For the same reason
+// erasure itself is recorded without a diff.
+var auditRedacted = map[string]bool{
+	"name": true, "first_name": true, "last_name": true, "username": true, "email": true, "email_bidx": true,
+	"phone": true, "preferences": true,
+}
+
+// auditMiddleware appends an audit_log row for every POST, PUT, PATCH and
+// DELETE in its group: the caller, route, response status, request ID and,
+// for routes in auditEntities, the entity with the fields the request
+// changed. Requests that fail are recorded too, as attempts without
+// changes. It runs after authentication so the caller is known.
+func auditMiddleware(q *db.Queries) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		entity := auditEntities[route]
+		var id string
+		if entity.param != "" {
+			id = c.Param(entity.param)
+		}
+		ctx := c.Request.Context()
+		var before map[string]any
+		if entity.snapshot != nil && id != "" {
+			before = auditSnapshot(ctx, q, entity, id)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		changes := []byte("{}")
+		if before != nil && status < 400 {
+			after := auditSnapshot(ctx, q, entity, id)
+			if b, err := json.Marshal(auditDiff(before, after)); err == nil {
+				changes = b
+			}
+		}
+		actor := callerID(c)
+		if actor == "" {
+			actor = "anonymous"
+		}
+		// The row is written even if the client has gone away.
+		err := q.InsertAuditEntry(context.WithoutCancel(ctx), db.InsertAuditEntryParams{
+			Actor:      actor,
+			Method:     c.Request.Method,
+			Route:      route,
+			Status:     int32(status),
+			EntityType: pgtype.Text{String: entity.kind, Valid: entity.kind != ""},
+			EntityID:   pgtype.Text{String: id, Valid: id != ""},
+			Changes:    changes,
+			RequestID:  pgtype.Text{String: c.GetString("request_id"), Valid: c.GetString("request_id") != ""},
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "audit: recording request failed", "route", route, "error", err)
+		}
+	}
+}
+
+// auditSnapshot loads the entity as a JSON object. A missing entity is an
+// empty object, so creating or deleting it shows every field changing.
+func auditSnapshot(ctx context.Context, q *db.Queries, entity auditEntity, id string) map[string]any {
+	out := map[string]any{}
+	v, err := entity.snapshot(ctx, q, id)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.WarnContext(ctx, "audit: loading entity failed", "entity", entity.kind, "error", err)
+		}
+		return out
+	}
+	b, err := json.Marshal(v)
+	if err == nil {
+		_ = json.Unmarshal(b, &out)
+	}
+	return out
+}
+
+type auditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// auditDiff returns the top-level fields that differ between before and
+// after, with auditRedacted values replaced.
+func auditDiff(before, after map[string]any) map[string]auditChange {
+	diff := make(map[string]auditChange)
+	for k, b := range before {
+		if a, ok := after[k]; !ok || !reflect.DeepEqual(a, b) {
+			diff[k] = auditChange{Before: b, After: after[k]}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			diff[k] = auditChange{After: a}
+		}
+	}
+	for k, ch := range diff {
+		if auditRedacted[k] {
+			diff[k] = auditChange{Before: redactedValue(ch.Before), After: redactedValue(ch.After)}
+		}
+	}
+	return diff
+}
+
+func redactedValue(v any) any {
+	if v == nil || v == "" {
+		return v
+	}
+	return "[redacted]"
+}
+
+// AuditHandler serves the audit log to admins.
+type AuditHandler struct {
+	q *db.Queries
+}
+
+func NewAuditHandler(q *db.Queries) *AuditHandler {
+	return &AuditHandler{q: q}
+}
+
+type auditEntryResponse struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Method     string          `json:"method"`
+	Route      string          `json:"route"`
+	Status     int32           `json:"status"`
+	EntityType string          `json:"entity_type,omitempty"`
+	EntityID   string          `json:"entity_id,omitempty"`
+	Changes    json.RawMessage `json:"changes"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// List returns audit entries newest first, optionally filtered by actor,
+// entity_type and entity_id, paginated by the cursor of the previous page.
+func (h *AuditHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			writeError(c, apierror.Invalid("limit", "limit must be between 1 and 200"))
+			return
+		}
+		limit = n
+	}
+	optional := func(name string) pgtype.Text {
+		v := c.Query(name)
+		return pgtype.Text{String: v, Valid: v != ""}
+	}
+	params := db.ListAuditEntriesParams{
+		Actor:      optional("actor"),
+		EntityType: optional("entity_type"),
+		EntityID:   optional("entity_id"),
+		RowLimit:   int32(limit),
+	}
+	if v := c.Query("cursor"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(c, errBadCursor)
+			return
+		}
+		params.BeforeID = pgtype.Int8{Int64: id, Valid: true}
+	}
+
+	entries, err := h.q.ListAuditEntries(ctx, params)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve audit log"))
+		return
+	}
+	total, err := h.q.CountAuditEntries(ctx, db.CountAuditEntriesParams{
+		Actor:      params.Actor,
+		EntityType: params.EntityType,
+		EntityID:   params.EntityID,
+	})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve audit log"))
+		return
+	}
+
+	data := make([]auditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, auditEntryResponse{
+			ID:         e.ID,
+			Actor:      e.Actor,
+			Method:     e.Method,
+			Route:      e.Route,
+			Status:     e.Status,
+			EntityType: e.EntityType.String,
+			EntityID:   e.EntityID.String,
+			Changes:    e.Changes,
+			RequestID:  e.RequestID.String,
+			CreatedAt:  e.CreatedAt.Time,
+		})
+	}
+	c.JSON(http.StatusOK, newPage(data, total, limit, c.Query("cursor"), func(last auditEntryResponse) string {
+		return strconv.FormatInt(last.ID, 10)
+	}))
+}
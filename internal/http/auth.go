@@ -0,0 +1,244 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"backend/internal/apierror"
+	"backend/internal/auth/jwks"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/clerk/clerk-sdk-go/v2/jwt"
+	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Scope is a single permission such as "users:read". Roles and API keys are
+// granted a set of scopes; routes declare the scopes they require.
+type Scope string
+
+const (
+	ScopeUsersRead      Scope = "users:read"
+	ScopeUsersWrite     Scope = "users:write"
+	ScopeWebhooksReplay Scope = "webhooks:replay"
+	ScopeWebhooksManage Scope = "webhooks:manage"
+	ScopeAuditRead      Scope = "audit:read"
+	ScopeSettingsManage Scope = "settings:manage"
+	ScopeOpsRead        Scope = "ops:read"
+)
+
+// roleScopes maps a database role to the scopes it grants.
+var roleScopes = map[string][]Scope{
+	"superadmin": {ScopeUsersRead, ScopeUsersWrite, ScopeWebhooksReplay, ScopeWebhooksManage, ScopeAuditRead, ScopeSettingsManage, ScopeOpsRead},
+	"admin":      {ScopeUsersRead, ScopeUsersWrite, ScopeWebhooksReplay, ScopeWebhooksManage, ScopeAuditRead, ScopeOpsRead},
+	"user":       {},
+}
+
+func scopesFromStrings(ss []string) []Scope {
+	out := make([]Scope, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, Scope(s))
+	}
+	return out
+}
+
+func hasScope(granted []Scope, want Scope) bool {
+	for _, g := range granted {
+		if g == want {
+			return true
+		}
+	}
+	return false
+}
+
+// authMiddleware authenticates the caller either with an X-API-Key header or
+// a Clerk session JWT, and stores the granted scopes in the context. It does
+// not authorize; pair it with RequireScope.
+func authMiddleware(q *db.Queries, keys *jwks.Cache) gin.HandlerFunc {
+	return authenticate(q, keys, nil)
+}
+
+// provisioningAuthMiddleware is authMiddleware for routes a brand-new user
+// may call before Clerk's user.created webhook has arrived: a valid token
+// for a user without a local row creates that row from the Clerk API.
+func provisioningAuthMiddleware(q *db.Queries, keys *jwks.Cache, pii *crypto.Keyring) gin.HandlerFunc {
+	return authenticate(q, keys, func(ctx context.Context, clerkID string) (string, error) {
+		return provisionUser(ctx, q, pii, clerkID)
+	})
+}
+
+// authenticate builds the auth middleware. provision, when non-nil, is
+// called for a verified token whose user has no local row.
+func authenticate(q *db.Queries, keys *jwks.Cache, provision func(ctx context.Context, clerkID string) (string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			key, err := q.GetActiveAPIKeyByHash(c.Request.Context(), hex.EncodeToString(sum[:]))
+			if err != nil {
+				writeError(c, apierror.Unauthorized("invalid api key"))
+				return
+			}
+			_ = q.TouchAPIKey(c.Request.Context(), key.ID)
+
+			c.Set("api_key_id", key.ID)
+			c.Set("scopes", scopesFromStrings(key.Scopes))
+			c.Next()
+			return
+		}
+
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			writeError(c, apierror.Unauthorized("missing authorization header"))
+			return
+		}
+
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+
+		// Verify JWT against the cached Clerk JWKS.
+		unverified, err := jwt.Decode(c.Request.Context(), &jwt.DecodeParams{Token: token})
+		if err != nil {
+			writeError(c, apierror.Unauthorized("invalid or expired token"))
+			return
+		}
+		jwk, err := keys.Key(c.Request.Context(), unverified.KeyID)
+		if err != nil {
+			writeError(c, apierror.Unauthorized("invalid or expired token"))
+			return
+		}
+		claims, err := jwt.Verify(c.Request.Context(), &jwt.VerifyParams{Token: token, JWK: jwk})
+		if err != nil {
+			writeError(c, apierror.Unauthorized("invalid or expired token"))
+			return
+		}
+
+		clerkID := claims.Subject
+
+		if clerkID == "" {
+			writeError(c, apierror.Unauthorized("invalid token subject"))
+			return
+		}
+
+		// Look up the caller's role in the database.
+		role, err := q.GetUserRole(c.Request.Context(), clerkID)
+		if errors.Is(err, pgx.ErrNoRows) && provision != nil {
+			role, err = provision(c.Request.Context(), clerkID)
+		}
+		if err != nil {
+			writeError(c, apierror.Forbidden("user not found or inactive"))
+			return
+		}
+
+		// Store caller identity in context for downstream handlers.
+		c.Set("clerk_id", clerkID)
+		c.Set("role", role)
+		c.Set("scopes", roleScopes[role])
+		c.Next()
+	}
+}
+
+// provisionUser creates the local row of a user that exists in Clerk but has
+// not been synced yet. Users that do have a row (deleted, banned or inactive)
+// are left alone and stay rejected.
+func provisionUser(ctx context.Context, q *db.Queries, pii *crypto.Keyring, clerkID string) (string, error) {
+	exists, err := q.UserExists(ctx, clerkID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", pgx.ErrNoRows
+	}
+
+	cu, err := clerkUser.Get(ctx, clerkID)
+	if err != nil {
+		return "", err
+	}
+	u, err := webhooks.ClerkUserFromAPI(cu)
+	if err != nil {
+		return "", err
+	}
+
+	params := u.UpsertParams()
+	if params.Email, params.EmailBidx, err = webhooks.SealEmail(pii, params.Email); err != nil {
+		return "", err
+	}
+	if _, err := q.UpsertUserWithRole(ctx, params); err != nil {
+		return "", err
+	}
+	return q.GetUserRole(ctx, clerkID)
+}
+
+// RequireScope rejects callers that were not granted every listed scope.
+// It must run after authMiddleware.
+func RequireScope(scopes ...Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get("scopes")
+		granted, _ := v.([]Scope)
+		for _, s := range scopes {
+			if !hasScope(granted, s) {
+				writeError(c, missingScope(s))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// canEditUser reports whether the caller may modify clerkID's data: users may
+// edit themselves, anyone else needs the users:write scope.
+func canEditUser(c *gin.Context, clerkID string) bool {
+	if clerkID != "" && c.GetString("clerk_id") == clerkID {
+		return true
+	}
+	v, _ := c.Get("scopes")
+	granted, _ := v.([]Scope)
+	return hasScope(granted, ScopeUsersWrite)
+}
+
+// RequireRole rejects callers whose database role is not one of roles.
+// API-key callers have no role and are always rejected.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		writeError(c, apierror.Forbidden("insufficient permissions"))
+	}
+}
+
+// RouteRegistry registers handlers together with the scopes they require, so
+// every route's permissions are declared in one place. Routes registered with
+// no scopes only get the middleware of the group they belong to.
+type RouteRegistry struct {
+	routes []RouteInfo
+}
+
+type RouteInfo struct {
+	Method string  `json:"method"`
+	Path   string  `json:"path"`
+	Scopes []Scope `json:"scopes"`
+}
+
+func (rr *RouteRegistry) Handle(g *gin.RouterGroup, method, path string, scopes []Scope, handlers ...gin.HandlerFunc) {
+	// The root group's base path is "/", which would double the slash.
+	full := strings.TrimSuffix(g.BasePath(), "/") + path
+	rr.routes = append(rr.routes, RouteInfo{Method: method, Path: full, Scopes: scopes})
+	if len(scopes) > 0 {
+		handlers = append([]gin.HandlerFunc{RequireScope(scopes...)}, handlers...)
+	}
+	g.Handle(method, path, handlers...)
+}
+
+// Routes returns every route registered so far.
+func (rr *RouteRegistry) Routes() []RouteInfo {
+	return rr.routes
+}
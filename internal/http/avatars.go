@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+	"backend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// avatarURLExpiry is how long a presigned avatar URL stays valid.
+const avatarURLExpiry = 15 * time.Minute
+
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// AvatarHandler uploads user avatars to object storage and hands out
+// presigned download URLs.
+type AvatarHandler struct {
+	q     *db.Queries
+	store *storage.S3
+}
+
+func NewAvatarHandler(q *db.Queries, store *storage.S3) *AvatarHandler {
+	return &AvatarHandler{q: q, store: store}
+}
+
+// Upload accepts a multipart form with the image in the "file" field. The
+// content type is sniffed from the data rather than trusted from the client.
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	clerkID := c.Param("id")
+	if !canEditUser(c, clerkID) {
+		writeError(c, missingScope(ScopeUsersWrite))
+		return
+	}
+
+	fh, err := c.FormFile("file")
+	if isBodyTooLarge(err) {
+		writeError(c, apierror.TooLarge("avatar is too large"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Invalid("file", "file is required"))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		writeError(c, apierror.Invalid("file", "file is required"))
+		return
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := avatarExtensions[contentType]
+	if !ok {
+		writeError(c, apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMedia, "avatar must be a PNG, JPEG, GIF or WebP image"))
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		writeError(c, apierror.Internal(err, "failed to read upload"))
+		return
+	}
+
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	key := "avatars/" + clerkID + "/" + hex.EncodeToString(suffix) + ext
+
+	ctx := c.Request.Context()
+	if err := h.store.Put(ctx, key, contentType, f, fh.Size); err != nil {
+		writeError(c, apierror.Upstream(err, "failed to store avatar"))
+		return
+	}
+
+	rows, err := h.q.SetUserAvatar(ctx, db.SetUserAvatarParams{ClerkID: clerkID, AvatarKey: pgtype.Text{String: key, Valid: true}})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to update user"))
+		return
+	}
+	if rows == 0 {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.avatar_updated", gin.H{"key": key})
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        h.store.PresignGet(key, avatarURLExpiry),
+		"expires_at": time.Now().Add(avatarURLExpiry).UTC(),
+	})
+}
+
+// Get returns a short-lived download URL for the user's avatar.
+func (h *AvatarHandler) Get(c *gin.Context) {
+	key, err := h.q.GetUserAvatarKey(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve avatar"))
+		return
+	}
+	if !key.Valid {
+		writeError(c, apierror.NotFound("user has no avatar"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        h.store.PresignGet(key.String, avatarURLExpiry),
+		"expires_at": time.Now().Add(avatarURLExpiry).UTC(),
+	})
+}
@@ -0,0 +1,34 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitMiddleware caps request bodies at defaultLimit bytes, or at the
+// per-route limit in overrides (keyed by route template, e.g.
+// "/webhooks/clerk"). Handlers see a read error once the cap is exceeded and
+// should answer 413 via isBodyTooLarge.
+func bodyLimitMiddleware(defaultLimit int64, overrides map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultLimit
+		if n, ok := overrides[c.FullPath()]; ok {
+			limit = n
+		}
+		if c.Request.ContentLength > limit {
+			writeError(c, apierror.TooLarge("request body too large"))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+func isBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
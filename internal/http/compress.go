@@ -0,0 +1,213 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compression gzips responses for clients that accept it. Bodies are
+// buffered up to MinBytes before deciding, so small responses go out as
+// they are; streamed responses are compressed as they are flushed.
+type Compression struct {
+	// MinBytes is the smallest body worth compressing; zero disables
+	// compression.
+	MinBytes int
+	// Level is the gzip level, gzip.DefaultCompression by default.
+	Level int
+	// Skip lists route patterns (gin's FullPath) never compressed.
+	Skip map[string]bool
+}
+
+// DefaultCompression is used unless the COMPRESSION settings override it.
+// /metrics is skipped because the Prometheus handler compresses its own
+// output.
+func DefaultCompression() Compression {
+	return Compression{
+		MinBytes: 1024,
+		Level:    gzip.DefaultCompression,
+		Skip:     map[string]bool{"/metrics": true},
+	}
+}
+
+// compressibleTypes are the media types worth compressing; images, archives
+// and profiles are compressed already.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"image/svg+xml",
+	"text/",
+}
+
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range compressibleTypes {
+		if mt == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip, either
+// by name or through "*", with a non-zero quality.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+// compressMiddleware gzips eligible responses per cfg. Profiling under
+// /debug is left alone.
+func compressMiddleware(cfg Compression) gin.HandlerFunc {
+	if cfg.MinBytes <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	pool := sync.Pool{New: func() any {
+		gz, err := gzip.NewWriterLevel(nil, cfg.Level)
+		if err != nil {
+			gz = gzip.NewWriter(nil)
+		}
+		return gz
+	}}
+	return func(c *gin.Context) {
+		if cfg.Skip[c.FullPath()] || strings.HasPrefix(c.FullPath(), "/debug/") || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Header("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, minBytes: cfg.MinBytes, pool: &pool}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// compressWriter holds the body back until it reaches minBytes, is
+// flushed or ends, and then sends it either through gzip or as is.
+type compressWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	pool     *sync.Pool
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide picks the encoding once the body is known to be large, or once it
+// has to go out, and sends what was buffered.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	status := w.Status()
+	if len(w.buf) >= w.minBytes && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) &&
+		status != http.StatusNoContent && status != http.StatusNotModified && status != http.StatusPartialContent {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+func (w *compressWriter) write(b []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		return w.write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minBytes {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers, so the encoding has to be chosen first.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Written counts buffered bytes, which the handler has written.
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends everything written so far, compressed if the body was large
+// enough by then.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close sends whatever is still buffered and ends the gzip stream.
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
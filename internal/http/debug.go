@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+}
+
+// debugHandler serves the runtime debug endpoints, mounted at /debug:
+//
+//   - /debug/pprof/ and its profiles (heap, goroutine, allocs, profile for
+//     CPU, trace, ...), for `go tool pprof`
+//   - /debug/vars: expvar, i.e. memstats, the goroutine count and the
+//     command line
+func debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// NewDebugServer serves the debug endpoints on addr without authentication,
+// for profiling from the host or a port-forward. addr must be a loopback
+// address, e.g. 127.0.0.1:6060, so the listener is never exposed.
+func NewDebugServer(addr string) (*http.Server, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, &net.AddrError{Err: "debug listener must bind a loopback address", Addr: addr}
+	}
+	return &http.Server{Addr: addr, Handler: debugHandler()}, nil
+}
+
+// registerDebugRoutes mounts the debug endpoints on the API behind admin
+// authentication. auth checks the caller's role rather than a scope, so API
+// keys never reach them.
+func registerDebugRoutes(r *gin.Engine, rr *RouteRegistry, auth ...gin.HandlerFunc) {
+	h := gin.WrapH(debugHandler())
+	debug := r.Group("/debug", auth...)
+	rr.Handle(debug, http.MethodGet, "/pprof/*profile", nil, h)
+	rr.Handle(debug, http.MethodPost, "/pprof/symbol", nil, h)
+	rr.Handle(debug, http.MethodGet, "/vars", nil, h)
+}
@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dependencyCacheTTL is how long a dependency report is reused, so a
+// dashboard or several load balancers polling it do not hammer the
+// services it checks.
+const dependencyCacheTTL = 5 * time.Second
+
+// Dependency is one service the instance relies on.
+type Dependency struct {
+	Name    string
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// Error is "timeout" or "unreachable"; details stay in the logs.
+	Error string `json:"error,omitempty"`
+}
+
+type dependencyReport struct {
+	Status       string                      `json:"status"`
+	CheckedAt    time.Time                   `json:"checked_at"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// DependencyChecker reports the status and latency of each dependency,
+// checked concurrently with its own timeout and cached for
+// dependencyCacheTTL.
+type DependencyChecker struct {
+	deps []Dependency
+
+	mu     sync.Mutex
+	cached dependencyReport
+}
+
+func NewDependencyChecker(deps ...Dependency) *DependencyChecker {
+	return &DependencyChecker{deps: deps}
+}
+
+// Report answers 200 with status "ok" when every dependency is up and
+// "degraded" otherwise; like /health it is for dashboards, and readiness
+// is decided by /health/ready.
+func (d *DependencyChecker) Report(c *gin.Context) {
+	c.JSON(http.StatusOK, d.report(c.Request.Context()))
+}
+
+func (d *DependencyChecker) report(ctx context.Context) dependencyReport {
+	// Holding the lock while checking makes concurrent callers wait for
+	// one round of checks and share it.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if time.Since(d.cached.CheckedAt) < dependencyCacheTTL {
+		return d.cached
+	}
+
+	// The checks outlive a caller that disconnects, since others may be
+	// waiting for the result.
+	ctx = context.WithoutCancel(ctx)
+	results := make([]dependencyStatus, len(d.deps))
+	var wg sync.WaitGroup
+	for i, dep := range d.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	r := dependencyReport{Status: "ok", CheckedAt: time.Now(), Dependencies: make(map[string]dependencyStatus, len(d.deps))}
+	for i, dep := range d.deps {
+		r.Dependencies[dep.Name] = results[i]
+		if results[i].Status != "up" {
+			r.Status = "degraded"
+		}
+	}
+	d.cached = r
+	return r
+}
+
+func check(ctx context.Context, dep Dependency) dependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, dep.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.Check(ctx)
+	s := dependencyStatus{Status: "up", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	switch {
+	case err == nil:
+	case errors.Is(err, context.DeadlineExceeded):
+		s.Status, s.Error = "down", "timeout"
+	default:
+		s.Status, s.Error = "down", "unreachable"
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "health: dependency check failed", "dependency", dep.Name, "error", err)
+	}
+	return s
+}
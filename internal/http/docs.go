@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"embed"
+	"net/http"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate sh swaggerui/fetch.sh
+
+// swaggerUI holds the vendored swagger-ui-dist files, at the version in
+// swaggerui/VERSION. Serving them ourselves keeps the docs page from running
+// whatever a CDN happens to return. The files are embedded by name, so a
+// tree without them does not build; run go generate ./internal/http.
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// swaggerAssets are the files /docs/assets serves, with their content types.
+var swaggerAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+}
+
+// docsPage is Swagger UI for /openapi.json.
+const docsPage = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API docs</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+func docsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+func docsAssetHandler(c *gin.Context) {
+	name := c.Param("file")
+	contentType, ok := swaggerAssets[name]
+	if !ok {
+		writeError(c, apierror.NotFound("asset not found"))
+		return
+	}
+	body, err := swaggerUI.ReadFile("swaggerui/" + name)
+	if err != nil {
+		writeError(c, apierror.NotFound("asset not found"))
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, contentType, body)
+}
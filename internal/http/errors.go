@@ -0,0 +1,24 @@
+package httpapi
+
+import (
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeError answers the request with err, mapped by apierror.From and
+// shaped by the API version the request was routed to. The cause is added
+// to c.Errors, where the access log and the error reporter pick it up; the
+// client only sees the code and message.
+func writeError(c *gin.Context, err error) {
+	e := apierror.From(err)
+	if e.Err != nil {
+		_ = c.Error(e.Err)
+	}
+	c.AbortWithStatusJSON(e.Status, versionOf(c).errorBody(e, c.GetString("request_id")))
+}
+
+// missingScope is the 403 for a caller lacking scope s.
+func missingScope(s Scope) *apierror.Error {
+	return apierror.Forbidden("insufficient permissions").With("missing_scope", s)
+}
@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// QueueListener is the webhook queue's notification listener, e.g. a
+// *pglisten.Listener.
+type QueueListener interface {
+	Connected() bool
+}
+
+type HealthHandler struct {
+	pool *pgxpool.Pool
+	// schemaVersion is the migration version this build expects.
+	schemaVersion uint
+	// versionQuery reads the migrations version table.
+	versionQuery string
+	// queue, when set, must be connected for the instance to be ready.
+	queue QueueListener
+
+	draining atomic.Bool
+}
+
+func NewHealthHandler(pool *pgxpool.Pool, schemaVersion uint, migrationsTable string, queue QueueListener) *HealthHandler {
+	if migrationsTable == "" {
+		migrationsTable = "schema_migrations"
+	}
+	return &HealthHandler{
+		pool:          pool,
+		schemaVersion: schemaVersion,
+		versionQuery:  "SELECT version, dirty FROM " + pgx.Identifier{migrationsTable}.Sanitize() + " LIMIT 1",
+		queue:         queue,
+	}
+}
+
+// appliedSchema reads the migration version applied to the database and
+// whether a failed migration left it dirty.
+func (h *HealthHandler) appliedSchema(ctx context.Context) (version int64, dirty bool, err error) {
+	err = h.pool.QueryRow(ctx, h.versionQuery).Scan(&version, &dirty)
+	return version, dirty, err
+}
+
+// Drain makes Ready fail from now on, so load balancers stop sending
+// traffic while in-flight requests finish during shutdown.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and serving HTTP. It checks no
+// dependency: a database outage must not get every instance restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Health reports whether the database answers, plus the connection pool's
+// usage. A pool with every connection checked out is reported as degraded
+// before requests start failing on acquire timeouts. It always answers 200
+// and is meant for dashboards; probes use /health/live and /health/ready.
+func (h *HealthHandler) Health(c *gin.Context) {
+	s := h.pool.Stat()
+	pool := gin.H{
+		"acquired":         s.AcquiredConns(),
+		"idle":             s.IdleConns(),
+		"total":            s.TotalConns(),
+		"max":              s.MaxConns(),
+		"empty_acquires":   s.EmptyAcquireCount(),
+		"wait_duration_ms": s.EmptyAcquireWaitTime().Milliseconds(),
+	}
+
+	var v int
+	if err := h.pool.QueryRow(c.Request.Context(), "SELECT 1").Scan(&v); err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": "degraded", "db": "down", "pool": pool})
+		return
+	}
+	if samplePool(s).exhausted {
+		c.JSON(http.StatusOK, gin.H{"status": "degraded", "db": "exhausted", "pool": pool})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "db": "up", "pool": pool})
+}
+
+// Ready reports whether this instance should receive traffic: it is not
+// shutting down, the database answers, its schema is at least the version
+// this build expects and not left dirty by a failed migration, and the
+// webhook queue's listener is connected. A newer schema is fine, since
+// migrations must stay compatible with the previous release during a
+// rollout. Not ready is a 503 so load balancers stop routing here.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": "shutting down"})
+		return
+	}
+
+	version, dirty, err := h.appliedSchema(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "db": "down"})
+		return
+	}
+
+	schema := gin.H{"version": version, "expected": h.schemaVersion, "dirty": dirty}
+	switch {
+	case dirty:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "db": "up", "schema": schema, "reason": "schema is dirty"})
+	case version < int64(h.schemaVersion):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "db": "up", "schema": schema, "reason": "schema is behind"})
+	case h.queue != nil && !h.queue.Connected():
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "db": "up", "schema": schema, "queue": "disconnected", "reason": "queue listener is not connected"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "db": "up", "schema": schema, "queue": "connected"})
+	}
+}
@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/pkg/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// internalSignatureMiddleware verifies the X-Internal-Signature HMAC that
+// sibling services attach via pkg/signing.
+func internalSignatureMiddleware(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if isBodyTooLarge(err) {
+			writeError(c, apierror.TooLarge("request body too large"))
+			return
+		}
+		if err != nil {
+			writeError(c, apierror.BadRequest("failed to read body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !signing.Verify(
+			secret,
+			c.Request.Method,
+			c.Request.URL.Path,
+			c.GetHeader(signing.HeaderTimestamp),
+			body,
+			c.GetHeader(signing.HeaderSignature),
+			5*time.Minute,
+			time.Now(),
+		) {
+			writeError(c, apierror.Unauthorized("invalid internal signature"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// shedRetryAfterSeconds is what shed requests are told to wait; overload
+// usually clears within seconds, and clients add their own backoff.
+const shedRetryAfterSeconds = 1
+
+// shedAcquireWait is the mean time requests may wait for a database
+// connection, over the last shedSampleInterval, before new ones are shed.
+// A pool with every connection checked out is normal under load as long
+// as connections come back quickly; long waits mean requests are queueing
+// towards their timeouts.
+const (
+	shedAcquireWait    = 100 * time.Millisecond
+	shedSampleInterval = time.Second
+)
+
+// loadShedMiddleware answers 503 instead of queueing when the server is
+// saturated: when more than maxInFlight requests are already being served
+// (0 disables the check), or when requests have been waiting too long for
+// a connection from pool. Health checks and metrics scrapes are never shed
+// so probes and dashboards keep reporting on the instance.
+func loadShedMiddleware(maxInFlight int64, pool *pgxpool.Pool) gin.HandlerFunc {
+	var inFlight atomic.Int64
+	var pressure *poolPressure
+	if pool != nil {
+		pressure = newPoolPressure(pool.Stat)
+	}
+
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.FullPath(), "/health") || c.FullPath() == "/metrics" {
+			c.Next()
+			return
+		}
+
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		if maxInFlight > 0 && n > maxInFlight {
+			shed(c, "server is overloaded")
+			return
+		}
+		if pressure != nil && pressure.saturated(time.Now()) {
+			shed(c, "database is saturated")
+			return
+		}
+		c.Next()
+	}
+}
+
+// poolSample is the part of pgxpool.Stat load shedding looks at.
+type poolSample struct {
+	acquires      int64
+	emptyAcquires int64
+	waited        time.Duration
+	exhausted     bool
+}
+
+func samplePool(s *pgxpool.Stat) poolSample {
+	return poolSample{
+		acquires:      s.AcquireCount(),
+		emptyAcquires: s.EmptyAcquireCount(),
+		waited:        s.EmptyAcquireWaitTime(),
+		exhausted:     s.MaxConns() > 0 && s.AcquiredConns() >= s.MaxConns(),
+	}
+}
+
+// saturatedBetween reports whether the pool was saturated between two
+// samples: acquires that had to wait for a connection waited shedAcquireWait
+// or more on average, or the pool was exhausted throughout without a single
+// acquire completing.
+func saturatedBetween(prev, cur poolSample) bool {
+	if n := cur.emptyAcquires - prev.emptyAcquires; n > 0 {
+		return (cur.waited-prev.waited)/time.Duration(n) >= shedAcquireWait
+	}
+	return prev.exhausted && cur.exhausted && cur.acquires == prev.acquires
+}
+
+// poolPressure re-evaluates saturatedBetween once per shedSampleInterval,
+// so the verdict reflects recent waits rather than the pool's lifetime.
+type poolPressure struct {
+	stat func() *pgxpool.Stat
+
+	mu        sync.Mutex
+	sampledAt time.Time
+	last      poolSample
+	verdict   bool
+}
+
+func newPoolPressure(stat func() *pgxpool.Stat) *poolPressure {
+	return &poolPressure{stat: stat, sampledAt: time.Now(), last: samplePool(stat())}
+}
+
+func (p *poolPressure) saturated(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.Sub(p.sampledAt) >= shedSampleInterval {
+		cur := samplePool(p.stat())
+		p.verdict = saturatedBetween(p.last, cur)
+		p.last, p.sampledAt = cur, now
+	}
+	return p.verdict
+}
+
+func shed(c *gin.Context, msg string) {
+	c.Header("Retry-After", strconv.Itoa(shedRetryAfterSeconds))
+	writeError(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, msg))
+}
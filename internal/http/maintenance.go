@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maintenance turns API requests away with 503 while the service is being
+// worked on, e.g. during a long migration.
+type Maintenance struct {
+	Enabled bool
+	// Message is returned to clients; defaultMaintenanceMessage when empty.
+	Message string
+	// RetryAfter is sent as the Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+}
+
+const defaultMaintenanceMessage = "the service is down for maintenance"
+
+// maintenanceExempt are the routes served during maintenance besides health
+// checks: metrics, the Clerk webhook, so that no sync events are lost, and
+// the settings routes that turn maintenance off again.
+var maintenanceExempt = map[string]bool{
+	"/metrics":                       true,
+	"/webhooks/clerk":                true,
+	APIV1 + "/admin/settings":        true,
+	APIV1 + "/admin/settings/reload": true,
+}
+
+// maintenanceMiddleware answers 503 while maintenance is enabled in
+// settings. Readiness keeps passing, so the load balancer still routes
+// webhooks to the instance.
+func maintenanceMiddleware(settings *Settings) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m := settings.maintenance.Load()
+		if !m.Enabled || strings.HasPrefix(c.FullPath(), "/health") || maintenanceExempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+		msg := m.Message
+		if msg == "" {
+			msg = defaultMaintenanceMessage
+		}
+		c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(m.RetryAfter))))
+		writeError(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeMaintenance, msg))
+	}
+}
@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTPMetrics counts requests and their latency per route and status. The
+// route is the matched pattern, e.g. /users/:id, and requests that match no
+// route share "unmatched", so scanners cannot blow up label cardinality. A
+// nil *HTTPMetrics is a no-op.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time spent serving one HTTP request, by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// metricsMiddleware records every request, including those rejected by the
+// load shedder and rate limiter, so it must run before them.
+func metricsMiddleware(m *HTTPMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m == nil {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.duration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// privateNetworks are the loopback and private address ranges /metrics is
+// reachable from, and proxies are trusted from, by default.
+var privateNetworks = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// MetricsConfig guards GET /metrics. A scrape is allowed when it comes
+// directly from one of AllowedPrefixes, or carries the basic-auth
+// credentials when Username is set.
+type MetricsConfig struct {
+	// Gatherer is what /metrics serves; nil leaves the route out.
+	Gatherer prometheus.Gatherer
+
+	AllowedPrefixes []netip.Prefix
+	Username        string
+	Password        string
+}
+
+// DefaultTrustedProxies are the ranges proxies are trusted from unless
+// TRUSTED_PROXIES says otherwise: load balancers sit on private networks,
+// and clients on the internet cannot send from them.
+func DefaultTrustedProxies() []netip.Prefix {
+	return privateNetworks
+}
+
+// DefaultMetricsConfig serves prometheus.DefaultGatherer to the loopback
+// and private ranges, without basic auth.
+func DefaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{Gatherer: prometheus.DefaultGatherer, AllowedPrefixes: privateNetworks}
+}
+
+// metricsHandler serves cfg.Gatherer to callers the guard admits.
+//
+// The network check uses the TCP peer address, not ClientIP, and refuses
+// requests carrying X-Forwarded-For: a request relayed by the public load
+// balancer arrives from a private address too, and forwarding headers are
+// set by the client. Scrapers reach the instance directly.
+func metricsHandler(cfg MetricsConfig) gin.HandlerFunc {
+	h := promhttp.HandlerFor(cfg.Gatherer, promhttp.HandlerOpts{})
+
+	return func(c *gin.Context) {
+		if cfg.Username != "" {
+			user, pass, ok := c.Request.BasicAuth()
+			if ok && subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Password)) == 1 {
+				h.ServeHTTP(c.Writer, c.Request)
+				return
+			}
+		}
+		if cfg.fromInternalNetwork(c) {
+			h.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+		if cfg.Username != "" {
+			c.Header("WWW-Authenticate", `Basic realm="metrics"`)
+			writeError(c, apierror.Unauthorized("unauthorized"))
+			return
+		}
+		writeError(c, apierror.Forbidden("forbidden"))
+	}
+}
+
+func (cfg MetricsConfig) fromInternalNetwork(c *gin.Context) bool {
+	if c.GetHeader("X-Forwarded-For") != "" || c.GetHeader("Forwarded") != "" {
+		return false
+	}
+	addr, err := netip.ParseAddr(c.RemoteIP())
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range cfg.AllowedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
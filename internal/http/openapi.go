@@ -0,0 +1,406 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// routeDoc describes a route for the OpenAPI spec. request and response
+// are zero values of the body types, e.g. updateUserRequest{}; a nil one
+// leaves the body undescribed.
+type routeDoc struct {
+	summary string
+	query   []string
+	request any
+	// upload names the multipart file field of an upload.
+	upload string
+	// status is the success status, 200 when zero.
+	status   int
+	response any
+}
+
+type okResponse struct {
+	OK      bool   `json:"ok"`
+	ClerkID string `json:"clerk_id"`
+}
+
+type presignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type subscriptionResponse struct {
+	Subscription db.GetWebhookSubscriptionRow `json:"subscription"`
+	// Secret is only returned when it is issued or rotated.
+	Secret string `json:"secret,omitempty"`
+}
+
+var listQuery = []string{"limit", "cursor"}
+
+// routeDocs documents the routes in the registry, keyed by method and
+// route template. Routes not listed appear in the spec without bodies.
+var routeDocs = map[string]routeDoc{
+	"GET /health":              {summary: "Liveness and database status"},
+	"GET /health/live":         {summary: "Liveness probe"},
+	"GET /health/ready":        {summary: "Readiness probe; 503 while not ready"},
+	"GET /health/dependencies": {summary: "Status and latency of each dependency", response: dependencyReport{}},
+	"GET /version":             {summary: "Build, expected and applied schema version", response: versionResponse{}},
+	"POST /webhooks/clerk":     {summary: "Clerk webhook, verified by its Svix signature"},
+	"GET /metrics":             {summary: "Prometheus metrics"},
+	"GET /openapi.json":        {summary: "This document"},
+	"GET /docs":                {summary: "Swagger UI for this document"},
+	"GET /docs/assets/:file":   {summary: "Vendored Swagger UI files for /docs"},
+
+	"GET " + APIV1 + "/me": {summary: "The caller's own user", response: db.GetUserByClerkIDRow{}},
+	"GET " + APIV1 + "/users": {
+		summary:  "List users, newest first",
+		query:    []string{"limit", "cursor", "offset", "q", "has_email", "created_after", "created_before", "org_id", "include_deleted"},
+		response: Page[db.ListUsersPageRow]{},
+	},
+	"GET " + APIV1 + "/users/:id":                {summary: "Get a user", query: []string{"include_deleted"}, response: db.GetUserByClerkIDRow{}},
+	"GET " + APIV1 + "/users/by-clerk/:clerk_id": {summary: "Get a user by Clerk ID", query: []string{"include_deleted"}, response: db.GetUserByClerkIDRow{}},
+	"PATCH " + APIV1 + "/users/:id":              {summary: "Update a user's profile", request: updateUserRequest{}, response: db.UpdateUserProfileRow{}},
+	"GET " + APIV1 + "/users/:id/preferences":    {summary: "Get a user's preferences", response: Preferences{}},
+	"PUT " + APIV1 + "/users/:id/preferences":    {summary: "Replace a user's preferences", request: Preferences{}, response: Preferences{}},
+	"GET " + APIV1 + "/users/:id/activity":       {summary: "A user's activity, newest first", query: listQuery, response: Page[userEventResponse]{}},
+	"GET " + APIV1 + "/users/:id/data-export":    {summary: "Everything stored about a user", response: dataExport{}},
+	"DELETE " + APIV1 + "/users/:id/personal-data": {
+		summary: "Queue erasure of a user's personal data", status: http.StatusAccepted,
+	},
+	"POST " + APIV1 + "/users/:id/avatar": {summary: "Upload a user's avatar", upload: "file", response: presignedURL{}},
+	"GET " + APIV1 + "/users/:id/avatar":  {summary: "A short-lived URL of a user's avatar", response: presignedURL{}},
+
+	"GET " + APIV1 + "/admin/routes":               {summary: "Every route with its required scopes", response: []RouteInfo{}},
+	"POST " + APIV1 + "/admin/users":               {summary: "Create or invite a user", request: createUserRequest{}, status: http.StatusCreated, response: db.GetUserByClerkIDRow{}},
+	"GET " + APIV1 + "/admin/users/export":         {summary: "Export users as CSV or JSON", query: []string{"format", "q", "has_email", "created_after", "created_before", "org_id"}},
+	"POST " + APIV1 + "/admin/users/:id/ban":       {summary: "Ban a user and revoke their sessions", response: okResponse{}},
+	"POST " + APIV1 + "/admin/users/:id/unban":     {summary: "Lift a ban", response: okResponse{}},
+	"POST " + APIV1 + "/admin/users/:id/restore":   {summary: "Restore a soft-deleted user", response: okResponse{}},
+	"POST " + APIV1 + "/admin/users/:id/merge":     {summary: "Merge a duplicate account into a user", request: mergeUsersRequest{}, response: mergeResult{}},
+	"GET " + APIV1 + "/admin/webhooks":             {summary: "List received Clerk webhooks", query: []string{"status", "limit", "cursor"}, response: Page[db.ListWebhookEventsRow]{}},
+	"GET " + APIV1 + "/admin/webhooks/:id":         {summary: "Get a received Clerk webhook with its payload"},
+	"POST " + APIV1 + "/admin/webhooks/:id/replay": {summary: "Queue a webhook for processing again", status: http.StatusAccepted},
+	"GET " + APIV1 + "/admin/webhook-subscriptions": {
+		summary: "List outbound webhook subscriptions", response: Page[db.ListWebhookSubscriptionsRow]{},
+	},
+	"POST " + APIV1 + "/admin/webhook-subscriptions": {
+		summary: "Create an outbound webhook subscription", request: subscriptionRequest{}, status: http.StatusCreated, response: subscriptionResponse{},
+	},
+	"GET " + APIV1 + "/admin/webhook-subscriptions/:id": {
+		summary: "Get an outbound webhook subscription", response: db.GetWebhookSubscriptionRow{},
+	},
+	"PATCH " + APIV1 + "/admin/webhook-subscriptions/:id": {
+		summary: "Update an outbound webhook subscription", request: subscriptionRequest{}, response: subscriptionResponse{},
+	},
+	"DELETE " + APIV1 + "/admin/webhook-subscriptions/:id": {
+		summary: "Delete an outbound webhook subscription", status: http.StatusNoContent,
+	},
+	"GET " + APIV1 + "/admin/audit-log": {
+		summary: "The audit log, newest first", query: []string{"limit", "cursor", "actor", "entity_type", "entity_id"}, response: Page[auditEntryResponse]{},
+	},
+	"GET " + APIV1 + "/admin/slow-requests":    {summary: "The most recent slow requests"},
+	"GET " + APIV1 + "/admin/settings":         {summary: "Runtime settings in effect", response: settingsResponse{}},
+	"PATCH " + APIV1 + "/admin/settings":       {summary: "Change runtime settings until the next reload", request: patchSettingsRequest{}, response: settingsResponse{}},
+	"POST " + APIV1 + "/admin/settings/reload": {summary: "Re-read runtime settings from the configuration", response: settingsResponse{}},
+
+	"GET /internal/users": {summary: "List users for sibling services", query: listQuery, response: Page[db.ListUsersPageRow]{}},
+}
+
+// v1ErrorBody is the error envelope, for the spec.
+type v1ErrorBody struct {
+	Error v1Error `json:"error"`
+}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+// OpenAPI returns the OpenAPI 3 document of the routes registered so far,
+// as indented JSON. It depends only on the registry and routeDocs, so it
+// is the same on every build of the same code.
+func (rr *RouteRegistry) OpenAPI() ([]byte, error) {
+	b := &schemaBuilder{components: map[string]any{}, names: map[reflect.Type]string{}}
+	paths := map[string]map[string]any{}
+	for _, r := range rr.routes {
+		path := pathParam.ReplaceAllString(r.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(r.Method)] = b.operation(r)
+	}
+	b.schema(reflect.TypeFor[v1ErrorBody]())
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Backend API",
+			"version": v1.name,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "Error",
+					"content":     jsonContent(ref("V1ErrorBody")),
+				},
+			},
+			"securitySchemes": map[string]any{
+				"bearer":            map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "Clerk session JWT"},
+				"apiKey":            map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"internalSignature": map[string]any{"type": "apiKey", "in": "header", "name": "X-Internal-Signature"},
+			},
+		},
+	}, "", "  ")
+}
+
+func (b *schemaBuilder) operation(r RouteInfo) map[string]any {
+	doc := routeDocs[r.Method+" "+r.Path]
+	op := map[string]any{"summary": doc.summary}
+	if doc.summary == "" {
+		op["summary"] = r.Method + " " + r.Path
+	}
+
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, q := range doc.query {
+		params = append(params, map[string]any{"name": q, "in": "query", "schema": map[string]any{"type": "string"}})
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+
+	switch {
+	case strings.HasPrefix(r.Path, APIV1+"/"), strings.HasPrefix(r.Path, "/debug/"):
+		op["security"] = []any{map[string]any{"bearer": []any{}}, map[string]any{"apiKey": []any{}}}
+	case strings.HasPrefix(r.Path, "/internal/"):
+		op["security"] = []any{map[string]any{"internalSignature": []any{}}}
+	}
+	var notes []string
+	if strings.HasPrefix(r.Path, APIV1+"/admin/") || strings.HasPrefix(r.Path, "/debug/") {
+		notes = append(notes, "Admins only.")
+	}
+	if len(r.Scopes) > 0 {
+		scopes := make([]string, len(r.Scopes))
+		for i, s := range r.Scopes {
+			scopes[i] = string(s)
+		}
+		notes = append(notes, "Requires "+strings.Join(scopes, ", ")+".")
+		op["x-scopes"] = scopes
+	}
+	if notes != nil {
+		op["description"] = strings.Join(notes, " ")
+	}
+
+	switch {
+	case doc.request != nil:
+		op["requestBody"] = map[string]any{"required": true, "content": jsonContent(b.schema(reflect.TypeOf(doc.request)))}
+	case doc.upload != "":
+		op["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+			"multipart/form-data": map[string]any{"schema": map[string]any{
+				"type":       "object",
+				"required":   []string{doc.upload},
+				"properties": map[string]any{doc.upload: map[string]any{"type": "string", "format": "binary"}},
+			}},
+		}}
+	}
+
+	status := doc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if doc.response != nil {
+		ok["content"] = jsonContent(b.schema(reflect.TypeOf(doc.response)))
+	}
+	op["responses"] = map[string]any{
+		strconv.Itoa(status): ok,
+		"default":            map[string]any{"$ref": "#/components/responses/Error"},
+	}
+	return op
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaBuilder derives JSON schemas from Go types the way encoding/json
+// marshals them. Named structs become components, referenced by name.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType = reflect.TypeFor[time.Time]()
+	rawType  = reflect.TypeFor[json.RawMessage]()
+)
+
+// nullableSchemas are the pgtype columns, which marshal as null when not
+// valid.
+var nullableSchemas = map[reflect.Type]map[string]any{
+	reflect.TypeFor[pgtype.Text]():        {"type": "string", "nullable": true},
+	reflect.TypeFor[pgtype.Timestamptz](): {"type": "string", "format": "date-time", "nullable": true},
+	reflect.TypeFor[pgtype.Int8]():        {"type": "integer", "format": "int64", "nullable": true},
+	reflect.TypeFor[pgtype.Int4]():        {"type": "integer", "format": "int32", "nullable": true},
+	reflect.TypeFor[pgtype.Bool]():        {"type": "boolean", "nullable": true},
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if s, ok := nullableSchemas[t]; ok {
+		return s
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := map[string]any{"nullable": true}
+		if inner := b.schema(t.Elem()); inner["$ref"] != nil {
+			s["allOf"] = []any{inner}
+		} else {
+			for k, v := range inner {
+				s[k] = v
+			}
+		}
+		return s
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.components[name] = b.object(t)
+		}
+		return ref(name)
+	}
+	return map[string]any{}
+}
+
+// componentName names t after its Go type, e.g. PageListUsersPageRow for
+// Page[db.ListUsersPageRow], with the package prepended if the name is
+// taken.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		arg := strings.TrimSuffix(name[i+1:], "]")
+		name = name[:i] + exported(arg[strings.LastIndexByte(arg, '.')+1:])
+	}
+	name = exported(name)
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		name = exported(pkg[strings.LastIndexByte(pkg, '/')+1:]) + name
+	}
+	return name
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	b.fields(t, props, &required)
+	s := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		slices.Sort(required)
+		s["required"] = required
+	}
+	return s
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.fields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema := b.schema(f.Type)
+		rules := strings.Split(f.Tag.Get("validate"), ",")
+		// Rules after dive apply to the elements.
+		if i := slices.Index(rules, "dive"); i >= 0 {
+			rules = rules[:i]
+		}
+		for _, rule := range rules {
+			if values, ok := strings.CutPrefix(rule, "oneof="); ok && schema["type"] == "string" {
+				schema = maps.Clone(schema)
+				schema["enum"] = strings.Fields(values)
+			}
+		}
+		props[name] = schema
+		if slices.Contains(rules, "required") ||
+			!strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// openAPIHandler serves the registry's spec, built on first request once
+// every route is registered.
+func openAPIHandler(rr *RouteRegistry) gin.HandlerFunc {
+	spec := sync.OnceValues(rr.OpenAPI)
+	return func(c *gin.Context) {
+		body, err := spec()
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", body)
+	}
+}
@@ -0,0 +1,31 @@
+package httpapi
+
+import (
+	"context"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+)
+
+// announceUser queues an outgoing user.* event carrying the user's profile
+// as q sees it, so it commits or rolls back with the change it announces,
+// as the Clerk webhook handlers do. Subscribers may see an event twice,
+// e.g. user.created from both POST /admin/users and Clerk's webhook.
+func announceUser(ctx context.Context, q *db.Queries, outbox *webhooks.Outbox, pii *crypto.Keyring, eventType, clerkID string) error {
+	u, err := q.GetUserByClerkID(ctx, clerkID)
+	if err != nil {
+		return err
+	}
+	if u.Email, err = pii.Decrypt(u.Email); err != nil {
+		return err
+	}
+	return outbox.WithTx(q).Emit(ctx, eventType, webhooks.UserEventData{
+		ClerkID:   u.ClerkID,
+		Name:      u.Name,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Username:  u.Username,
+	})
+}
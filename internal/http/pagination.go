@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"backend/internal/apierror"
+)
+
+// Page is the envelope of every list endpoint, so clients page through
+// all of them the same way.
+type Page[T any] struct {
+	Data []T      `json:"data"`
+	Meta PageMeta `json:"meta"`
+}
+
+// PageMeta describes a page: Total counts every matching row, Limit is the
+// page size, or 0 for a list returned whole, Cursor is the cursor the page
+// was requested with and NextCursor, passed as ?cursor=, fetches the
+// following page. Both cursors are empty when there is none.
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// newPage wraps rows fetched with limit after cursor. A full page gets a
+// next cursor made from its last row by next; a short one is the last page.
+func newPage[T any](data []T, total int64, limit int, cursor string, next func(last T) string) Page[T] {
+	if data == nil {
+		data = []T{}
+	}
+	p := Page[T]{Data: data, Meta: PageMeta{Total: total, Limit: limit, Cursor: cursor}}
+	if len(data) > 0 && len(data) == limit {
+		p.Meta.NextCursor = next(data[len(data)-1])
+	}
+	return p
+}
+
+// wholePage wraps a list that is not paginated: every row, no cursors and a
+// limit of 0.
+func wholePage[T any](data []T) Page[T] {
+	return newPage(data, int64(len(data)), 0, "", nil)
+}
+
+var errBadCursor = apierror.Invalid("cursor", "invalid cursor")
+
+// encodeCursor builds an opaque cursor from the sort key of the last row on
+// a page.
+func encodeCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errBadCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", errBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", errBadCursor
+	}
+	return t, id, nil
+}
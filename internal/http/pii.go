@@ -0,0 +1,22 @@
+package httpapi
+
+import (
+	"log"
+
+	"backend/internal/crypto"
+)
+
+// revealPII decrypts, in place, row fields that internal/crypto encrypted.
+// Rows written before encryption was enabled hold plaintext, which passes
+// through unchanged.
+func revealPII(keys *crypto.Keyring, fields ...*string) error {
+	for _, f := range fields {
+		v, err := keys.Decrypt(*f)
+		if err != nil {
+			log.Printf("pii: %v", err)
+			return err
+		}
+		*f = v
+	}
+	return nil
+}
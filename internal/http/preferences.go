@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"backend/internal/apierror"
+	"backend/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Preferences is the schema of users.preferences. Documents are stored
+// re-encoded from this struct, so unknown fields are dropped and the column
+// cannot turn into a dumping ground again.
+type Preferences struct {
+	Notifications NotificationPreferences `json:"notifications"`
+	// Locale is a language tag such as "en" or "pt-BR".
+	Locale string `json:"locale,omitempty" validate:"omitempty,locale"`
+	// Timezone is an IANA zone name such as "Europe/Berlin".
+	Timezone string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+}
+
+type NotificationPreferences struct {
+	Email bool `json:"email"`
+	Push  bool `json:"push"`
+	SMS   bool `json:"sms"`
+}
+
+// GetPreferences returns the user's preferences. Users may read their own;
+// reading anyone else's requires users:read.
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	clerkID := c.Param("id")
+	v, _ := c.Get("scopes")
+	granted, _ := v.([]Scope)
+	if c.GetString("clerk_id") != clerkID && !hasScope(granted, ScopeUsersRead) {
+		writeError(c, missingScope(ScopeUsersRead))
+		return
+	}
+
+	raw, err := h.q.GetUserPreferences(c.Request.Context(), clerkID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve preferences"))
+		return
+	}
+
+	// Stored documents were validated on write; decoding fills in defaults
+	// for fields added since.
+	var prefs Preferences
+	_ = json.Unmarshal(raw, &prefs)
+	c.JSON(http.StatusOK, prefs)
+}
+
+// PutPreferences replaces the user's preferences with a validated document.
+func (h *UserHandler) PutPreferences(c *gin.Context) {
+	clerkID := c.Param("id")
+	if !canEditUser(c, clerkID) {
+		writeError(c, missingScope(ScopeUsersWrite))
+		return
+	}
+
+	var prefs Preferences
+	if err := bindJSON(c, &prefs); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	doc, _ := json.Marshal(prefs)
+	n, err := h.q.SetUserPreferences(c.Request.Context(), db.SetUserPreferencesParams{ClerkID: clerkID, Preferences: doc})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to update preferences"))
+		return
+	}
+	if n == 0 {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.preferences_updated", nil)
+	c.JSON(http.StatusOK, prefs)
+}
@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/privacy"
+	"backend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// PrivacyHandler serves the GDPR data export and erasure endpoints.
+type PrivacyHandler struct {
+	q      *db.Queries
+	store  *storage.S3
+	eraser *privacy.Eraser
+	pii    *crypto.Keyring
+}
+
+func NewPrivacyHandler(q *db.Queries, store *storage.S3, eraser *privacy.Eraser, pii *crypto.Keyring) *PrivacyHandler {
+	return &PrivacyHandler{q: q, store: store, eraser: eraser, pii: pii}
+}
+
+type dataExport struct {
+	ExportedAt  time.Time                     `json:"exported_at"`
+	User        db.GetUserDataExportRow       `json:"user"`
+	Preferences json.RawMessage               `json:"preferences"`
+	AvatarURL   string                        `json:"avatar_url,omitempty"`
+	Memberships []db.ListMembershipsByUserRow `json:"memberships"`
+	Activity    []userEventResponse           `json:"activity"`
+}
+
+// Export returns everything stored about the user as one JSON document.
+// Users may export their own data; anyone else needs users:read.
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	clerkID := c.Param("id")
+	v, _ := c.Get("scopes")
+	granted, _ := v.([]Scope)
+	if c.GetString("clerk_id") != clerkID && !hasScope(granted, ScopeUsersRead) {
+		writeError(c, missingScope(ScopeUsersRead))
+		return
+	}
+
+	user, err := h.q.GetUserDataExport(ctx, clerkID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	if err == nil {
+		err = revealPII(h.pii, &user.Email.String, &user.Phone.String)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to export user data"))
+		return
+	}
+	prefs, err := h.q.GetUserPreferences(ctx, clerkID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.Internal(err, "failed to export user data"))
+		return
+	}
+	if len(prefs) == 0 {
+		prefs = []byte("{}")
+	}
+	memberships, err := h.q.ListMembershipsByUser(ctx, clerkID)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to export user data"))
+		return
+	}
+	events, err := h.q.ListAllUserEvents(ctx, clerkID)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to export user data"))
+		return
+	}
+
+	out := dataExport{
+		ExportedAt:  time.Now().UTC(),
+		User:        user,
+		Preferences: prefs,
+		Memberships: memberships,
+		Activity:    make([]userEventResponse, 0, len(events)),
+	}
+	if out.Memberships == nil {
+		out.Memberships = []db.ListMembershipsByUserRow{}
+	}
+	if user.AvatarKey.Valid && h.store != nil {
+		out.AvatarURL = h.store.PresignGet(user.AvatarKey.String, avatarURLExpiry)
+	}
+	for _, e := range events {
+		out.Activity = append(out.Activity, userEventResponse{
+			ID:        e.ID,
+			EventType: e.EventType,
+			Actor:     e.Actor,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt.Time,
+		})
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": clerkID + "-data-export.json"}))
+	c.JSON(http.StatusOK, out)
+}
+
+// Erase queues anonymization of the user's personal data. The work runs in
+// privacy.Eraser; repeating the request while one is pending returns it.
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	clerkID := c.Param("id")
+	if !canEditUser(c, clerkID) {
+		writeError(c, missingScope(ScopeUsersWrite))
+		return
+	}
+
+	ctx := c.Request.Context()
+	exists, err := h.q.UserExists(ctx, clerkID)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to queue erasure"))
+		return
+	}
+	if !exists {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+
+	req, err := h.q.CreateErasureRequest(ctx, db.CreateErasureRequestParams{ClerkID: clerkID, RequestedBy: actor(c)})
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to queue erasure"))
+		return
+	}
+	recordUserEvent(c, h.q, clerkID, "user.erasure_requested", nil)
+	h.eraser.Notify()
+
+	c.JSON(http.StatusAccepted, gin.H{"request_id": req.ID, "status": req.Status, "created_at": req.CreatedAt})
+}
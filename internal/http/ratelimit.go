@@ -0,0 +1,302 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitAlgorithm selects how a policy is enforced.
+type RateLimitAlgorithm string
+
+const (
+	// TokenBucket lets a client that has been idle spend Burst requests at
+	// once, then refills at Rate. It is the default.
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+	// SlidingWindow allows at most Burst requests in any Burst/Rate window,
+	// which smooths traffic for routes whose downstream dislikes bursts.
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitPolicy is the limit applied per client IP.
+type RateLimitPolicy struct {
+	Rate  rate.Limit
+	Burst int
+	// Algorithm defaults to TokenBucket when empty.
+	Algorithm RateLimitAlgorithm
+}
+
+// RateLimitConfig holds the default policy and per-route overrides keyed by
+// the route pattern (gin's FullPath), in the same style as the body limits.
+// Each route with an override gets its own bucket; all other routes share
+// the default one.
+type RateLimitConfig struct {
+	Default RateLimitPolicy
+	Routes  map[string]RateLimitPolicy
+	// IdleTTL is how long an idle client's in-memory bucket is kept;
+	// CleanupInterval is how often idle buckets are evicted.
+	IdleTTL         time.Duration
+	CleanupInterval time.Duration
+	// MaxClients caps how many clients each in-memory policy tracks; the
+	// least recently seen are evicted beyond it.
+	MaxClients int
+	// Exempt callers bypass every limit.
+	Exempt RateLimitExemptions
+}
+
+// DefaultRateLimitConfig is the policy table used unless the RATE_LIMIT_*
+// settings override it.
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Default:         RateLimitPolicy{Rate: 10, Burst: 20},
+		IdleTTL:         10 * time.Minute,
+		CleanupInterval: 2 * time.Minute,
+		MaxClients:      100_000,
+		Exempt:          RateLimitExemptions{Prefixes: SvixIPRanges},
+		Routes: map[string]RateLimitPolicy{
+			// Probes poll often and are cheap.
+			"/health":       {Rate: 50, Burst: 100},
+			"/health/ready": {Rate: 50, Burst: 100},
+			// Clerk delivers in bursts after an outage; dropping deliveries
+			// only causes more retries.
+			"/webhooks/clerk": {Rate: 50, Burst: 500},
+			// Exports stream the whole table.
+			APIV1 + "/admin/users/export": {Rate: rate.Every(30 * time.Second), Burst: 2},
+		},
+	}
+}
+
+func (p RateLimitPolicy) String() string {
+	s := fmt.Sprintf("%g/s burst %d", float64(p.Rate), p.Burst)
+	if p.Algorithm == SlidingWindow {
+		s += " (sliding window)"
+	}
+	return s
+}
+
+// String summarizes the effective limits for the startup log.
+func (cfg RateLimitConfig) String() string {
+	paths := make([]string, 0, len(cfg.Routes))
+	for p := range cfg.Routes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "default %s, idle ttl %s, cleanup every %s, max %d clients",
+		cfg.Default, cfg.IdleTTL, cfg.CleanupInterval, cfg.MaxClients)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "; %s %s", p, cfg.Routes[p])
+	}
+	fmt.Fprintf(&b, "; exempt %d ip ranges, %d api keys", len(cfg.Exempt.Prefixes), len(cfg.Exempt.APIKeyHashes))
+	return b.String()
+}
+
+// rateLimiter decides whether the client identified by key may make another
+// request. Implementations must be safe for concurrent use.
+type rateLimiter interface {
+	allow(ctx context.Context, key string) (rateDecision, error)
+}
+
+// rateDecision is the outcome of one allow call, with the bucket state
+// reported to clients in the X-RateLimit-* headers.
+type rateDecision struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// reset is when the bucket will be full again; retryAfter is when the
+	// next request would be allowed.
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// decide derives a rateDecision from the tokens left after the attempt.
+func decide(allowed bool, tokens float64, r rate.Limit, burst int) rateDecision {
+	d := rateDecision{allowed: allowed, limit: burst}
+	if tokens > 0 {
+		d.remaining = int(math.Floor(tokens))
+	}
+	if r > 0 {
+		perToken := float64(time.Second) / float64(r)
+		d.reset = time.Duration((float64(burst) - tokens) * perToken)
+		if tokens < 1 {
+			d.retryAfter = time.Duration((1 - tokens) * perToken)
+		}
+	}
+	return d
+}
+
+// limiterStore is the in-process token-bucket rateLimiter. Limits are per
+// replica.
+type limiterStore struct {
+	clients *lruStore[*rate.Limiter]
+	r       rate.Limit
+	burst   int
+	now     func() time.Time
+}
+
+func newLimiterStore(ctx context.Context, name string, p RateLimitPolicy, cfg RateLimitConfig, metrics *RateLimitMetrics) *limiterStore {
+	return &limiterStore{
+		clients: newLRUStore(ctx, name, cfg.MaxClients, cfg.IdleTTL, cfg.CleanupInterval, metrics, func() *rate.Limiter {
+			return rate.NewLimiter(p.Rate, p.Burst)
+		}),
+		r:     p.Rate,
+		burst: p.Burst,
+		now:   time.Now,
+	}
+}
+
+func (ls *limiterStore) allow(_ context.Context, key string) (rateDecision, error) {
+	var d rateDecision
+	ls.clients.with(key, func(lim *rate.Limiter) {
+		now := ls.now()
+		allowed := lim.AllowN(now, 1)
+		d = decide(allowed, lim.TokensAt(now), ls.r, ls.burst)
+	})
+	return d, nil
+}
+
+// fallbackLimiter uses primary and switches to fallback for any request on
+// which primary fails, so an unreachable Redis degrades limits to
+// per-replica instead of rejecting or admitting everything.
+type fallbackLimiter struct {
+	primary  rateLimiter
+	fallback rateLimiter
+
+	mu        sync.Mutex
+	lastLogAt time.Time
+}
+
+func (f *fallbackLimiter) allow(ctx context.Context, key string) (rateDecision, error) {
+	d, err := f.primary.allow(ctx, key)
+	if err == nil {
+		return d, nil
+	}
+
+	f.mu.Lock()
+	if time.Since(f.lastLogAt) > time.Minute {
+		f.lastLogAt = time.Now()
+		log.Printf("ratelimit: shared store failed, using in-memory limits: %v", err)
+	}
+	f.mu.Unlock()
+	return f.fallback.allow(ctx, key)
+}
+
+// newRateLimiters builds one limiter per policy using its algorithm. With a
+// Redis client the limits are shared across replicas, falling back to
+// in-memory limits when Redis fails. The in-memory stores sweep idle clients
+// until ctx is done.
+func newRateLimiters(ctx context.Context, cfg RateLimitConfig, client *redis.Client, metrics *RateLimitMetrics) (rateLimiter, map[string]rateLimiter) {
+	build := func(name string, p RateLimitPolicy) rateLimiter {
+		var local, shared rateLimiter
+		if p.Algorithm == SlidingWindow {
+			local = newWindowStore(ctx, name, windowFor(p), cfg, metrics)
+			if client != nil {
+				shared = newRedisWindowLimiter(client, "ratelimit:sw:"+name+":", windowFor(p))
+			}
+		} else {
+			local = newLimiterStore(ctx, name, p, cfg, metrics)
+			if client != nil {
+				shared = newRedisLimiter(client, "ratelimit:"+name+":", p.Rate, p.Burst)
+			}
+		}
+		if shared == nil {
+			return local
+		}
+		return &fallbackLimiter{primary: shared, fallback: local}
+	}
+
+	routes := make(map[string]rateLimiter, len(cfg.Routes))
+	for path, p := range cfg.Routes {
+		routes[path] = build(path, p)
+	}
+	return build("default", cfg.Default), routes
+}
+
+// liveRateLimits holds the limiters built from the current RateLimitConfig,
+// which Settings can replace at runtime.
+type liveRateLimits struct {
+	ctx     context.Context
+	client  *redis.Client
+	metrics *RateLimitMetrics
+	cur     atomic.Pointer[rateLimitSet]
+}
+
+type rateLimitSet struct {
+	cfg    RateLimitConfig
+	def    rateLimiter
+	routes map[string]rateLimiter
+	// stop ends the in-memory stores' sweepers once the set is replaced.
+	stop context.CancelFunc
+}
+
+func newLiveRateLimits(ctx context.Context, cfg RateLimitConfig, client *redis.Client, metrics *RateLimitMetrics) *liveRateLimits {
+	l := &liveRateLimits{ctx: ctx, client: client, metrics: metrics}
+	l.set(cfg)
+	return l
+}
+
+// set replaces the limiters with ones built from cfg. In-memory buckets
+// start over; Redis-backed ones keep their counts.
+func (l *liveRateLimits) set(cfg RateLimitConfig) {
+	ctx, stop := context.WithCancel(l.ctx)
+	def, routes := newRateLimiters(ctx, cfg, l.client, l.metrics)
+	if old := l.cur.Swap(&rateLimitSet{cfg: cfg, def: def, routes: routes, stop: stop}); old != nil {
+		old.stop()
+	}
+}
+
+func (l *liveRateLimits) config() RateLimitConfig {
+	return l.cur.Load().cfg
+}
+
+// rateLimitMiddleware applies the limiter of the matched route, or the
+// default one, to every caller that is not exempt.
+func rateLimitMiddleware(limits *liveRateLimits, metrics *RateLimitMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		set := limits.cur.Load()
+		if set.cfg.Exempt.exempt(c) {
+			c.Next()
+			return
+		}
+		l, ok := set.routes[c.FullPath()]
+		if !ok {
+			l = set.def
+		}
+		d, err := l.allow(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(d.limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+		if !d.allowed {
+			metrics.rejected(c.FullPath())
+			c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(d.retryAfter))))
+			writeError(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
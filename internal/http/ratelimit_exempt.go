@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SvixIPRanges are the addresses Svix delivers Clerk webhooks from, as
+// published at https://docs.svix.com/receiving/source-ips. Exempting them
+// keeps webhook retries after an outage from being throttled and dropped.
+var SvixIPRanges = []netip.Prefix{
+	// US
+	netip.MustParsePrefix("44.228.126.217/32"),
+	netip.MustParsePrefix("50.112.21.217/32"),
+	netip.MustParsePrefix("52.24.126.164/32"),
+	netip.MustParsePrefix("54.148.139.208/32"),
+	netip.MustParsePrefix("2600:1f24:64:8000::/52"),
+	// EU
+	netip.MustParsePrefix("52.215.16.239/32"),
+	netip.MustParsePrefix("54.216.8.72/32"),
+	netip.MustParsePrefix("63.33.109.123/32"),
+	netip.MustParsePrefix("2a05:d028:17:8000::/52"),
+}
+
+// RateLimitExemptions lists callers the rate limiter never throttles.
+type RateLimitExemptions struct {
+	// Prefixes are matched against the client IP, e.g. health-check
+	// sources and the Svix delivery ranges.
+	Prefixes []netip.Prefix
+	// APIKeyHashes are hex SHA-256 hashes of X-API-Key values, as stored in
+	// api_keys.key_hash. The key is not validated here; authMiddleware still
+	// rejects unknown keys.
+	APIKeyHashes map[string]bool
+}
+
+func (ex RateLimitExemptions) exempt(c *gin.Context) bool {
+	if len(ex.APIKeyHashes) > 0 {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			if ex.APIKeyHashes[hex.EncodeToString(sum[:])] {
+				return true
+			}
+		}
+	}
+	if len(ex.Prefixes) > 0 {
+		addr, err := netip.ParseAddr(c.ClientIP())
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, p := range ex.Prefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RateLimitMetrics reports the size of the in-memory limiter stores, how
+// many clients they evicted and how many requests were rejected. A nil
+// *RateLimitMetrics is a no-op.
+type RateLimitMetrics struct {
+	entries    *prometheus.GaugeVec
+	evictions  *prometheus.CounterVec
+	rejections *prometheus.CounterVec
+}
+
+func NewRateLimitMetrics(reg prometheus.Registerer) *RateLimitMetrics {
+	m := &RateLimitMetrics{
+		entries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ratelimit_store_entries",
+			Help: "Clients currently tracked by the in-memory rate limiter, per policy.",
+		}, []string{"policy"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_store_evictions_total",
+			Help: "Clients dropped from the in-memory rate limiter, by reason (idle or capacity).",
+		}, []string{"policy", "reason"}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ratelimit_rejections_total",
+			Help: "Requests answered with 429, by route.",
+		}, []string{"route"}),
+	}
+	reg.MustRegister(m.entries, m.evictions, m.rejections)
+	return m
+}
+
+func (m *RateLimitMetrics) size(policy string, n int) {
+	if m != nil {
+		m.entries.WithLabelValues(policy).Set(float64(n))
+	}
+}
+
+func (m *RateLimitMetrics) evicted(policy, reason string, n int) {
+	if m != nil && n > 0 {
+		m.evictions.WithLabelValues(policy, reason).Add(float64(n))
+	}
+}
+
+func (m *RateLimitMetrics) rejected(route string) {
+	if m != nil {
+		if route == "" {
+			route = "unmatched"
+		}
+		m.rejections.WithLabelValues(route).Inc()
+	}
+}
+
+type lruEntry[V any] struct {
+	key      string
+	value    V
+	lastSeen time.Time
+}
+
+// lruStore holds per-client limiter state, at most maxEntries of it. Once
+// full, the least recently seen client is dropped to make room, so spraying
+// requests from many addresses costs the attacker its own budget rather than
+// unbounded memory; the evicted client simply starts over with a fresh
+// limit. Idle clients are also swept every cleanupInterval until ctx is
+// done.
+type lruStore[V any] struct {
+	policy     string
+	maxEntries int
+	newValue   func() V
+	metrics    *RateLimitMetrics
+
+	mu    sync.Mutex
+	order *list.List // front is the most recently seen
+	items map[string]*list.Element
+}
+
+func newLRUStore[V any](ctx context.Context, policy string, maxEntries int, idleTTL, cleanupInterval time.Duration, metrics *RateLimitMetrics, newValue func() V) *lruStore[V] {
+	s := &lruStore[V]{
+		policy:     policy,
+		maxEntries: maxEntries,
+		newValue:   newValue,
+		metrics:    metrics,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+
+	go func() {
+		t := time.NewTicker(cleanupInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.sweep(idleTTL)
+			}
+		}
+	}()
+
+	return s
+}
+
+// with runs fn on key's state, creating it if needed, while holding the
+// store's lock.
+func (s *lruStore[V]) with(key string, fn func(v V)) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*lruEntry[V])
+		e.lastSeen = now
+		s.order.MoveToFront(el)
+		fn(e.value)
+		return
+	}
+
+	evicted := 0
+	for s.maxEntries > 0 && s.order.Len() >= s.maxEntries {
+		s.remove(s.order.Back())
+		evicted++
+	}
+	e := &lruEntry[V]{key: key, value: s.newValue(), lastSeen: now}
+	s.items[key] = s.order.PushFront(e)
+	fn(e.value)
+
+	s.metrics.evicted(s.policy, "capacity", evicted)
+	s.metrics.size(s.policy, s.order.Len())
+}
+
+// sweep drops clients not seen for longer than idleTTL. The list is ordered
+// by recency, so it stops at the first client that is still active.
+func (s *lruStore[V]) sweep(idleTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := 0
+	for el := s.order.Back(); el != nil; el = s.order.Back() {
+		if time.Since(el.Value.(*lruEntry[V]).lastSeen) <= idleTTL {
+			break
+		}
+		s.remove(el)
+		evicted++
+	}
+	s.metrics.evicted(s.policy, "idle", evicted)
+	s.metrics.size(s.policy, s.order.Len())
+}
+
+func (s *lruStore[V]) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*lruEntry[V]).key)
+}
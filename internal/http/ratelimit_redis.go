@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// tokenBucketScript implements the same token bucket as rate.Limiter on a
+// Redis hash, using the server clock so replicas with skewed clocks agree.
+// It returns whether the request is allowed (1 or 0) and the tokens left in
+// thousandths, since Lua numbers are truncated to integers in replies.
+var tokenBucketScript = redis.NewScript(`
+local rate  = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t     = redis.call('TIME')
+local now   = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state  = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts     = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+// redisLimiter is a rateLimiter shared by every replica using the same
+// Redis.
+type redisLimiter struct {
+	client *redis.Client
+	prefix string
+	r      rate.Limit
+	burst  int
+}
+
+func newRedisLimiter(client *redis.Client, prefix string, r rate.Limit, burst int) *redisLimiter {
+	return &redisLimiter{client: client, prefix: prefix, r: r, burst: burst}
+}
+
+func (l *redisLimiter) allow(ctx context.Context, key string) (rateDecision, error) {
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key},
+		strconv.FormatFloat(float64(l.r), 'f', -1, 64), l.burst).Int64Slice()
+	if err != nil {
+		return rateDecision{}, err
+	}
+	if len(res) != 2 {
+		return rateDecision{}, fmt.Errorf("ratelimit: unexpected script reply %v", res)
+	}
+	return decide(res[0] == 1, float64(res[1])/1000, l.r, l.burst), nil
+}
@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for the in-memory limiters.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// burst sends n requests for key at the current time and counts how many
+// were allowed.
+func burst(t *testing.T, l rateLimiter, key string, n int) int {
+	t.Helper()
+	allowed := 0
+	for i := 0; i < n; i++ {
+		d, err := l.allow(t.Context(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimitBursts(t *testing.T) {
+	// Ten requests a second, bursts of ten: the sliding window is one second
+	// long.
+	policy := RateLimitPolicy{Rate: 10, Burst: 10}
+	cfg := DefaultRateLimitConfig()
+
+	type step struct {
+		after time.Duration // idle time before the burst
+		sent  int
+		want  int // requests allowed
+	}
+	tests := []struct {
+		algorithm RateLimitAlgorithm
+		steps     []step
+	}{
+		{TokenBucket, []step{
+			{0, 25, 10},
+			// Half a second refills five tokens.
+			{500 * time.Millisecond, 25, 5},
+			// A full second refills the bucket.
+			{time.Second, 25, 10},
+			{10 * time.Second, 25, 10},
+		}},
+		{SlidingWindow, []step{
+			{0, 25, 10},
+			// Still inside the first window, which is full.
+			{500 * time.Millisecond, 25, 0},
+			// Halfway into the next window, half of the previous one's ten
+			// requests still count.
+			{time.Second, 25, 5},
+			// Both remembered windows have passed.
+			{10 * time.Second, 25, 10},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+			var l rateLimiter
+			if tt.algorithm == SlidingWindow {
+				ws := newWindowStore(t.Context(), "test", windowFor(policy), cfg, nil)
+				ws.now = clock.now
+				l = ws
+			} else {
+				ls := newLimiterStore(t.Context(), "test", policy, cfg, nil)
+				ls.now = clock.now
+				l = ls
+			}
+
+			for i, s := range tt.steps {
+				clock.advance(s.after)
+				if got := burst(t, l, "203.0.113.9", s.sent); got != s.want {
+					t.Errorf("burst %d: allowed %d of %d, want %d (denied %d, want %d)",
+						i, got, s.sent, s.want, s.sent-got, s.sent-s.want)
+				}
+			}
+			// Clients have separate limits.
+			if got := burst(t, l, "203.0.113.10", 25); got != 10 {
+				t.Errorf("second client: allowed %d of 25, want 10", got)
+			}
+		})
+	}
+}
+
+func TestRateLimitDecisionHeaders(t *testing.T) {
+	policy := RateLimitPolicy{Rate: 10, Burst: 10}
+	cfg := DefaultRateLimitConfig()
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+
+	ls := newLimiterStore(t.Context(), "test", policy, cfg, nil)
+	ls.now = clock.now
+	ws := newWindowStore(t.Context(), "test", windowFor(policy), cfg, nil)
+	ws.now = clock.now
+
+	for name, l := range map[string]rateLimiter{"token_bucket": ls, "sliding_window": ws} {
+		t.Run(name, func(t *testing.T) {
+			burst(t, l, "203.0.113.9", 10)
+			d, err := l.allow(t.Context(), "203.0.113.9")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.allowed || d.limit != 10 || d.remaining != 0 {
+				t.Errorf("decision after burst = %+v, want denied with limit 10 and none remaining", d)
+			}
+			if d.retryAfter <= 0 {
+				t.Errorf("retryAfter = %s, want a positive wait", d.retryAfter)
+			}
+		})
+	}
+}
+
+func TestNewRateLimitersUsesEachRoutesAlgorithm(t *testing.T) {
+	cfg := DefaultRateLimitConfig()
+	cfg.Routes = map[string]RateLimitPolicy{
+		"/bucket": {Rate: 1, Burst: 1},
+		"/window": {Rate: 1, Burst: 1, Algorithm: SlidingWindow},
+	}
+	def, routes := newRateLimiters(t.Context(), cfg, nil, nil)
+	if _, ok := def.(*limiterStore); !ok {
+		t.Errorf("default limiter is %T, want *limiterStore", def)
+	}
+	if _, ok := routes["/bucket"].(*limiterStore); !ok {
+		t.Errorf("/bucket limiter is %T, want *limiterStore", routes["/bucket"])
+	}
+	if _, ok := routes["/window"].(*windowStore); !ok {
+		t.Errorf("/window limiter is %T, want *windowStore", routes["/window"])
+	}
+}
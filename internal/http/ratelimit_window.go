@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript is the Redis counterpart of windowStore. It returns
+// whether the request is allowed (1 or 0), the previous and current window
+// counts and the milliseconds elapsed in the current window.
+var slidingWindowScript = redis.NewScript(`
+local window = tonumber(ARGV[1])
+local limit  = tonumber(ARGV[2])
+local t      = redis.call('TIME')
+local now    = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'prev', 'curr')
+local start = tonumber(state[1]) or now
+local prev  = tonumber(state[2]) or 0
+local curr  = tonumber(state[3]) or 0
+
+if now - start >= 2 * window then
+  start, prev, curr = now, 0, 0
+elseif now - start >= window then
+  start, prev, curr = start + window, curr, 0
+end
+local elapsed = now - start
+
+local allowed = 0
+if prev * (window - elapsed) / window + curr + 1 <= limit then
+  curr = curr + 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'start', start, 'prev', prev, 'curr', curr)
+redis.call('PEXPIRE', KEYS[1], 2 * window + 1000)
+return {allowed, prev, curr, elapsed}
+`)
+
+// slidingWindow is the sliding-window counter shared by windowStore and
+// redisWindowLimiter: at most limit requests are allowed in any window,
+// estimating the requests still inside it from the previous fixed window's
+// count weighted by how much of it overlaps. Unlike a token bucket it never
+// lets a client spend a full burst right after an idle period on top of
+// requests it has just made.
+type slidingWindow struct {
+	window time.Duration
+	limit  int
+}
+
+// windowFor converts a policy into its sliding-window equivalent: Burst
+// requests per Burst/Rate, so the long-run rate matches the token bucket.
+func windowFor(p RateLimitPolicy) slidingWindow {
+	w := time.Duration(float64(p.Burst) / float64(p.Rate) * float64(time.Second))
+	return slidingWindow{window: max(w, time.Millisecond), limit: p.Burst}
+}
+
+func (sw slidingWindow) estimate(prev, curr int, elapsed time.Duration) float64 {
+	return float64(prev)*float64(sw.window-elapsed)/float64(sw.window) + float64(curr)
+}
+
+// decide reports the window state after the attempt in the same terms as a
+// token bucket: reset is when both counted windows have expired and
+// retryAfter is when the estimate next drops low enough to allow a request.
+func (sw slidingWindow) decide(allowed bool, prev, curr int, elapsed time.Duration) rateDecision {
+	d := rateDecision{allowed: allowed, limit: sw.limit}
+	if rem := float64(sw.limit) - sw.estimate(prev, curr, elapsed); rem > 0 {
+		d.remaining = int(math.Floor(rem))
+	}
+	switch {
+	case curr > 0:
+		d.reset = 2*sw.window - elapsed
+	case prev > 0:
+		d.reset = sw.window - elapsed
+	}
+
+	if !allowed {
+		room := float64(sw.limit - 1)
+		if prev > 0 && float64(curr) <= room {
+			// Wait for enough of the previous window to slide out.
+			at := time.Duration(float64(sw.window) * (1 - (room-float64(curr))/float64(prev)))
+			d.retryAfter = max(at-elapsed, 0)
+		} else {
+			// Only the next window helps, once the current count has
+			// decayed enough.
+			at := time.Duration(float64(sw.window) * (1 - room/float64(curr)))
+			d.retryAfter = sw.window - elapsed + max(at, 0)
+		}
+	}
+	return d
+}
+
+type windowCounter struct {
+	start      time.Time
+	prev, curr int
+}
+
+// windowStore is the in-process sliding-window rateLimiter.
+type windowStore struct {
+	slidingWindow
+	clients *lruStore[*windowCounter]
+	now     func() time.Time
+}
+
+func newWindowStore(ctx context.Context, name string, sw slidingWindow, cfg RateLimitConfig, metrics *RateLimitMetrics) *windowStore {
+	// A counter must outlive both windows it remembers.
+	idleTTL := max(cfg.IdleTTL, 2*sw.window)
+	ws := &windowStore{slidingWindow: sw, now: time.Now}
+	ws.clients = newLRUStore(ctx, name, cfg.MaxClients, idleTTL, cfg.CleanupInterval, metrics, func() *windowCounter {
+		return &windowCounter{start: ws.now()}
+	})
+	return ws
+}
+
+func (ws *windowStore) allow(_ context.Context, key string) (rateDecision, error) {
+	var d rateDecision
+	ws.clients.with(key, func(c *windowCounter) {
+		now := ws.now()
+		switch elapsed := now.Sub(c.start); {
+		case elapsed >= 2*ws.window:
+			c.start, c.prev, c.curr = now, 0, 0
+		case elapsed >= ws.window:
+			c.start, c.prev, c.curr = c.start.Add(ws.window), c.curr, 0
+		}
+		elapsed := now.Sub(c.start)
+
+		allowed := ws.estimate(c.prev, c.curr, elapsed)+1 <= float64(ws.limit)
+		if allowed {
+			c.curr++
+		}
+		d = ws.decide(allowed, c.prev, c.curr, elapsed)
+	})
+	return d, nil
+}
+
+// redisWindowLimiter is the sliding-window rateLimiter shared through Redis.
+type redisWindowLimiter struct {
+	slidingWindow
+	client *redis.Client
+	prefix string
+}
+
+func newRedisWindowLimiter(client *redis.Client, prefix string, sw slidingWindow) *redisWindowLimiter {
+	return &redisWindowLimiter{slidingWindow: sw, client: client, prefix: prefix}
+}
+
+func (l *redisWindowLimiter) allow(ctx context.Context, key string) (rateDecision, error) {
+	res, err := slidingWindowScript.Run(ctx, l.client, []string{l.prefix + key},
+		l.window.Milliseconds(), l.limit).Int64Slice()
+	if err != nil {
+		return rateDecision{}, err
+	}
+	if len(res) != 4 {
+		return rateDecision{}, fmt.Errorf("ratelimit: unexpected script reply %v", res)
+	}
+	return l.decide(res[0] == 1, int(res[1]), int(res[2]), time.Duration(res[3])*time.Millisecond), nil
+}
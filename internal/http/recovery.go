@@ -0,0 +1,63 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryMiddleware turns a panic into a 500 with a JSON body and logs it
+// with its stack trace. It logs no request headers, unlike gin.Recovery,
+// since they carry credentials. A write to a client that has hung up
+// panics too; that is logged without a stack and answered with nothing.
+// It runs after requestIDMiddleware, so the record carries the request ID.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			// The server's own signal to abort the response.
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			ctx := c.Request.Context()
+			if err, ok := v.(error); ok && clientGone(err) {
+				slog.WarnContext(ctx, "http: client disconnected", "route", c.FullPath(), "error", err)
+				c.Abort()
+				return
+			}
+			slog.ErrorContext(ctx, "http: panic serving request",
+				"method", c.Request.Method,
+				"route", c.FullPath(),
+				"panic", v,
+				"stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			writeError(c, apierror.Internal(nil, "internal error"))
+		}()
+		c.Next()
+	}
+}
+
+// clientGone reports whether err is a write to a connection the client
+// closed.
+func clientGone(err error) bool {
+	var se *os.SyscallError
+	if !errors.As(err, new(*net.OpError)) || !errors.As(err, &se) {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend/internal/report"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportMiddleware sends panics and 5xx responses to the error reporter
+// with the route, caller and request ID. Panics are re-raised for
+// recoveryMiddleware to answer. 503s are left out: they come from load
+// shedding and are expected under overload, which the metrics already show.
+func reportMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if v := recover(); v != nil {
+				report.Panic(c.Request.Context(), v, reportRequest(c, http.StatusInternalServerError))
+				panic(v)
+			}
+		}()
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < 500 || status == http.StatusServiceUnavailable {
+			return
+		}
+		err := c.Errors.Last()
+		if err == nil {
+			report.Error(fmt.Errorf("%s %s answered %d", c.Request.Method, c.FullPath(), status), reportRequest(c, status))
+			return
+		}
+		report.Error(err.Err, reportRequest(c, status))
+	}
+}
+
+func reportRequest(c *gin.Context, status int) report.Request {
+	return report.Request{
+		HTTP:      c.Request,
+		Route:     c.FullPath(),
+		Status:    status,
+		UserID:    callerID(c),
+		RequestID: c.GetString("request_id"),
+	}
+}
@@ -0,0 +1,25 @@
+package httpapi
+
+import (
+	"backend/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDMiddleware gives every request an ID: the caller's X-Request-ID
+// when it is well-formed, a random one otherwise. The ID is echoed in the
+// response header, stored in the request context for logs and outbound
+// calls, and included in error bodies, so a user reporting an error can
+// quote it.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Request = c.Request.WithContext(requestid.WithContext(c.Request.Context(), id))
+		c.Set("request_id", id)
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,342 @@
+// Package httpapi wires the Gin router: route groups, their middleware
+// stacks and the handlers behind them.
+package httpapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/auth/jwks"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/pglisten"
+	"backend/internal/privacy"
+	"backend/internal/storage"
+	"backend/internal/tracing"
+	"backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Config holds everything the router needs from main.
+type Config struct {
+	Pool    *pgxpool.Pool
+	Queries *db.Queries
+	JWKS    *jwks.Cache
+	// SchemaVersion is the migration version this build expects;
+	// /health/ready fails while the database is behind it.
+	SchemaVersion uint
+	// MigrationsTable is the version table /health/ready reads;
+	// schema_migrations when empty.
+	MigrationsTable string
+	// ReadPool serves read-only listings and exports; nil reads from Pool.
+	ReadPool *db.ReadPool
+
+	// WebhookWorker processes queued Clerk webhooks.
+	WebhookWorker *webhooks.Worker
+	// WebhookListener wakes the worker on new webhooks; when set,
+	// /health/ready requires it to be connected.
+	WebhookListener *pglisten.Listener
+	WebhookMetrics  *webhooks.Metrics
+	// WebhookSecrets are the accepted Clerk signing secrets; more than one
+	// is configured only while rotating.
+	WebhookSecrets []string
+	// WebhookTolerance bounds how far svix-timestamp may drift from now.
+	WebhookTolerance time.Duration
+	// InternalSigningSecret enables the /internal group when non-empty.
+	InternalSigningSecret []byte
+
+	CORS CORSConfig
+
+	// LogLevel is the level of the default logger, which Settings changes
+	// at runtime.
+	LogLevel *slog.LevelVar
+	// Features are the feature flags enabled at startup.
+	Features []string
+	// Maintenance answers 503 to API requests from startup; Settings
+	// switches it at runtime.
+	Maintenance Maintenance
+	// ReloadSettings re-reads the configuration for Settings.Reload; nil
+	// disables reloading.
+	ReloadSettings func() (RuntimeSettings, error)
+
+	// RateLimits defaults to DefaultRateLimitConfig when left zero.
+	RateLimits RateLimitConfig
+	// Redis, when set, backs the rate limiter so limits apply across
+	// replicas.
+	Redis            *redis.Client
+	RateLimitMetrics *RateLimitMetrics
+
+	// TrustedProxies are the peers whose X-Forwarded-For and X-Real-IP
+	// headers set the client IP that rate limits and logs use. Requests
+	// from anyone else keep their TCP peer address, so clients cannot pick
+	// their own IP.
+	TrustedProxies []netip.Prefix
+
+	// AccessLog logs every request; when false only 5xx responses are
+	// logged.
+	AccessLog bool
+
+	// HTTPMetrics records request counts and latency per route.
+	HTTPMetrics *HTTPMetrics
+	// SlowRequests logs requests over its threshold and lists the recent
+	// ones at GET /admin/slow-requests; nil disables it.
+	SlowRequests *SlowRequests
+	// Metrics serves GET /metrics behind its network or basic-auth guard.
+	Metrics MetricsConfig
+
+	// Storage enables the avatar endpoints when non-nil.
+	Storage *storage.S3
+	// Eraser carries out personal-data erasure requests.
+	Eraser *privacy.Eraser
+	// PIIKeys encrypts email and phone columns; nil stores them in
+	// plaintext.
+	PIIKeys *crypto.Keyring
+
+	// MaxInFlight is the number of concurrent requests beyond which new
+	// ones are shed with 503; zero disables the cap.
+	MaxInFlight int64
+
+	// RequestTimeouts defaults to DefaultRequestTimeouts when left zero.
+	RequestTimeouts RequestTimeouts
+
+	// Compression defaults to DefaultCompression when left zero.
+	Compression Compression
+
+	// MaxBodyBytes caps request bodies on every route except the Clerk
+	// webhook and avatar uploads, which use their own limits.
+	MaxBodyBytes        int64
+	WebhookMaxBodyBytes int64
+	AvatarMaxBodyBytes  int64
+}
+
+// dependencies lists the services the dependency report checks: Postgres,
+// the Clerk API, and Redis and object storage when configured.
+func dependencies(cfg Config) *DependencyChecker {
+	deps := []Dependency{
+		{Name: "postgres", Timeout: 2 * time.Second, Check: cfg.Pool.Ping},
+		{Name: "clerk", Timeout: 3 * time.Second, Check: func(ctx context.Context) error {
+			_, err := jwks.ClerkFetcher(ctx)
+			return err
+		}},
+	}
+	if cfg.Redis != nil {
+		deps = append(deps, Dependency{Name: "redis", Timeout: time.Second, Check: func(ctx context.Context) error {
+			return cfg.Redis.Ping(ctx).Err()
+		}})
+	}
+	if cfg.Storage != nil {
+		deps = append(deps, Dependency{Name: "storage", Timeout: 3 * time.Second, Check: cfg.Storage.Ping})
+	}
+	return NewDependencyChecker(deps...)
+}
+
+// Router is the assembled HTTP API.
+type Router struct {
+	*gin.Engine
+	Registry *RouteRegistry
+	// Health flips readiness off during shutdown.
+	Health *HealthHandler
+	// Settings changes the log level, rate limits, feature flags and
+	// maintenance mode at runtime.
+	Settings *Settings
+
+	// stop ends the router's background goroutines.
+	stop context.CancelFunc
+}
+
+// Close stops the router's background goroutines, such as the rate
+// limiters' cleanup tickers. Call it once the server has shut down.
+func (r *Router) Close() {
+	r.stop()
+}
+
+// NewRouter builds the engine with three route groups:
+//
+//   - public: no authentication (health, version, inbound webhooks)
+//   - authenticated: any caller with a valid Clerk session or API key
+//   - admin: authenticated callers with role admin or superadmin
+//
+// The authenticated and admin groups are the versioned API, under APIV1.
+// Routes inside a group additionally declare their required scopes via the
+// registry.
+func NewRouter(cfg Config) *Router {
+	r := gin.New()
+	trustProxies(r, cfg.TrustedProxies)
+	r.Use(requestIDMiddleware())
+	r.Use(recoveryMiddleware())
+	r.Use(reportMiddleware())
+	// Every request is a span, continuing the caller's trace when it sent a
+	// traceparent header. Probes and scrapes are left out as noise.
+	r.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !strings.HasPrefix(c.FullPath(), "/health") && c.FullPath() != "/metrics"
+	})))
+	r.Use(accessLogMiddleware(cfg.AccessLog))
+	r.Use(metricsMiddleware(cfg.HTTPMetrics))
+	r.Use(cfg.SlowRequests.middleware())
+	r.Use(corsMiddleware(cfg.CORS))
+	compression := cfg.Compression
+	if compression.MinBytes == 0 && compression.Skip == nil {
+		compression = DefaultCompression()
+	}
+	r.Use(compressMiddleware(compression))
+	rateLimits := cfg.RateLimits
+	if rateLimits.Default.Burst == 0 {
+		rateLimits = DefaultRateLimitConfig()
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	limits := newLiveRateLimits(ctx, rateLimits, cfg.Redis, cfg.RateLimitMetrics)
+	settings := newSettings(cfg.LogLevel, limits, cfg.Features, cfg.Maintenance, cfg.ReloadSettings)
+	r.Use(maintenanceMiddleware(settings))
+	r.Use(loadShedMiddleware(cfg.MaxInFlight, cfg.Pool))
+	r.Use(rateLimitMiddleware(limits, cfg.RateLimitMetrics))
+	r.Use(bodyLimitMiddleware(cfg.MaxBodyBytes, map[string]int64{
+		"/webhooks/clerk":           cfg.WebhookMaxBodyBytes,
+		APIV1 + "/users/:id/avatar": cfg.AvatarMaxBodyBytes,
+	}))
+	timeouts := cfg.RequestTimeouts
+	if timeouts.Default == 0 && timeouts.Routes == nil {
+		timeouts = DefaultRequestTimeouts()
+	}
+	r.Use(timeoutMiddleware(timeouts))
+
+	r.NoRoute(func(c *gin.Context) {
+		writeError(c, apierror.NotFound("no such route"))
+	})
+
+	rr := &RouteRegistry{}
+
+	readPool := cfg.ReadPool
+	if readPool == nil {
+		readPool = db.NewReadPool(cfg.Pool, nil)
+	}
+
+	var queue QueueListener
+	if cfg.WebhookListener != nil {
+		queue = cfg.WebhookListener
+	}
+	health := NewHealthHandler(cfg.Pool, cfg.SchemaVersion, cfg.MigrationsTable, queue)
+	outbox := webhooks.NewOutbox(cfg.Queries, cfg.PIIKeys)
+	users := NewUserHandler(cfg.Queries, db.New(readPool), cfg.Pool, outbox, cfg.PIIKeys)
+	hooks := NewWebhookHandler(cfg.Queries, cfg.WebhookWorker, cfg.WebhookMetrics, cfg.WebhookSecrets, cfg.WebhookTolerance, cfg.PIIKeys)
+	adminUsers := NewAdminUserHandler(cfg.Queries, cfg.Pool, outbox, cfg.PIIKeys)
+	userExport := NewUserExportHandler(readPool, cfg.PIIKeys)
+	privacyHandler := NewPrivacyHandler(cfg.Queries, cfg.Storage, cfg.Eraser, cfg.PIIKeys)
+	adminWebhooks := NewAdminWebhookHandler(cfg.Queries, cfg.WebhookWorker, cfg.PIIKeys)
+	adminSubs := NewAdminSubscriptionHandler(cfg.Queries)
+	audit := NewAuditHandler(cfg.Queries)
+
+	public := r.Group("")
+	rr.Handle(public, http.MethodGet, "/health", nil, health.Health)
+	rr.Handle(public, http.MethodGet, "/health/live", nil, health.Live)
+	rr.Handle(public, http.MethodGet, "/health/ready", nil, health.Ready)
+	rr.Handle(public, http.MethodGet, "/health/dependencies", nil, dependencies(cfg).Report)
+	rr.Handle(public, http.MethodGet, "/version", nil, versionHandler(health))
+	rr.Handle(public, http.MethodPost, "/webhooks/clerk", nil, hooks.Clerk)
+	if cfg.Metrics.Gatherer != nil {
+		rr.Handle(public, http.MethodGet, "/metrics", nil, metricsHandler(cfg.Metrics))
+	}
+	rr.Handle(public, http.MethodGet, "/openapi.json", nil, openAPIHandler(rr))
+	rr.Handle(public, http.MethodGet, "/docs", nil, docsHandler)
+	rr.Handle(public, http.MethodGet, "/docs/assets/:file", nil, docsAssetHandler)
+
+	api := versionGroup(r, APIV1, v1)
+	authed := api.Group("", authMiddleware(cfg.Queries, cfg.JWKS), auditMiddleware(cfg.Queries))
+	rr.Handle(authed, http.MethodGet, "/users", []Scope{ScopeUsersRead}, users.List)
+	rr.Handle(authed, http.MethodGet, "/users/:id", []Scope{ScopeUsersRead}, users.Get)
+	rr.Handle(authed, http.MethodGet, "/users/by-clerk/:clerk_id", []Scope{ScopeUsersRead}, users.Get)
+	rr.Handle(authed, http.MethodPatch, "/users/:id", nil, users.Update)
+	rr.Handle(authed, http.MethodGet, "/users/:id/preferences", nil, users.GetPreferences)
+	rr.Handle(authed, http.MethodGet, "/users/:id/activity", []Scope{ScopeUsersRead}, users.Activity)
+	rr.Handle(authed, http.MethodGet, "/users/:id/data-export", nil, privacyHandler.Export)
+	rr.Handle(authed, http.MethodDelete, "/users/:id/personal-data", nil, privacyHandler.Erase)
+	rr.Handle(authed, http.MethodPut, "/users/:id/preferences", nil, users.PutPreferences)
+
+	if cfg.Storage != nil {
+		avatars := NewAvatarHandler(cfg.Queries, cfg.Storage)
+		rr.Handle(authed, http.MethodPost, "/users/:id/avatar", nil, avatars.Upload)
+		rr.Handle(authed, http.MethodGet, "/users/:id/avatar", []Scope{ScopeUsersRead}, avatars.Get)
+	}
+
+	me := api.Group("", provisioningAuthMiddleware(cfg.Queries, cfg.JWKS, cfg.PIIKeys))
+	rr.Handle(me, http.MethodGet, "/me", nil, users.Me)
+
+	// Admin routes are gated by their scopes, not by role, so API keys with
+	// the right scopes can call them; every route here must declare one.
+	// Audited before the scope check, so denied attempts are recorded too.
+	admin := api.Group("/admin", authMiddleware(cfg.Queries, cfg.JWKS), auditMiddleware(cfg.Queries))
+	rr.Handle(admin, http.MethodGet, "/routes", []Scope{ScopeOpsRead}, func(c *gin.Context) {
+		c.JSON(http.StatusOK, rr.Routes())
+	})
+	rr.Handle(admin, http.MethodPost, "/users", []Scope{ScopeUsersWrite}, adminUsers.Create)
+	rr.Handle(admin, http.MethodGet, "/users/export", []Scope{ScopeUsersRead}, userExport.Export)
+	rr.Handle(admin, http.MethodPost, "/users/:id/ban", []Scope{ScopeUsersWrite}, adminUsers.Ban)
+	rr.Handle(admin, http.MethodPost, "/users/:id/unban", []Scope{ScopeUsersWrite}, adminUsers.Unban)
+	rr.Handle(admin, http.MethodPost, "/users/:id/restore", []Scope{ScopeUsersWrite}, adminUsers.Restore)
+	rr.Handle(admin, http.MethodPost, "/users/:id/merge", []Scope{ScopeUsersWrite}, adminUsers.Merge)
+	rr.Handle(admin, http.MethodGet, "/webhooks", []Scope{ScopeWebhooksReplay}, adminWebhooks.List)
+	rr.Handle(admin, http.MethodGet, "/webhooks/:id", []Scope{ScopeWebhooksReplay}, adminWebhooks.Get)
+	rr.Handle(admin, http.MethodPost, "/webhooks/:id/replay", []Scope{ScopeWebhooksReplay}, adminWebhooks.Replay)
+	rr.Handle(admin, http.MethodGet, "/webhook-subscriptions", []Scope{ScopeWebhooksManage}, adminSubs.List)
+	rr.Handle(admin, http.MethodPost, "/webhook-subscriptions", []Scope{ScopeWebhooksManage}, adminSubs.Create)
+	rr.Handle(admin, http.MethodGet, "/webhook-subscriptions/:id", []Scope{ScopeWebhooksManage}, adminSubs.Get)
+	rr.Handle(admin, http.MethodPatch, "/webhook-subscriptions/:id", []Scope{ScopeWebhooksManage}, adminSubs.Update)
+	rr.Handle(admin, http.MethodDelete, "/webhook-subscriptions/:id", []Scope{ScopeWebhooksManage}, adminSubs.Delete)
+	rr.Handle(admin, http.MethodGet, "/audit-log", []Scope{ScopeAuditRead}, audit.List)
+	rr.Handle(admin, http.MethodGet, "/slow-requests", []Scope{ScopeOpsRead}, cfg.SlowRequests.List)
+	rr.Handle(admin, http.MethodGet, "/settings", []Scope{ScopeSettingsManage}, settings.Get)
+	rr.Handle(admin, http.MethodPatch, "/settings", []Scope{ScopeSettingsManage}, settings.Patch)
+	rr.Handle(admin, http.MethodPost, "/settings/reload", []Scope{ScopeSettingsManage}, settings.ReloadHandler)
+
+	registerDebugRoutes(r, rr, authMiddleware(cfg.Queries, cfg.JWKS), RequireRole("admin", "superadmin"))
+
+	// Internal endpoints for sibling services, authenticated by HMAC
+	// signature instead of user credentials.
+	if len(cfg.InternalSigningSecret) > 0 {
+		internal := r.Group("/internal", internalSignatureMiddleware(cfg.InternalSigningSecret))
+		rr.Handle(internal, http.MethodGet, "/users", nil, users.List)
+	}
+
+	warnUnknownRoutes(r, "RATE_LIMIT_ROUTES", rateLimits.Routes, DefaultRateLimitConfig().Routes)
+	warnUnknownRoutes(r, "REQUEST_TIMEOUT_ROUTES", timeouts.Routes, DefaultRequestTimeouts().Routes)
+	warnUnknownRoutes(r, "COMPRESSION_SKIP_ROUTES", compression.Skip, DefaultCompression().Skip)
+
+	return &Router{Engine: r, Registry: rr, Health: health, Settings: settings, stop: stop}
+}
+
+// warnUnknownRoutes logs the keys of a per-route setting that name no
+// registered route and so never apply, typically a path missing its /api/v1
+// prefix or a typo. Built-in defaults are skipped, as some of them name
+// routes that are only registered when their feature is enabled.
+func warnUnknownRoutes[V any](r *gin.Engine, setting string, routes map[string]V, defaults map[string]V) {
+	known := make(map[string]bool)
+	for _, route := range r.Routes() {
+		known[route.Path] = true
+	}
+	for path := range routes {
+		if _, ok := defaults[path]; ok || known[path] {
+			continue
+		}
+		slog.Warn("router: route override matches no route", "setting", setting, "route", path)
+	}
+}
+
+// trustProxies makes r take the client IP from X-Forwarded-For and X-Real-IP
+// only on requests whose peer is in prefixes.
+func trustProxies(r *gin.Engine, prefixes []netip.Prefix) {
+	proxies := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		proxies[i] = p.String()
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		panic(err)
+	}
+}
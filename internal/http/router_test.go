@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// proxyTestEngine serves GET /ip, which echoes c.ClientIP(), behind the
+// rate limiter, taking forwarding headers only from trusted.
+func proxyTestEngine(t *testing.T, trusted []netip.Prefix, limits RateLimitConfig) *gin.Engine {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	r := gin.New()
+	trustProxies(r, trusted)
+	r.Use(rateLimitMiddleware(newLiveRateLimits(ctx, limits, nil, nil), nil))
+	r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+	return r
+}
+
+func getIP(r http.Handler, peer string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = peer
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// oneRequestLimits allows each client a single request per hour.
+func oneRequestLimits(exempt ...netip.Prefix) RateLimitConfig {
+	cfg := DefaultRateLimitConfig()
+	cfg.Default = RateLimitPolicy{Rate: 1.0 / 3600, Burst: 1}
+	cfg.Routes = nil
+	cfg.Exempt = RateLimitExemptions{Prefixes: exempt}
+	return cfg
+}
+
+func TestClientIPIgnoresForwardingHeadersFromUntrustedPeers(t *testing.T) {
+	spoofed := map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}
+	tests := []struct {
+		name    string
+		trusted []netip.Prefix
+		peer    string
+		header  map[string]string
+		want    string
+	}{
+		{"untrusted peer, X-Forwarded-For", DefaultTrustedProxies(), "203.0.113.9:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.9"},
+		{"untrusted peer, X-Real-IP", DefaultTrustedProxies(), "203.0.113.9:4000", map[string]string{"X-Real-IP": "198.51.100.2"}, "203.0.113.9"},
+		{"untrusted peer, both", DefaultTrustedProxies(), "203.0.113.9:4000", spoofed, "203.0.113.9"},
+		{"no trusted proxies", nil, "10.0.0.5:4000", spoofed, "10.0.0.5"},
+		{"trusted proxy, X-Forwarded-For", DefaultTrustedProxies(), "10.0.0.5:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy, X-Real-IP", DefaultTrustedProxies(), "10.0.0.5:4000", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2"},
+		{"configured proxy", []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")}, "203.0.113.9:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := proxyTestEngine(t, tt.trusted, oneRequestLimits())
+			w := getIP(r, tt.peer, tt.header)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeyIgnoresSpoofedHeaders(t *testing.T) {
+	r := proxyTestEngine(t, DefaultTrustedProxies(), oneRequestLimits())
+
+	if w := getIP(r, "203.0.113.9:4000", nil); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", w.Code)
+	}
+	// A fresh X-Forwarded-For per request must not buy a fresh bucket.
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		w := getIP(r, "203.0.113.9:4000", map[string]string{"X-Forwarded-For": ip, "X-Real-IP": ip})
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("spoofing %s: status = %d, want 429", ip, w.Code)
+		}
+	}
+
+	// Behind a trusted proxy each forwarded client has its own bucket.
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2"} {
+		if w := getIP(r, "10.0.0.5:4000", map[string]string{"X-Forwarded-For": ip}); w.Code != http.StatusOK {
+			t.Errorf("forwarded %s: status = %d, want 200", ip, w.Code)
+		}
+	}
+}
+
+func TestSvixExemptionRequiresTrustedPeer(t *testing.T) {
+	svix := SvixIPRanges[0].Addr().String()
+	r := proxyTestEngine(t, DefaultTrustedProxies(), oneRequestLimits(SvixIPRanges...))
+
+	// Claiming a Svix address from the internet is not an exemption.
+	codes := make([]int, 3)
+	for i := range codes {
+		codes[i] = getIP(r, "203.0.113.9:4000", map[string]string{"X-Forwarded-For": svix, "X-Real-IP": svix}).Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("spoofed Svix address: statuses = %v, want [200 429 429]", codes)
+	}
+
+	// Svix forwarded by a trusted proxy, or connecting directly, is exempt.
+	for i := 0; i < 3; i++ {
+		if w := getIP(r, "10.0.0.5:4000", map[string]string{"X-Forwarded-For": svix}); w.Code != http.StatusOK {
+			t.Errorf("forwarded Svix request %d: status = %d, want 200", i, w.Code)
+		}
+		if w := getIP(r, svix+":4000", nil); w.Code != http.StatusOK {
+			t.Errorf("direct Svix request %d: status = %d, want 200", i, w.Code)
+		}
+	}
+}
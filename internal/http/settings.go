@@ -0,0 +1,227 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeSettings are the settings that take effect without a restart,
+// either on SIGHUP or through POST /admin/settings/reload.
+type RuntimeSettings struct {
+	LogLevel   slog.Level
+	RateLimits RateLimitConfig
+	// Features are the names of the enabled feature flags.
+	Features    []string
+	Maintenance Maintenance
+}
+
+// Settings holds the live RuntimeSettings. Everything else in Config is
+// read once at startup.
+type Settings struct {
+	// mu serialises changes; reads go through the atomics without it.
+	mu          sync.Mutex
+	logLevel    *slog.LevelVar
+	limits      *liveRateLimits
+	features    atomic.Pointer[map[string]bool]
+	maintenance atomic.Pointer[Maintenance]
+	reload      func() (RuntimeSettings, error)
+}
+
+func newSettings(logLevel *slog.LevelVar, limits *liveRateLimits, features []string, maintenance Maintenance, reload func() (RuntimeSettings, error)) *Settings {
+	if logLevel == nil {
+		logLevel = new(slog.LevelVar)
+	}
+	s := &Settings{logLevel: logLevel, limits: limits, reload: reload}
+	s.setFeatures(features)
+	s.maintenance.Store(&maintenance)
+	return s
+}
+
+// Enabled reports whether the feature flag name is on.
+func (s *Settings) Enabled(name string) bool {
+	return (*s.features.Load())[name]
+}
+
+func (s *Settings) setFeatures(names []string) {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	s.features.Store(&m)
+}
+
+// Current returns the settings in effect.
+func (s *Settings) Current() RuntimeSettings {
+	var features []string
+	for name, on := range *s.features.Load() {
+		if on {
+			features = append(features, name)
+		}
+	}
+	slices.Sort(features)
+	return RuntimeSettings{
+		LogLevel:    s.logLevel.Level(),
+		RateLimits:  s.limits.config(),
+		Features:    features,
+		Maintenance: *s.maintenance.Load(),
+	}
+}
+
+// Apply puts rs into effect. Rate limits are only rebuilt when they
+// changed, since that resets every in-memory bucket.
+func (s *Settings) Apply(ctx context.Context, rs RuntimeSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logLevel.Set(rs.LogLevel)
+	if rs.RateLimits.String() != s.limits.config().String() {
+		s.limits.set(rs.RateLimits)
+	}
+	s.setFeatures(rs.Features)
+	if old := s.maintenance.Swap(&rs.Maintenance); old.Enabled != rs.Maintenance.Enabled {
+		if rs.Maintenance.Enabled {
+			slog.WarnContext(ctx, "settings: maintenance mode on")
+		} else {
+			slog.WarnContext(ctx, "settings: maintenance mode off")
+		}
+	}
+	slog.InfoContext(ctx, "settings: applied",
+		"log_level", rs.LogLevel.String(),
+		"rate_limits", rs.RateLimits.String(),
+		"features", rs.Features,
+		"maintenance", rs.Maintenance.Enabled)
+}
+
+var errReloadUnsupported = errors.New("settings: reloading is not configured")
+
+// Reload re-reads the configuration and applies it. On error the current
+// settings stay in effect.
+func (s *Settings) Reload(ctx context.Context) error {
+	if s.reload == nil {
+		return errReloadUnsupported
+	}
+	rs, err := s.reload()
+	if err != nil {
+		return err
+	}
+	s.Apply(ctx, rs)
+	return nil
+}
+
+type maintenanceJSON struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+type settingsResponse struct {
+	LogLevel    string          `json:"log_level"`
+	RateLimits  string          `json:"rate_limits"`
+	Features    []string        `json:"features"`
+	Maintenance maintenanceJSON `json:"maintenance"`
+}
+
+func (s *Settings) response() settingsResponse {
+	rs := s.Current()
+	features := rs.Features
+	if features == nil {
+		features = []string{}
+	}
+	return settingsResponse{
+		LogLevel:   rs.LogLevel.String(),
+		RateLimits: rs.RateLimits.String(),
+		Features:   features,
+		Maintenance: maintenanceJSON{
+			Enabled:           rs.Maintenance.Enabled,
+			Message:           rs.Maintenance.Message,
+			RetryAfterSeconds: ceilSeconds(rs.Maintenance.RetryAfter),
+		},
+	}
+}
+
+// Get returns the settings in effect.
+func (s *Settings) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, s.response())
+}
+
+type patchSettingsRequest struct {
+	LogLevel    *string         `json:"log_level"`
+	Features    map[string]bool `json:"features"`
+	Maintenance *struct {
+		Enabled           *bool   `json:"enabled"`
+		Message           *string `json:"message"`
+		RetryAfterSeconds *int    `json:"retry_after_seconds" validate:"omitnil,min=1"`
+	} `json:"maintenance"`
+}
+
+// Patch changes the log level, switches feature flags on and off or
+// enters and leaves maintenance mode. The change lasts until the next
+// reload or restart; rate limits change only through the configuration.
+func (s *Settings) Patch(c *gin.Context) {
+	var req patchSettingsRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+	rs := s.Current()
+	if req.LogLevel != nil {
+		if err := rs.LogLevel.UnmarshalText([]byte(*req.LogLevel)); err != nil {
+			writeError(c, apierror.Invalid("log_level", "log_level must be debug, info, warn or error"))
+			return
+		}
+	}
+	if len(req.Features) > 0 {
+		enabled := make(map[string]bool, len(rs.Features))
+		for _, name := range rs.Features {
+			enabled[name] = true
+		}
+		for name, on := range req.Features {
+			enabled[name] = on
+		}
+		rs.Features = rs.Features[:0]
+		for name, on := range enabled {
+			if on {
+				rs.Features = append(rs.Features, name)
+			}
+		}
+		slices.Sort(rs.Features)
+	}
+	if m := req.Maintenance; m != nil {
+		if m.Enabled != nil {
+			rs.Maintenance.Enabled = *m.Enabled
+		}
+		if m.Message != nil {
+			rs.Maintenance.Message = *m.Message
+		}
+		if m.RetryAfterSeconds != nil {
+			rs.Maintenance.RetryAfter = time.Duration(*m.RetryAfterSeconds) * time.Second
+		}
+	}
+	s.Apply(c.Request.Context(), rs)
+	c.JSON(http.StatusOK, s.response())
+}
+
+// ReloadHandler re-reads the configuration, as SIGHUP does.
+func (s *Settings) ReloadHandler(c *gin.Context) {
+	if err := s.Reload(c.Request.Context()); err != nil {
+		if errors.Is(err, errReloadUnsupported) {
+			writeError(c, apierror.New(http.StatusNotImplemented, apierror.CodeNotImplemented, "reloading is not configured"))
+			return
+		}
+		// The problems can quote configured values, so they stay in the log.
+		slog.ErrorContext(c.Request.Context(), "settings: reload failed", "error", err)
+		writeError(c, apierror.Internal(err, "reloading settings failed; the current settings remain in effect"))
+		return
+	}
+	c.JSON(http.StatusOK, s.response())
+}
@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// slowRequestsKept is how many recent slow requests the admin endpoint
+// lists.
+const slowRequestsKept = 100
+
+// slowRequestExempt are routes that are slow by design: streamed exports.
+// /debug routes are exempt too, since profiles run for seconds.
+var slowRequestExempt = map[string]bool{
+	APIV1 + "/admin/users/export":    true,
+	APIV1 + "/users/:id/data-export": true,
+}
+
+type slowRequest struct {
+	At        time.Time `json:"at"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	UserID    string    `json:"user_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+}
+
+// SlowRequests logs a warning for every request slower than its threshold
+// and keeps the most recent ones for GET /admin/slow-requests. A nil
+// *SlowRequests records nothing.
+type SlowRequests struct {
+	threshold time.Duration
+
+	mu     sync.Mutex
+	recent []slowRequest // ring buffer, next is the oldest once full
+	next   int
+}
+
+// NewSlowRequests returns nil when threshold is zero, disabling detection.
+func NewSlowRequests(threshold time.Duration) *SlowRequests {
+	if threshold <= 0 {
+		return nil
+	}
+	return &SlowRequests{threshold: threshold, recent: make([]slowRequest, 0, slowRequestsKept)}
+}
+
+func (s *SlowRequests) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s == nil {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		elapsed := time.Since(start)
+		route := c.FullPath()
+		if elapsed < s.threshold || slowRequestExempt[route] || strings.HasPrefix(route, "/debug/") {
+			return
+		}
+		ctx := c.Request.Context()
+		r := slowRequest{
+			At:        start,
+			Method:    c.Request.Method,
+			Route:     route,
+			Status:    c.Writer.Status(),
+			LatencyMS: float64(elapsed.Microseconds()) / 1000,
+			UserID:    callerID(c),
+			RequestID: requestid.FromContext(ctx),
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			r.TraceID = sc.TraceID().String()
+		}
+		slog.WarnContext(ctx, "slow request",
+			"method", r.Method, "route", r.Route, "status", r.Status,
+			"latency_ms", r.LatencyMS, "threshold_ms", s.threshold.Milliseconds(), "user_id", r.UserID)
+		s.add(r)
+	}
+}
+
+func (s *SlowRequests) add(r slowRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.recent) < slowRequestsKept {
+		s.recent = append(s.recent, r)
+		return
+	}
+	s.recent[s.next] = r
+	s.next = (s.next + 1) % slowRequestsKept
+}
+
+// List returns the recent slow requests, newest first.
+func (s *SlowRequests) List(c *gin.Context) {
+	if s == nil {
+		c.JSON(http.StatusOK, gin.H{"threshold_ms": 0, "requests": []slowRequest{}})
+		return
+	}
+	s.mu.Lock()
+	out := make([]slowRequest, 0, len(s.recent))
+	for i := range s.recent {
+		// Walk backwards from the newest entry.
+		out = append(out, s.recent[(s.next-1-i+2*len(s.recent))%len(s.recent)])
+	}
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"threshold_ms": s.threshold.Milliseconds(), "requests": out})
+}
@@ -0,0 +1,29 @@
+#!/bin/sh
+# fetch.sh vendors the Swagger UI files /docs serves from the swagger-ui-dist
+# npm package at the version in VERSION. The tarball is checked against the
+# sha512 integrity the npm registry publishes for that version before
+# anything is extracted. Run it through `go generate ./internal/http` and
+# commit the result.
+set -eu
+cd "$(dirname "$0")"
+
+version=$(cat VERSION)
+meta=$(curl -fsSL "https://registry.npmjs.org/swagger-ui-dist/$version")
+tarball=$(printf '%s' "$meta" | sed -n 's/.*"tarball":"\([^"]*\)".*/\1/p')
+integrity=$(printf '%s' "$meta" | sed -n 's/.*"integrity":"sha512-\([^"]*\)".*/\1/p')
+if [ -z "$tarball" ] || [ -z "$integrity" ]; then
+	echo "fetch.sh: no tarball or integrity for swagger-ui-dist@$version" >&2
+	exit 1
+fi
+
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+curl -fsSL -o "$tmp/package.tgz" "$tarball"
+got=$(openssl dgst -sha512 -binary "$tmp/package.tgz" | openssl base64 -A)
+if [ "$got" != "$integrity" ]; then
+	echo "fetch.sh: swagger-ui-dist@$version does not match its published integrity" >&2
+	exit 1
+fi
+
+tar -xzf "$tmp/package.tgz" -C "$tmp" package/swagger-ui.css package/swagger-ui-bundle.js
+cp "$tmp/package/swagger-ui.css" "$tmp/package/swagger-ui-bundle.js" .
@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeouts bounds how long a handler may run, by default and per
+// route pattern (gin's FullPath). A zero duration leaves the route
+// unbounded.
+type RequestTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// DefaultRequestTimeouts is used unless the REQUEST_TIMEOUT settings
+// override it. The exports stream for as long as they take and extend
+// their own write deadline instead.
+func DefaultRequestTimeouts() RequestTimeouts {
+	return RequestTimeouts{
+		Default: 30 * time.Second,
+		Routes: map[string]time.Duration{
+			APIV1 + "/admin/users/export":    0,
+			APIV1 + "/users/:id/data-export": 0,
+		},
+	}
+}
+
+// timeoutMiddleware gives the request context a deadline. Queries run with
+// that context are cancelled when it passes, as they are when the client
+// disconnects, and a handler that then answers with a 5xx is answered with
+// 504 instead. Profiling under /debug is left unbounded.
+func timeoutMiddleware(timeouts RequestTimeouts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := timeouts.Routes[c.FullPath()]
+		if !ok {
+			d = timeouts.Default
+		}
+		if d <= 0 || strings.HasPrefix(c.FullPath(), "/debug/") {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		w := &timeoutWriter{ResponseWriter: c.Writer, c: c, ctx: ctx}
+		c.Writer = w
+
+		c.Next()
+
+		if !w.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			writeError(c, ctx.Err())
+		}
+	}
+}
+
+// timeoutWriter turns a 5xx written after the deadline into a 504, since
+// the error the handler saw was almost certainly the cancelled context.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	ctx      context.Context
+	timedOut bool
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code >= 500 && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write replaces the handler's error body with the timeout error.
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.timedOut {
+		return w.ResponseWriter.Write(b)
+	}
+	if !w.Written() {
+		body, err := json.Marshal(versionOf(w.c).errorBody(apierror.From(w.ctx.Err()), w.c.GetString("request_id")))
+		if err != nil {
+			return 0, err
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if _, err := w.ResponseWriter.Write(body); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
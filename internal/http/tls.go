@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig serves the API over HTTPS directly, for deployments without a
+// terminating proxy in front: with the certificate in CertFile and KeyFile,
+// or with certificates Let's Encrypt issues for AutocertDomains. The zero
+// value serves plain HTTP.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	AutocertDomains []string
+	// AutocertCacheDir keeps issued certificates across restarts, which
+	// would otherwise each request new ones and soon hit Let's Encrypt's
+	// rate limits.
+	AutocertCacheDir string
+	// AutocertEmail is given to Let's Encrypt for expiry notices.
+	AutocertEmail string
+	// ChallengeAddr answers ACME HTTP-01 challenges and redirects every
+	// other plain HTTP request to HTTPS.
+	ChallengeAddr string
+}
+
+// Enabled reports whether the API is served over TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// Configure sets up srv to serve TLS; start it with ListenAndServeTLS("",
+// ""). With autocert it also returns the server for ChallengeAddr, to be
+// run alongside srv. The certificate files are read once, so a renewed
+// certificate takes effect on restart.
+func (t TLSConfig) Configure(srv *http.Server) (challenge *http.Server, err error) {
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig.Certificates = []tls.Certificate{cert}
+		return nil, nil
+	}
+	if len(t.AutocertDomains) == 0 {
+		return nil, errors.New("tls: no certificate or autocert domains configured")
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(t.AutocertDomains...),
+		Email:      t.AutocertEmail,
+	}
+	if t.AutocertCacheDir != "" {
+		m.Cache = autocert.DirCache(t.AutocertCacheDir)
+	}
+	srv.TLSConfig = m.TLSConfig()
+	srv.TLSConfig.MinVersion = tls.VersionTLS12
+
+	return &http.Server{
+		Addr:              t.ChallengeAddr,
+		Handler:           m.HTTPHandler(nil),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}, nil
+}
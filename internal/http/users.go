@@ -0,0 +1,463 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/apierror"
+	"backend/internal/crypto"
+	"backend/internal/db"
+	"backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// User is kept in sync with db.ListUsersRow as an API contract reference.
+// The /users handlers serialize the sqlc rows directly; this struct is not
+// used for actual responses.
+type User struct {
+	ClerkID     string `json:"clerk_id,omitempty"`
+	Name        string `json:"name"`
+	FirstName   string `json:"first_name,omitempty"`
+	LastName    string `json:"last_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Role        string `json:"role"`
+	IsActive    bool   `json:"is_active"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+	DeletedAt   string `json:"deleted_at,omitempty"`
+	LastLoginAt string `json:"last_login_at,omitempty"`
+	BannedAt    string `json:"banned_at,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	Version     int64  `json:"version"`
+}
+
+// UserHandler serves the user directory endpoints. readQ serves the
+// read-only listing queries, which tolerate replica lag. pii decrypts email
+// and phone for responses and encrypts phone on update.
+type UserHandler struct {
+	q      *db.Queries
+	readQ  *db.Queries
+	pool   *pgxpool.Pool
+	outbox *webhooks.Outbox
+	pii    *crypto.Keyring
+}
+
+func NewUserHandler(q, readQ *db.Queries, pool *pgxpool.Pool, outbox *webhooks.Outbox, pii *crypto.Keyring) *UserHandler {
+	return &UserHandler{q: q, readQ: readQ, pool: pool, outbox: outbox, pii: pii}
+}
+
+// Me returns the caller's own user row. It needs a user token; API keys do
+// not belong to a user.
+func (h *UserHandler) Me(c *gin.Context) {
+	clerkID := c.GetString("clerk_id")
+	if clerkID == "" {
+		writeError(c, apierror.Forbidden("/me requires a user session"))
+		return
+	}
+
+	user, err := h.q.GetUserByClerkID(c.Request.Context(), clerkID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	if err == nil {
+		err = revealPII(h.pii, &user.Email, &user.Phone)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve user"))
+		return
+	}
+	c.Header("ETag", versionETag(user.Version))
+	c.JSON(http.StatusOK, user)
+}
+
+// usersETag derives a weak ETag from the table fingerprint and the query
+// string, since filters and pagination change the body too.
+func usersETag(fp db.UsersFingerprintRow, rawQuery string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s", fp.ActiveCount, fp.LastUpdatedAt.Time.UnixMicro(), rawQuery)))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// versionETag is the strong ETag of a single user: its row version, which
+// Update accepts back in If-Match.
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch extracts the version from an If-Match header set to a
+// versionETag. ok is false when the header is absent or "*".
+func parseIfMatch(header string) (version int64, ok bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	version, err = strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || strings.HasPrefix(header, "W/") {
+		return 0, false, apierror.BadRequest("If-Match must be the ETag returned for the user")
+	}
+	return version, true, nil
+}
+
+// etagMatches implements the weak comparison of If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns active users newest first, paginated with ?limit= and either
+// ?cursor= (from next_cursor) or ?offset=, and narrowed by the filters in
+// parseUserFilters. With ?org_id= it returns all members of that
+// organization instead. Admins may add ?include_deleted=true to see
+// soft-deleted users as well. Listing reads from the replica when one is
+// configured.
+func (h *UserHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if orgID := c.Query("org_id"); orgID != "" {
+		users, err := h.readQ.ListUsersByOrganization(ctx, orgID)
+		for i := 0; err == nil && i < len(users); i++ {
+			err = revealPII(h.pii, &users[i].Email)
+		}
+		if err != nil {
+			writeError(c, apierror.Internal(err, "failed to retrieve users"))
+			return
+		}
+		c.JSON(http.StatusOK, wholePage(users))
+		return
+	}
+
+	includeDeleted, ok := includeDeletedParam(c)
+	if !ok {
+		return
+	}
+
+	// Polling clients get a 304 while nothing changed. Membership changes
+	// do not touch users, so the org_id listing above is not cached.
+	fp, err := h.readQ.UsersFingerprint(ctx)
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve users"))
+		return
+	}
+	etag := usersETag(fp, c.Request.URL.RawQuery)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			writeError(c, apierror.Invalid("limit", "limit must be between 1 and 200"))
+			return
+		}
+		limit = n
+	}
+
+	filters, err := parseUserFilters(c, h.pii)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	params := db.ListUsersPageParams{
+		Q:             filters.Q,
+		QBidx:         filters.QBidx,
+		HasEmail:      filters.HasEmail,
+		CreatedAfter:  filters.CreatedAfter,
+		CreatedBefore: filters.CreatedBefore,
+		RowLimit:      int32(limit),
+	}
+	if v := c.Query("cursor"); v != "" {
+		createdAt, clerkID, err := decodeCursor(v)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		params.CursorCreatedAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		params.CursorClerkID = pgtype.Text{String: clerkID, Valid: true}
+	} else if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(c, apierror.Invalid("offset", "offset must be a non-negative integer"))
+			return
+		}
+		params.RowOffset = int32(n)
+	}
+
+	users, total, err := h.listPage(ctx, params, filters, includeDeleted)
+	for i := 0; err == nil && i < len(users); i++ {
+		err = revealPII(h.pii, &users[i].Email, &users[i].Phone)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve users"))
+		return
+	}
+
+	c.JSON(http.StatusOK, newPage(users, total, limit, c.Query("cursor"), func(last db.ListUsersPageRow) string {
+		return encodeCursor(last.CreatedAt.Time, last.ClerkID)
+	}))
+}
+
+// parseUserFilters reads the /users search filters: ?q= (substring of name,
+// email or username), ?has_email=, ?created_after= and ?created_before=
+// (RFC 3339). Encrypted emails only match a q that is the whole address,
+// through its blind index.
+func parseUserFilters(c *gin.Context, pii *crypto.Keyring) (db.CountUsersParams, error) {
+	var f db.CountUsersParams
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		f.Q = pgtype.Text{String: likeEscaper.Replace(q), Valid: true}
+		f.QBidx = pii.BlindIndexText(pgtype.Text{String: q, Valid: true})
+	}
+	if v := c.Query("has_email"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, apierror.Invalid("has_email", "has_email must be true or false")
+		}
+		f.HasEmail = pgtype.Bool{Bool: b, Valid: true}
+	}
+	for name, dst := range map[string]*pgtype.Timestamptz{
+		"created_after":  &f.CreatedAfter,
+		"created_before": &f.CreatedBefore,
+	} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, apierror.Invalid(name, name+" must be an RFC 3339 timestamp")
+			}
+			*dst = pgtype.Timestamptz{Time: t, Valid: true}
+		}
+	}
+	return f, nil
+}
+
+// includeDeletedParam parses ?include_deleted=, which only admins may set.
+// ok is false when a response was already written.
+func includeDeletedParam(c *gin.Context) (include, ok bool) {
+	v := c.Query("include_deleted")
+	if v == "" || v == "false" {
+		return false, true
+	}
+	if v != "true" {
+		writeError(c, apierror.Invalid("include_deleted", "include_deleted must be true or false"))
+		return false, false
+	}
+	if role := c.GetString("role"); role != "admin" && role != "superadmin" {
+		writeError(c, apierror.Forbidden("include_deleted requires an admin"))
+		return false, false
+	}
+	return true, true
+}
+
+// listPage runs ListUsersPage and CountUsers, or their IncludingDeleted
+// twins.
+func (h *UserHandler) listPage(ctx context.Context, params db.ListUsersPageParams, filters db.CountUsersParams, includeDeleted bool) ([]db.ListUsersPageRow, int64, error) {
+	if !includeDeleted {
+		users, err := h.readQ.ListUsersPage(ctx, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		total, err := h.readQ.CountUsers(ctx, filters)
+		return users, total, err
+	}
+
+	rows, err := h.readQ.ListUsersPageIncludingDeleted(ctx, db.ListUsersPageIncludingDeletedParams(params))
+	if err != nil {
+		return nil, 0, err
+	}
+	users := make([]db.ListUsersPageRow, len(rows))
+	for i, r := range rows {
+		users[i] = db.ListUsersPageRow(r)
+	}
+	total, err := h.readQ.CountUsersIncludingDeleted(ctx, db.CountUsersIncludingDeletedParams(filters))
+	return users, total, err
+}
+
+// likeEscaper escapes LIKE wildcards so ?q= is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Get returns one active user, or a soft-deleted one too for admins passing
+// ?include_deleted=true. Users are keyed by their Clerk ID, so /users/:id
+// and /users/by-clerk/:clerk_id resolve the same row.
+func (h *UserHandler) Get(c *gin.Context) {
+	clerkID := c.Param("id")
+	if clerkID == "" {
+		clerkID = c.Param("clerk_id")
+	}
+	includeDeleted, ok := includeDeletedParam(c)
+	if !ok {
+		return
+	}
+
+	var user db.GetUserByClerkIDRow
+	var err error
+	if includeDeleted {
+		var row db.GetUserByClerkIDIncludingDeletedRow
+		row, err = h.q.GetUserByClerkIDIncludingDeleted(c.Request.Context(), clerkID)
+		user = db.GetUserByClerkIDRow(row)
+	} else {
+		user, err = h.q.GetUserByClerkID(c.Request.Context(), clerkID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	if err == nil {
+		err = revealPII(h.pii, &user.Email, &user.Phone)
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to retrieve user"))
+		return
+	}
+	c.Header("ETag", versionETag(user.Version))
+	c.JSON(http.StatusOK, user)
+}
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+	localePattern   = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	phonePattern    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// updateUserRequest holds the mutable profile fields. Omitted fields are left
+// unchanged; an empty username, locale or phone clears it. Version, like an
+// If-Match header, makes the update conditional on the row being unchanged.
+type updateUserRequest struct {
+	Name     *string `json:"name" validate:"omitnil,min=1,max=200"`
+	Username *string `json:"username" validate:"omitnil,omitempty,username"`
+	Locale   *string `json:"locale" validate:"omitnil,omitempty,locale"`
+	Phone    *string `json:"phone" validate:"omitnil,omitempty,phone"`
+	// Version is the version being edited, for clients that cannot send
+	// If-Match.
+	Version *int64 `json:"version"`
+}
+
+func (r *updateUserRequest) normalize() {
+	for _, v := range []*string{r.Name, r.Username, r.Locale, r.Phone} {
+		if v != nil {
+			*v = strings.TrimSpace(*v)
+		}
+	}
+}
+
+// Update applies a partial profile update. Users may edit themselves; editing
+// anyone else requires the users:write scope. The caller must say which
+// version it edited, as If-Match with the user's ETag or as "version" in the
+// body, so a concurrent change is a 409 instead of being overwritten;
+// If-Match: * explicitly overwrites whatever is current. Note that name and
+// username are overwritten again by the next user.updated webhook from
+// Clerk.
+func (h *UserHandler) Update(c *gin.Context) {
+	clerkID := c.Param("id")
+	if !canEditUser(c, clerkID) {
+		writeError(c, missingScope(ScopeUsersWrite))
+		return
+	}
+
+	var req updateUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	params := db.UpdateUserProfileParams{ClerkID: clerkID}
+	ifMatch := c.GetHeader("If-Match")
+	if v, ok, err := parseIfMatch(ifMatch); err != nil {
+		writeError(c, err)
+		return
+	} else if ok {
+		params.ExpectedVersion = pgtype.Int8{Int64: v, Valid: true}
+	} else if req.Version != nil {
+		params.ExpectedVersion = pgtype.Int8{Int64: *req.Version, Valid: true}
+	} else if strings.TrimSpace(ifMatch) == "" {
+		writeError(c, apierror.New(http.StatusPreconditionRequired, apierror.CodePreconditionRequired,
+			"send If-Match with the user's ETag, or the version being edited"))
+		return
+	}
+	for _, f := range []struct {
+		value *string
+		dst   *pgtype.Text
+	}{
+		{req.Name, &params.Name},
+		{req.Username, &params.Username},
+		{req.Locale, &params.Locale},
+		{req.Phone, &params.Phone},
+	} {
+		if f.value != nil {
+			*f.dst = pgtype.Text{String: *f.value, Valid: true}
+		}
+	}
+	var err error
+	if params.Phone, err = h.pii.EncryptText(params.Phone); err != nil {
+		writeError(c, apierror.Internal(err, "failed to update user"))
+		return
+	}
+
+	var user db.UpdateUserProfileRow
+	err = db.WithTx(c.Request.Context(), h.pool, func(q *db.Queries) error {
+		var err error
+		if user, err = q.UpdateUserProfile(c.Request.Context(), params); err != nil {
+			return err
+		}
+		return announceUser(c.Request.Context(), q, h.outbox, h.pii, "user.updated", clerkID)
+	})
+	if err == nil {
+		err = revealPII(h.pii, &user.Email, &user.Phone)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// With an expected version, no row means either a missing user or
+		// a stale version; the current row tells which.
+		if params.ExpectedVersion.Valid {
+			if current, gerr := h.q.GetUserByClerkID(c.Request.Context(), clerkID); gerr == nil {
+				c.Header("ETag", versionETag(current.Version))
+				writeError(c, apierror.Conflict("user was modified concurrently").With("version", current.Version))
+				return
+			}
+		}
+		writeError(c, apierror.NotFound("user not found"))
+		return
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		writeError(c, apierror.Conflict("username is already taken"))
+		return
+	}
+	if err != nil {
+		writeError(c, apierror.Internal(err, "failed to update user"))
+		return
+	}
+	// The activity log is not encrypted, so it only records that the phone
+	// changed.
+	if req.Phone != nil {
+		redacted := "[redacted]"
+		req.Phone = &redacted
+	}
+	recordUserEvent(c, h.q, clerkID, "user.profile_updated", req)
+	c.Header("ETag", versionETag(user.Version))
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,144 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks request bodies against their `validate` struct tags.
+// Errors name fields by their JSON names.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	for tag, pattern := range map[string]*regexp.Regexp{
+		"username": usernamePattern,
+		"locale":   localePattern,
+		"phone":    phonePattern,
+	} {
+		if err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return pattern.MatchString(fl.Field().String())
+		}); err != nil {
+			panic(err)
+		}
+	}
+	if err := v.RegisterValidation("subscriber_url", func(fl validator.FieldLevel) bool {
+		return validSubscriberURL(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// normalizer is implemented by requests that trim or default their fields
+// before they are validated.
+type normalizer interface {
+	normalize()
+}
+
+// bindJSON decodes the request body into req, normalizes and validates it.
+// A malformed body is a 400; a field of the wrong type or failing its tags
+// is a 422 listing every such field.
+func bindJSON(c *gin.Context, req any) error {
+	if err := json.NewDecoder(c.Request.Body).Decode(req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case isBodyTooLarge(err):
+			return err
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return apierror.Validation(apierror.FieldError{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)})
+		}
+		return apierror.BadRequest("invalid request body")
+	}
+	if n, ok := req.(normalizer); ok {
+		n.normalize()
+	}
+	return validateRequest(req)
+}
+
+// validateRequest runs the validate tags on req.
+func validateRequest(req any) error {
+	err := validate.Struct(req)
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	fields := make([]apierror.FieldError, len(invalid))
+	for i, fe := range invalid {
+		// The namespace starts with the Go type name.
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fields[i] = apierror.FieldError{Field: field, Message: fieldMessage(fe)}
+	}
+	return apierror.Validation(fields...)
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "max":
+		bound := "at least "
+		if fe.Tag() == "max" {
+			bound = "at most "
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			if fe.Tag() == "min" && fe.Param() == "1" {
+				return "must not be empty"
+			}
+			return "must be " + bound + fe.Param() + " characters"
+		case reflect.Slice, reflect.Map:
+			return "must have " + bound + fe.Param() + " entries"
+		}
+		return "must be " + bound + fe.Param()
+	case "username":
+		return "must be 3-32 letters, digits, '_', '.' or '-'"
+	case "locale":
+		return "must be a language tag such as en or pt-BR"
+	case "timezone":
+		return "must be an IANA zone name such as Europe/Berlin"
+	case "phone":
+		return "must be an E.164 number such as +14155550123"
+	case "subscriber_url", "http_url":
+		return "must be an absolute http(s) URL"
+	}
+	return "is invalid"
+}
+
+// jsonKind describes t to a client that sent a value of the wrong type.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,39 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"backend/internal/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+type versionResponse struct {
+	buildinfo.Info
+	// MigrationVersion is the migration version the build expects.
+	MigrationVersion uint `json:"migration_version"`
+	// SchemaVersion is the version applied to the database, null when the
+	// database cannot be read; SchemaDirty is set when a failed migration
+	// left it half applied.
+	SchemaVersion *int64 `json:"schema_version"`
+	SchemaDirty   bool   `json:"schema_dirty"`
+}
+
+// versionHandler reports which build is serving: commit, build time, Go
+// version, the migration version the build expects and the one health's
+// database is actually at, so a deploy that skipped its migrations shows
+// up side by side.
+func versionHandler(health *HealthHandler) gin.HandlerFunc {
+	info := buildinfo.Get()
+	return func(c *gin.Context) {
+		body := versionResponse{Info: info, MigrationVersion: health.schemaVersion}
+		version, dirty, err := health.appliedSchema(c.Request.Context())
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "version: reading the schema version failed", "error", err)
+		} else {
+			body.SchemaVersion, body.SchemaDirty = &version, dirty
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"backend/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIV1 prefixes version 1 of the API. A breaking change ships as a new
+// version, registered with its own group next to v1, which keeps serving
+// existing clients unchanged. Operational routes (health, version, metrics,
+// debug, webhooks and the signed /internal routes) are not versioned.
+//
+// The error and list envelopes changed within v1 rather than as a new
+// version; the breaks and how clients migrate are recorded in CHANGELOG.md.
+const APIV1 = "/api/v1"
+
+// apiVersion is one version of the API.
+type apiVersion struct {
+	name string
+	// errorBody renders an error response of this version.
+	errorBody func(e *apierror.Error, requestID string) any
+}
+
+type v1Error struct {
+	Code      apierror.Code         `json:"code"`
+	Message   string                `json:"message"`
+	Fields    []apierror.FieldError `json:"fields,omitempty"`
+	Details   map[string]any        `json:"details,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// v1 errors are {"error": {"code", "message", "fields", "details",
+// "request_id"}}, with fields and details only when there are any.
+var v1 = apiVersion{
+	name: "v1",
+	errorBody: func(e *apierror.Error, requestID string) any {
+		return gin.H{"error": v1Error{
+			Code:      e.Code,
+			Message:   e.Message,
+			Fields:    e.Fields,
+			Details:   e.Details,
+			RequestID: requestID,
+		}}
+	},
+}
+
+// versionGroup returns the route group serving version v under prefix. Its
+// responses carry an API-Version header.
+func versionGroup(r *gin.Engine, prefix string, v apiVersion) *gin.RouterGroup {
+	return r.Group(prefix, func(c *gin.Context) {
+		c.Set("api_version", v)
+		c.Header("API-Version", v.name)
+		c.Next()
+	})
+}
+
+// versionOf returns the version c was routed to; unversioned routes render
+// errors like v1.
+func versionOf(c *gin.Context) apiVersion {
+	if v, ok := c.Get("api_version"); ok {
+		return v.(apiVersion)
+	}
+	return v1
+}
+
+// legacyPrefixes are the API's paths from before versioning, which v1
+// still answers; see Router.ServeHTTP.
+var legacyPrefixes = []string{"/users", "/me", "/admin"}
+
+func legacyPath(path string) bool {
+	for _, p := range legacyPrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalRoute returns the route pattern gin reports as FullPath for
+// requests to path. Legacy paths are served by their APIV1 twins, so a
+// per-route setting written as /users/:id has to be keyed as
+// /api/v1/users/:id to take effect.
+func CanonicalRoute(path string) string {
+	if legacyPath(path) {
+		return APIV1 + path
+	}
+	return path
+}
+
+// ServeHTTP serves requests to the pre-versioning paths, e.g. /users/:id,
+// as their /api/v1 equivalents. The responses are marked deprecated, with a
+// Link to the new path, so clients can be moved before the old paths go.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if legacyPath(req.URL.Path) {
+		successor := APIV1 + req.URL.Path
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		req.URL.Path = successor
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = APIV1 + req.URL.RawPath
+		}
+	}
+	r.Engine.ServeHTTP(w, req)
+}
@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+
+// svixSignature signs body the way Svix does for a delivery sent at ts.
+func svixSignature(t *testing.T, secret, id string, ts int64, body []byte) string {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(secret[len("whsec_"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(ts, 10) + "." + string(body)))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySvixClockSkew(t *testing.T) {
+	const tolerance = 5 * time.Minute
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"type":"user.created","data":{"id":"user_1"}}`)
+
+	tests := []struct {
+		name string
+		skew time.Duration // how far the signature's timestamp is before now
+		want bool
+	}{
+		{"now", 0, true},
+		{"inside, past", tolerance - time.Second, true},
+		{"inside, future", -(tolerance - time.Second), true},
+		{"at tolerance, past", tolerance, true},
+		{"at tolerance, future", -tolerance, true},
+		{"just outside, past", tolerance + time.Second, false},
+		{"just outside, future", -(tolerance + time.Second), false},
+		{"far past", 24 * time.Hour, false},
+		{"far future", -24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := now.Add(-tt.skew).Unix()
+			sig := svixSignature(t, testWebhookSecret, "msg_1", ts, body)
+			got := verifySvix(body, []string{testWebhookSecret}, "msg_1", strconv.FormatInt(ts, 10), sig, tolerance, now)
+			if got != tt.want {
+				t.Errorf("verifySvix() with timestamp %s from now = %v, want %v", -tt.skew, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySvixRejectsBadSignatures(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"type":"user.created"}`)
+	sig := svixSignature(t, testWebhookSecret, "msg_1", now.Unix(), body)
+	other := "whsec_" + base64.StdEncoding.EncodeToString([]byte("another secret"))
+
+	tests := []struct {
+		name    string
+		secrets []string
+		id      string
+		ts      string
+		sig     string
+		body    []byte
+		want    bool
+	}{
+		{"valid", []string{testWebhookSecret}, "msg_1", ts, sig, body, true},
+		{"rotated secret", []string{other, testWebhookSecret}, "msg_1", ts, sig, body, true},
+		{"one of several signatures", []string{testWebhookSecret}, "msg_1", ts, "v1,bm9wZQ== " + sig, body, true},
+		{"wrong secret", []string{other}, "msg_1", ts, sig, body, false},
+		{"no secrets", nil, "msg_1", ts, sig, body, false},
+		{"tampered body", []string{testWebhookSecret}, "msg_1", ts, sig, []byte(`{"type":"user.deleted"}`), false},
+		{"different id", []string{testWebhookSecret}, "msg_2", ts, sig, body, false},
+		{"malformed timestamp", []string{testWebhookSecret}, "msg_1", "yesterday", sig, body, false},
+		{"missing signature", []string{testWebhookSecret}, "msg_1", ts, "", body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifySvix(tt.body, tt.secrets, tt.id, tt.ts, tt.sig, 5*time.Minute, now); got != tt.want {
+				t.Errorf("verifySvix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
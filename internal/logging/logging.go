@@ -0,0 +1,23 @@
+// Package logging configures the process-wide slog logger. Output from the
+// standard log package goes through it too, so existing log.Printf calls
+// come out as structured records at level INFO. Records logged with a
+// request's context also carry its request ID and trace ID.
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Setup installs the default logger at level, writing JSON, or text when
+// format is "text" for reading logs in a terminal. Pass a *slog.LevelVar to
+// change the level while running.
+func Setup(level slog.Leveler, format string) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler = slog.NewJSONHandler(os.Stderr, opts)
+	if format == "text" {
+		h = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+}
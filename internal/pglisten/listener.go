@@ -0,0 +1,137 @@
+// Package pglisten delivers Postgres NOTIFY messages to in-process handlers.
+//
+// A Listener holds one dedicated connection, outside the pool, on which it
+// LISTENs to every channel with a registered handler. When the connection
+// drops it reconnects with exponential backoff and calls the OnReconnect
+// hooks, since notifications sent while disconnected are lost; consumers use
+// that to resynchronise (e.g. drop a cache or poll once). LISTEN needs a
+// session, so the connection string must not point at a transaction-mode
+// pooler such as PgBouncer.
+package pglisten
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Handler receives the payload of one notification.
+type Handler func(payload string)
+
+type Listener struct {
+	connString string
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu          sync.Mutex
+	handlers    map[string][]Handler
+	onReconnect []func()
+
+	connected atomic.Bool
+}
+
+func New(connString string) *Listener {
+	return &Listener{
+		connString: connString,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		handlers:   make(map[string][]Handler),
+	}
+}
+
+// Handle registers h for channel. Handlers run on the listener's goroutine
+// and must not block. Register handlers before calling Run.
+func (l *Listener) Handle(channel string, h Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[channel] = append(l.handlers[channel], h)
+}
+
+// OnReconnect registers fn to run after every reconnect (not the first
+// connect).
+func (l *Listener) OnReconnect(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onReconnect = append(l.onReconnect, fn)
+}
+
+// Connected reports whether the listener currently holds a connection with
+// every channel subscribed.
+func (l *Listener) Connected() bool {
+	return l.connected.Load()
+}
+
+// Run listens until ctx is done, reconnecting whenever the connection fails.
+func (l *Listener) Run(ctx context.Context) {
+	wait := l.minBackoff
+	connected := false
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := l.listen(ctx, connected)
+		if ctx.Err() != nil {
+			return
+		}
+		connected = true
+
+		// A connection that stayed up for a while resets the backoff.
+		if time.Since(start) > l.maxBackoff {
+			wait = l.minBackoff
+		}
+		log.Printf("pglisten: connection lost, reconnecting in %s: %v", wait, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, l.maxBackoff)
+	}
+}
+
+// listen connects, subscribes and dispatches notifications until the
+// connection fails.
+func (l *Listener) listen(ctx context.Context, reconnect bool) error {
+	conn, err := pgx.Connect(ctx, l.connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	l.mu.Lock()
+	channels := make([]string, 0, len(l.handlers))
+	for ch := range l.handlers {
+		channels = append(channels, ch)
+	}
+	hooks := l.onReconnect
+	l.mu.Unlock()
+
+	for _, ch := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	l.connected.Store(true)
+	defer l.connected.Store(false)
+	if reconnect {
+		log.Printf("pglisten: reconnected, listening on %v", channels)
+		for _, fn := range hooks {
+			fn()
+		}
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.mu.Lock()
+		hs := l.handlers[n.Channel]
+		l.mu.Unlock()
+		for _, h := range hs {
+			h(n.Payload)
+		}
+	}
+}
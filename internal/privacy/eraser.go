@@ -0,0 +1,132 @@
+// Package privacy carries out personal-data erasure requests in the
+// background.
+package privacy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	pollInterval = 30 * time.Second
+	maxAttempts  = 5
+)
+
+// Eraser anonymizes users with a pending erasure request: it blanks their
+// profile, scrubs the details of their activity log and the raw webhook
+// payloads that mention them, and deletes their avatar. Non-identifying
+// records such as memberships and activity timestamps are kept.
+type Eraser struct {
+	pool  *pgxpool.Pool
+	q     *db.Queries
+	store *storage.S3
+	wake  chan struct{}
+}
+
+// NewEraser returns an Eraser. store may be nil when object storage is not
+// configured.
+func NewEraser(pool *pgxpool.Pool, q *db.Queries, store *storage.S3) *Eraser {
+	return &Eraser{pool: pool, q: q, store: store, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the eraser after a request was created. It never blocks.
+func (e *Eraser) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes pending requests until ctx is done.
+func (e *Eraser) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && e.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext erases one user and reports whether a request was found.
+func (e *Eraser) processNext(ctx context.Context) bool {
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		log.Printf("privacy: starting transaction failed: %v", err)
+		return false
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	qtx := e.q.WithTx(tx)
+
+	req, err := qtx.ClaimErasureRequest(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		log.Printf("privacy: claiming erasure request failed: %v", err)
+		return false
+	}
+
+	avatarKey, err := e.erase(ctx, qtx, req.ClerkID)
+	if err == nil {
+		err = qtx.CompleteErasureRequest(ctx, req.ID)
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("privacy: erasing %s (request %d) failed: %v", req.ClerkID, req.ID, err)
+		_ = tx.Rollback(ctx)
+		if ferr := e.q.FailErasureRequest(ctx, db.FailErasureRequestParams{
+			ID:          req.ID,
+			Error:       pgtype.Text{String: err.Error(), Valid: true},
+			MaxAttempts: maxAttempts,
+		}); ferr != nil {
+			log.Printf("privacy: recording failure of request %d failed: %v", req.ID, ferr)
+		}
+		return true
+	}
+
+	// The object is deleted only after the row no longer points at it. A
+	// failure leaves an orphaned object, which is logged for cleanup.
+	if avatarKey != "" && e.store != nil {
+		if err := e.store.Delete(ctx, avatarKey); err != nil {
+			log.Printf("privacy: deleting avatar %s of %s failed: %v", avatarKey, req.ClerkID, err)
+		}
+	}
+	log.Printf("privacy: erased personal data of %s (request %d)", req.ClerkID, req.ID)
+	return true
+}
+
+func (e *Eraser) erase(ctx context.Context, q *db.Queries, clerkID string) (string, error) {
+	avatarKey, err := q.AnonymizeUser(ctx, clerkID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	if err := q.ScrubUserEventDetails(ctx, clerkID); err != nil {
+		return "", err
+	}
+	if _, err := q.ScrubWebhookEventPayloads(ctx, clerkID); err != nil {
+		return "", err
+	}
+	if _, err := q.ScrubWebhookDeliveryPayloads(ctx, clerkID); err != nil {
+		return "", err
+	}
+	return avatarKey.String, nil
+}
@@ -0,0 +1,157 @@
+// Package report sends panics and server errors to Sentry, or to any
+// Sentry-compatible service such as GlitchTip. Everything is a no-op until
+// Init finds a DSN, so callers never check whether reporting is enabled.
+package report
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/buildinfo"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Request describes the request an error happened in.
+type Request struct {
+	HTTP      *http.Request
+	Route     string
+	Status    int
+	UserID    string
+	RequestID string
+}
+
+// sensitiveHeaders never leave the process, whatever SENTRY_SEND_PII says.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "X-Api-Key", "Svix-Signature", "X-Signature"}
+
+// emailPattern finds addresses in error messages, which often quote the
+// value that failed.
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Options configure reporting; see Init.
+type Options struct {
+	DSN         string
+	Environment string
+	// Release defaults to the build's commit.
+	Release string
+	// SampleRate is the share of events sent, in (0, 1].
+	SampleRate float64
+	// SendPII keeps client IPs, request bodies, query strings and email
+	// addresses in messages, which are scrubbed before sending otherwise.
+	SendPII bool
+}
+
+// Init configures reporting with opts. It returns false when opts.DSN is
+// empty.
+func Init(opts Options) (bool, error) {
+	if opts.DSN == "" {
+		return false, nil
+	}
+	release := opts.Release
+	if commit := buildinfo.Get().Commit; release == "" && commit != "unknown" {
+		release = commit
+	}
+	sendPII := opts.SendPII
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:            opts.DSN,
+		Environment:    opts.Environment,
+		Release:        release,
+		SampleRate:     opts.SampleRate,
+		SendDefaultPII: sendPII,
+		BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			return scrub(event, sendPII)
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("SENTRY_DSN: %w", err)
+	}
+	return true, nil
+}
+
+// Flush waits up to timeout for queued events to be sent; call it before
+// exiting.
+func Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
+
+// Error reports err, which made the request fail.
+func Error(err error, r Request) {
+	hub := sentry.CurrentHub()
+	if hub.Client() == nil {
+		return
+	}
+	hub.WithScope(func(scope *sentry.Scope) {
+		r.apply(scope)
+		hub.CaptureException(err)
+	})
+}
+
+// Panic reports a recovered panic value, with the stack of the caller.
+func Panic(ctx context.Context, v any, r Request) {
+	hub := sentry.CurrentHub()
+	if hub.Client() == nil {
+		return
+	}
+	hub.WithScope(func(scope *sentry.Scope) {
+		r.apply(scope)
+		scope.SetLevel(sentry.LevelFatal)
+		hub.RecoverWithContext(ctx, v)
+	})
+}
+
+func (r Request) apply(scope *sentry.Scope) {
+	if r.HTTP != nil {
+		scope.SetRequest(r.HTTP)
+		scope.SetTag("method", r.HTTP.Method)
+	}
+	if r.Route != "" {
+		scope.SetTag("route", r.Route)
+		// Group by route rather than by message, which may hold IDs.
+		scope.SetFingerprint([]string{"{{ default }}", r.Route})
+	}
+	if r.Status != 0 {
+		scope.SetTag("status", strconv.Itoa(r.Status))
+	}
+	if r.RequestID != "" {
+		scope.SetTag("request_id", r.RequestID)
+	}
+	if r.UserID != "" {
+		scope.SetUser(sentry.User{ID: r.UserID})
+	}
+}
+
+// scrub removes credentials from every event, and personal data unless
+// sendPII is set.
+func scrub(event *sentry.Event, sendPII bool) *sentry.Event {
+	if req := event.Request; req != nil {
+		for name := range req.Headers {
+			for _, s := range sensitiveHeaders {
+				if strings.EqualFold(name, s) {
+					delete(req.Headers, name)
+				}
+			}
+		}
+		req.Cookies = ""
+		if !sendPII {
+			req.Data = ""
+			req.QueryString = ""
+			req.Env = nil
+		}
+	}
+	if sendPII {
+		return event
+	}
+	event.User.IPAddress = ""
+	event.User.Email = ""
+	event.Message = emailPattern.ReplaceAllString(event.Message, "[email]")
+	for i := range event.Exception {
+		event.Exception[i].Value = emailPattern.ReplaceAllString(event.Exception[i].Value, "[email]")
+	}
+	return event
+}
@@ -0,0 +1,59 @@
+// Package tracing configures OpenTelemetry for the API: spans for every HTTP
+// request (otelgin) and database query (otelpgx), joined to the caller's
+// trace through the W3C traceparent header and exported over OTLP.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ServiceName identifies this service in traces unless OTEL_SERVICE_NAME
+// overrides it.
+const ServiceName = "prima-backend"
+
+// Setup installs the global propagator and, when OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set, a tracer provider exporting
+// over OTLP/HTTP. Without an endpoint spans are not recorded, but incoming
+// trace context is still passed on. The exporter, sampler
+// (OTEL_TRACES_SAMPLER, OTEL_TRACES_SAMPLER_ARG) and resource
+// (OTEL_RESOURCE_ATTRIBUTES) follow the standard OTEL_* variables.
+//
+// shutdown flushes buffered spans; call it before exiting.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, enabled bool, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return noop, false, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return noop, false, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, false, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, true, nil
+}
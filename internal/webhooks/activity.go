@@ -0,0 +1,19 @@
+package webhooks
+
+import (
+	"context"
+
+	"backend/internal/db"
+)
+
+// recordUserEvent appends to the user's activity log. It runs in the same
+// transaction as the change it describes, so a failure fails (and retries)
+// the whole webhook rather than leaving the log incomplete.
+func recordUserEvent(ctx context.Context, q *db.Queries, clerkID, eventType string) error {
+	return q.InsertUserEvent(ctx, db.InsertUserEventParams{
+		ClerkID:   clerkID,
+		EventType: eventType,
+		Actor:     "clerk",
+		Details:   []byte("{}"),
+	})
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"strings"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegisterClerkHandlers wires every supported Clerk event type to its
+// handler. Adding a new event means adding a handler file and one line here.
+// Email addresses are encrypted with keys before they are stored.
+func RegisterClerkHandlers(d *Dispatcher, pool *pgxpool.Pool, outbox *Outbox, keys *crypto.Keyring) {
+	q := db.New(pool)
+
+	u := &userHandlers{pool: pool, outbox: outbox, keys: keys}
+	On(d, u.upsert, "user.created", "user.updated")
+	On(d, u.delete, "user.deleted")
+	On(d, u.setBanned, "user.banned", "user.unbanned")
+
+	s := &sessionHandlers{q: q}
+	On(d, s.created, "session.created")
+
+	// email_address.* events are not handled: they fire for secondary
+	// addresses too and carry no ordering timestamp, while a change of the
+	// primary address always arrives as user.updated.
+
+	o := &organizationHandlers{q: q}
+	On(d, o.upsertOrganization, "organization.created", "organization.updated")
+	On(d, o.deleteOrganization, "organization.deleted")
+	On(d, o.upsertMembership, "organizationMembership.created", "organizationMembership.updated")
+	On(d, o.deleteMembership, "organizationMembership.deleted")
+}
+
+// ToText converts a possibly blank string into a nullable column value.
+func ToText(s string) pgtype.Text {
+	s = strings.TrimSpace(s)
+	return pgtype.Text{String: s, Valid: s != ""}
+}
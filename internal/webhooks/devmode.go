@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// DevSecret is the well-known signing secret accepted when the API runs with
+// WEBHOOK_DEV_MODE=true. It is public by design and must never be configured
+// in production.
+const DevSecret = "whsec_cHJpbWEtbG9jYWwtZGV2LXdlYmhvb2stc2VjcmV0ISEh"
+
+// SignClerk produces an svix-signature header value for body the same way
+// Clerk does: HMAC-SHA256 over "id.timestamp.body" keyed with the base64
+// part of a "whsec_" secret.
+func SignClerk(secret, svixID, svixTimestamp string, body []byte) (string, error) {
+	parts := strings.SplitN(secret, "_", 2)
+	if len(parts) != 2 {
+		return "", errors.New("webhook secret must look like whsec_<base64>")
+	}
+	key, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(svixID + "." + svixTimestamp + "."))
+	_, _ = mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
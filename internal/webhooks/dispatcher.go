@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// ErrBadPayload marks errors caused by a payload that does not match the
+// shape expected for its event type.
+var ErrBadPayload = errors.New("bad webhook payload")
+
+// Envelope is the part shared by every Clerk webhook. Data is decoded by the
+// handler registered for Type.
+type Envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	// Timestamp is when Clerk generated the event, in milliseconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// HandlerFunc processes the data object of one event.
+type HandlerFunc func(ctx context.Context, eventType string, data json.RawMessage) error
+
+// Dispatcher routes events to the handler registered for their type. Event
+// types without a handler are acknowledged and ignored.
+type Dispatcher struct {
+	handlers map[string]HandlerFunc
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string]HandlerFunc)}
+}
+
+// Register adds h for each of eventTypes. Registering a type twice panics, as
+// that is always a wiring bug.
+func (d *Dispatcher) Register(h HandlerFunc, eventTypes ...string) {
+	for _, t := range eventTypes {
+		if _, ok := d.handlers[t]; ok {
+			panic("webhooks: duplicate handler for " + t)
+		}
+		d.handlers[t] = h
+	}
+}
+
+// On registers a handler that receives the data object decoded into T. If T
+// implements Validate(eventType string) error, the payload is validated
+// before fn runs.
+func On[T any](d *Dispatcher, fn func(ctx context.Context, eventType string, data T) error, eventTypes ...string) {
+	d.Register(func(ctx context.Context, eventType string, raw json.RawMessage) error {
+		var data T
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return &ValidationError{Code: CodeMalformedJSON, Field: "data", Message: err.Error()}
+		}
+		if v, ok := any(data).(validator); ok {
+			if err := v.Validate(eventType); err != nil {
+				return err
+			}
+		}
+		return fn(ctx, eventType, data)
+	}, eventTypes...)
+}
+
+// Handles reports whether a handler is registered for eventType.
+func (d *Dispatcher) Handles(eventType string) bool {
+	_, ok := d.handlers[eventType]
+	return ok
+}
+
+// EventTypes returns the registered event types in sorted order.
+func (d *Dispatcher) EventTypes() []string {
+	types := make([]string, 0, len(d.handlers))
+	for t := range d.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Dispatch decodes the envelope of body and runs the matching handler.
+func (d *Dispatcher) Dispatch(ctx context.Context, body []byte) error {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return &ValidationError{Code: CodeMalformedJSON, Message: err.Error()}
+	}
+	h, ok := d.handlers[env.Type]
+	if !ok {
+		return nil
+	}
+	return h(ctx, env.Type, env.Data)
+}
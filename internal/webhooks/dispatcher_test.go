@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// userCall records one invocation of a user handler.
+type userCall struct {
+	handler   string
+	eventType string
+	user      ClerkUser
+}
+
+// recordingDispatcher routes user events the way RegisterClerkHandlers does,
+// to handlers that record their calls instead of writing to the database.
+func recordingDispatcher(calls *[]userCall) *Dispatcher {
+	record := func(name string) func(context.Context, string, ClerkUser) error {
+		return func(_ context.Context, eventType string, u ClerkUser) error {
+			*calls = append(*calls, userCall{handler: name, eventType: eventType, user: u})
+			return nil
+		}
+	}
+	d := NewDispatcher()
+	On(d, record("upsert"), "user.created", "user.updated")
+	On(d, record("delete"), "user.deleted")
+	return d
+}
+
+func TestRegisterClerkHandlersCoversUserEvents(t *testing.T) {
+	d := NewDispatcher()
+	RegisterClerkHandlers(d, nil, nil, nil)
+	for _, eventType := range []string{"user.created", "user.updated", "user.deleted", "user.banned", "user.unbanned"} {
+		if !d.Handles(eventType) {
+			t.Errorf("no handler registered for %s", eventType)
+		}
+	}
+	if d.Handles("user.renamed") {
+		t.Error("handler registered for unknown event user.renamed")
+	}
+}
+
+func TestDispatchUserEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		handler string
+		id      string
+		email   string
+	}{
+		{
+			name:    "user.created",
+			body:    `{"type":"user.created","data":{"id":"user_1","first_name":"Ada","primary_email_address_id":"idn_1","email_addresses":[{"id":"idn_1","email_address":"Ada@Example.com"}]}}`,
+			handler: "upsert",
+			id:      "user_1",
+			email:   "ada@example.com",
+		},
+		{
+			name:    "user.updated",
+			body:    `{"type":"user.updated","data":{"id":"user_1","username":"ada","email_addresses":[{"id":"idn_2","email_address":"ada@example.org"}]}}`,
+			handler: "upsert",
+			id:      "user_1",
+			email:   "ada@example.org",
+		},
+		{
+			// Deleted users arrive with only their id and deleted flag.
+			name:    "user.deleted",
+			body:    `{"type":"user.deleted","data":{"id":"user_1","deleted":true,"object":"user"}}`,
+			handler: "delete",
+			id:      "user_1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []userCall
+			if err := recordingDispatcher(&calls).Dispatch(context.Background(), []byte(tt.body)); err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+			if len(calls) != 1 {
+				t.Fatalf("handlers called %d times, want 1", len(calls))
+			}
+			got := calls[0]
+			if got.handler != tt.handler || got.eventType != tt.name {
+				t.Errorf("dispatched %s to %s, want %s", got.eventType, got.handler, tt.handler)
+			}
+			if got.user.ID != tt.id {
+				t.Errorf("user id = %q, want %q", got.user.ID, tt.id)
+			}
+			if email := got.user.PrimaryEmail(); email != tt.email {
+				t.Errorf("primary email = %q, want %q", email, tt.email)
+			}
+		})
+	}
+}
+
+func TestDispatchIgnoresUnknownEventTypes(t *testing.T) {
+	var calls []userCall
+	d := recordingDispatcher(&calls)
+	for _, body := range []string{
+		`{"type":"user.renamed","data":{"id":"user_1"}}`,
+		`{"type":"","data":{}}`,
+		// Unknown types are not decoded, so any data is acknowledged.
+		`{"type":"invoice.paid","data":"not an object"}`,
+	} {
+		if err := d.Dispatch(context.Background(), []byte(body)); err != nil {
+			t.Errorf("Dispatch(%s) error = %v, want nil", body, err)
+		}
+	}
+	if len(calls) != 0 {
+		t.Errorf("handlers called for unknown events: %+v", calls)
+	}
+}
+
+func TestDispatchRejectsBadUserPayloads(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"malformed envelope", `{"type":`, CodeMalformedJSON},
+		{"malformed data", `{"type":"user.created","data":{"id":42}}`, CodeMalformedJSON},
+		{"missing id", `{"type":"user.updated","data":{"first_name":"Ada"}}`, CodeMissingField},
+		{"missing id on delete", `{"type":"user.deleted","data":{"deleted":true}}`, CodeMissingField},
+		{"invalid email", `{"type":"user.created","data":{"id":"user_1","email_addresses":[{"id":"idn_1","email_address":"not-an-address"}]}}`, CodeInvalidEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []userCall
+			err := recordingDispatcher(&calls).Dispatch(context.Background(), []byte(tt.body))
+			if !errors.Is(err, ErrBadPayload) {
+				t.Fatalf("Dispatch() error = %v, want ErrBadPayload", err)
+			}
+			if code := ErrorCode(err); code != tt.code {
+				t.Errorf("ErrorCode() = %q, want %q", code, tt.code)
+			}
+			if len(calls) != 0 {
+				t.Errorf("handler ran for a rejected payload: %+v", calls)
+			}
+		})
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	d := NewDispatcher()
+	On(d, func(context.Context, string, ClerkUser) error { return nil }, "user.created")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering user.created twice did not panic")
+		}
+	}()
+	On(d, func(context.Context, string, ClerkUser) error { return nil }, "user.created")
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics counts webhook traffic per event type. Event types without a
+// registered handler are reported as "other" so attacker-controlled type
+// strings cannot blow up label cardinality. A nil *Metrics is a no-op.
+type Metrics struct {
+	d *Dispatcher
+
+	received  *prometheus.CounterVec
+	verified  *prometheus.CounterVec
+	rejected  *prometheus.CounterVec
+	processed *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+}
+
+func NewMetrics(reg prometheus.Registerer, d *Dispatcher) *Metrics {
+	m := &Metrics{
+		d: d,
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clerk_webhooks_received_total",
+			Help: "Clerk webhook requests received, before signature verification.",
+		}, []string{"type"}),
+		verified: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clerk_webhooks_verified_total",
+			Help: "Clerk webhooks with a valid Svix signature.",
+		}, []string{"type"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clerk_webhooks_rejected_total",
+			Help: "Clerk webhooks rejected because of a bad or stale signature.",
+		}, []string{"type"}),
+		processed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clerk_webhooks_processed_total",
+			Help: "Clerk webhook events processed successfully.",
+		}, []string{"type"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clerk_webhooks_failed_total",
+			Help: "Clerk webhook processing attempts that failed, by outcome (retry or dead).",
+		}, []string{"type", "outcome"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clerk_webhooks_processing_seconds",
+			Help:    "Time spent processing one Clerk webhook event.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"type"}),
+	}
+	reg.MustRegister(m.received, m.verified, m.rejected, m.processed, m.failed, m.latency)
+	return m
+}
+
+func (m *Metrics) label(eventType string) string {
+	if m.d != nil && m.d.Handles(eventType) {
+		return eventType
+	}
+	return "other"
+}
+
+func (m *Metrics) Received(eventType string) {
+	if m != nil {
+		m.received.WithLabelValues(m.label(eventType)).Inc()
+	}
+}
+
+func (m *Metrics) Verified(eventType string) {
+	if m != nil {
+		m.verified.WithLabelValues(m.label(eventType)).Inc()
+	}
+}
+
+func (m *Metrics) Rejected(eventType string) {
+	if m != nil {
+		m.rejected.WithLabelValues(m.label(eventType)).Inc()
+	}
+}
+
+func (m *Metrics) Processed(eventType string, d time.Duration) {
+	if m != nil {
+		l := m.label(eventType)
+		m.processed.WithLabelValues(l).Inc()
+		m.latency.WithLabelValues(l).Observe(d.Seconds())
+	}
+}
+
+func (m *Metrics) Failed(eventType string, dead bool, d time.Duration) {
+	if m != nil {
+		l := m.label(eventType)
+		outcome := "retry"
+		if dead {
+			outcome = "dead"
+		}
+		m.failed.WithLabelValues(l, outcome).Inc()
+		m.latency.WithLabelValues(l).Observe(d.Seconds())
+	}
+}
@@ -0,0 +1,43 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// OrderingKey returns the key that serializes processing of an event: the
+// Clerk user ID for user-scoped events and the organization ID for
+// organization events. Events with an empty key are processed in any order.
+func OrderingKey(evt Envelope) string {
+	var probe struct {
+		ID             string `json:"id"`
+		UserID         string `json:"user_id"`
+		PublicUserData struct {
+			UserID string `json:"user_id"`
+		} `json:"public_user_data"`
+	}
+	if err := json.Unmarshal(evt.Data, &probe); err != nil {
+		return ""
+	}
+
+	var key string
+	switch {
+	case strings.HasPrefix(evt.Type, "user."), strings.HasPrefix(evt.Type, "organization."):
+		key = probe.ID
+	case strings.HasPrefix(evt.Type, "session."):
+		key = probe.UserID
+	case strings.HasPrefix(evt.Type, "organizationMembership."):
+		key = probe.PublicUserData.UserID
+	}
+	return strings.TrimSpace(key)
+}
+
+// OccurredAt returns Clerk's event timestamp, falling back to now when the
+// envelope does not carry one.
+func OccurredAt(evt Envelope, now time.Time) time.Time {
+	if evt.Timestamp <= 0 {
+		return now
+	}
+	return time.UnixMilli(evt.Timestamp)
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"context"
+	"strings"
+
+	"backend/internal/db"
+)
+
+// ClerkOrganization is the data object of organization.* events.
+type ClerkOrganization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// ClerkMembership is the data object of organizationMembership.* events.
+type ClerkMembership struct {
+	ID           string `json:"id"`
+	Role         string `json:"role"`
+	Organization struct {
+		ID string `json:"id"`
+	} `json:"organization"`
+	PublicUserData struct {
+		UserID string `json:"user_id"`
+	} `json:"public_user_data"`
+}
+
+type organizationHandlers struct {
+	q *db.Queries
+}
+
+func (h *organizationHandlers) upsertOrganization(ctx context.Context, _ string, o ClerkOrganization) error {
+	name := strings.TrimSpace(o.Name)
+	if name == "" {
+		name = "Organization"
+	}
+	return h.q.UpsertOrganization(ctx, db.UpsertOrganizationParams{
+		ClerkOrgID: strings.TrimSpace(o.ID),
+		Name:       name,
+		Slug:       ToText(o.Slug),
+	})
+}
+
+func (h *organizationHandlers) deleteOrganization(ctx context.Context, _ string, o ClerkOrganization) error {
+	id := strings.TrimSpace(o.ID)
+	if err := h.q.SoftDeleteOrganization(ctx, id); err != nil {
+		return err
+	}
+	return h.q.DeleteMembershipsByOrganization(ctx, id)
+}
+
+func (h *organizationHandlers) upsertMembership(ctx context.Context, _ string, m ClerkMembership) error {
+	return h.q.UpsertMembership(ctx, db.UpsertMembershipParams{
+		ClerkMembershipID: strings.TrimSpace(m.ID),
+		ClerkOrgID:        strings.TrimSpace(m.Organization.ID),
+		ClerkUserID:       strings.TrimSpace(m.PublicUserData.UserID),
+		Role:              m.Role,
+	})
+}
+
+func (h *organizationHandlers) deleteMembership(ctx context.Context, _ string, m ClerkMembership) error {
+	return h.q.DeleteMembership(ctx, strings.TrimSpace(m.ID))
+}
@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Headers attached to outgoing webhook deliveries. The signature uses the
+// same scheme as Svix so subscribers can reuse a Svix verifier:
+// base64(HMAC-SHA256(key, "<id>.<timestamp>.<body>")), prefixed "v1,", where
+// key is the subscription secret without "whsec_", base64-decoded.
+const (
+	OutboundHeaderID        = "Webhook-Id"
+	OutboundHeaderTimestamp = "Webhook-Timestamp"
+	OutboundHeaderSignature = "Webhook-Signature"
+)
+
+// OutboundEvent is the body POSTed to subscribers.
+type OutboundEvent struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Outbox queues user lifecycle events for delivery to subscribers. The
+// personal data in queued payloads is sealed with keys; the Deliverer opens
+// it again just before sending.
+type Outbox struct {
+	q    *db.Queries
+	keys *crypto.Keyring
+}
+
+func NewOutbox(q *db.Queries, keys *crypto.Keyring) *Outbox {
+	return &Outbox{q: q, keys: keys}
+}
+
+// WithTx returns an Outbox that queues deliveries through q, typically bound
+// to the transaction that made the change being announced.
+func (o *Outbox) WithTx(q *db.Queries) *Outbox {
+	return &Outbox{q: q, keys: o.keys}
+}
+
+// Emit queues one delivery per interested subscriber.
+func (o *Outbox) Emit(ctx context.Context, eventType string, data any) error {
+	payload, err := json.Marshal(OutboundEvent{Type: eventType, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	if payload, err = SealPayload(o.keys, payload); err != nil {
+		return err
+	}
+	_, err = o.q.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventType: eventType,
+		Payload:   payload,
+	})
+	return err
+}
+
+// SignOutbound returns the Webhook-Signature value for a delivery. Like
+// Svix, the HMAC key is the base64 part of the "whsec_..." secret, decoded;
+// a secret in any other form is used as is.
+func SignOutbound(secret, id, timestamp string, body []byte) string {
+	key := []byte(secret)
+	if encoded, ok := strings.CutPrefix(secret, "whsec_"); ok {
+		if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			key = decoded
+		}
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(id + "." + timestamp + "."))
+	_, _ = mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Deliverer sends queued deliveries to subscribers, retrying failures with
+// the same backoff and dead-letter policy as the inbound Worker.
+type Deliverer struct {
+	q      *db.Queries
+	client *http.Client
+	cfg    WorkerConfig
+	keys   *crypto.Keyring
+}
+
+func NewDeliverer(q *db.Queries, client *http.Client, cfg WorkerConfig, keys *crypto.Keyring) *Deliverer {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Deliverer{q: q, client: client, cfg: cfg, keys: keys}
+}
+
+// Run delivers due events until ctx is done.
+func (d *Deliverer) Run(ctx context.Context) {
+	t := time.NewTicker(d.cfg.PollInterval)
+	defer t.Stop()
+
+	for {
+		d.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (d *Deliverer) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.q.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+			LeaseSeconds: int32(d.cfg.Lease / time.Second),
+			BatchSize:    int32(d.cfg.BatchSize),
+		})
+		if err != nil {
+			log.Printf("webhooks: claiming deliveries failed: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		for _, del := range deliveries {
+			d.deliver(ctx, del)
+		}
+	}
+}
+
+func (d *Deliverer) deliver(ctx context.Context, del db.ClaimDueWebhookDeliveriesRow) {
+	status, err := d.post(ctx, del)
+	code := pgtype.Int4{Int32: int32(status), Valid: status != 0}
+
+	if err == nil {
+		if err := d.q.MarkWebhookDeliveryDelivered(ctx, db.MarkWebhookDeliveryDeliveredParams{
+			ID:             del.ID,
+			ResponseStatus: code,
+		}); err != nil {
+			log.Printf("webhooks: marking delivery %d delivered failed: %v", del.ID, err)
+		}
+		return
+	}
+
+	if int(del.Attempts) >= d.cfg.MaxAttempts {
+		log.Printf("webhooks: delivery %d to %s dead-lettered after %d attempts: %v", del.ID, del.Url, del.Attempts, err)
+		if err := d.q.MarkWebhookDeliveryDead(ctx, db.MarkWebhookDeliveryDeadParams{
+			ID:             del.ID,
+			LastError:      ToText(err.Error()),
+			ResponseStatus: code,
+		}); err != nil {
+			log.Printf("webhooks: dead-lettering delivery %d failed: %v", del.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(backoff(d.cfg.BaseBackoff, d.cfg.MaxBackoff, int(del.Attempts)))
+	if err := d.q.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+		ID:             del.ID,
+		LastError:      ToText(err.Error()),
+		ResponseStatus: code,
+		NextAttemptAt:  pgtype.Timestamptz{Time: next, Valid: true},
+	}); err != nil {
+		log.Printf("webhooks: recording failure of delivery %d failed: %v", del.ID, err)
+	}
+}
+
+// post sends one delivery and returns the response status. Any non-2xx
+// response is an error.
+func (d *Deliverer) post(ctx context.Context, del db.ClaimDueWebhookDeliveriesRow) (int, error) {
+	id := "dlv_" + strconv.FormatInt(del.ID, 10)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body, err := OpenPayload(d.keys, del.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(OutboundHeaderID, id)
+	req.Header.Set(OutboundHeaderTimestamp, timestamp)
+	req.Header.Set(OutboundHeaderSignature, SignOutbound(del.Secret, id, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"backend/internal/crypto"
+)
+
+// payloadPIIKeys are the JSON keys whose string values are personal data,
+// wherever they appear in a payload: the addresses and numbers in Clerk's
+// user and email_address objects, and the email and phone of our outgoing
+// user events.
+var payloadPIIKeys = map[string]bool{
+	"email_address": true,
+	"phone_number":  true,
+	"email":         true,
+	"phone":         true,
+}
+
+// SealPayload encrypts the personal data in a webhook payload before it is
+// stored, leaving the rest of the document, e.g. the object IDs erasure
+// matches on, readable. With a nil keyring the payload is stored as is.
+func SealPayload(keys *crypto.Keyring, payload []byte) ([]byte, error) {
+	if keys == nil {
+		return payload, nil
+	}
+	return rewritePII(payload, keys.Encrypt)
+}
+
+// OpenPayload reverses SealPayload. Payloads stored before encryption was
+// enabled come back unchanged.
+func OpenPayload(keys *crypto.Keyring, payload []byte) ([]byte, error) {
+	if !bytes.Contains(payload, []byte(`"enc:`)) {
+		return payload, nil
+	}
+	return rewritePII(payload, keys.Decrypt)
+}
+
+func rewritePII(payload []byte, f func(string) (string, error)) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := walkPII(doc, f); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func walkPII(v any, f func(string) (string, error)) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok && payloadPIIKeys[k] {
+				out, err := f(s)
+				if err != nil {
+					return err
+				}
+				v[k] = out
+				continue
+			}
+			if err := walkPII(child, f); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if err := walkPII(child, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"backend/internal/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ClerkSession is the data object of session.* events.
+type ClerkSession struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// CreatedAt is milliseconds since the epoch.
+	CreatedAt int64 `json:"created_at"`
+}
+
+type sessionHandlers struct {
+	q *db.Queries
+}
+
+func (h *sessionHandlers) created(ctx context.Context, _ string, s ClerkSession) error {
+	userID := strings.TrimSpace(s.UserID)
+
+	// Use the session's own timestamp rather than NOW(): the event may be
+	// processed long after the sign-in happened.
+	loggedInAt := time.Now()
+	if s.CreatedAt > 0 {
+		loggedInAt = time.UnixMilli(s.CreatedAt)
+	}
+	return h.q.UpdateLastLogin(ctx, db.UpdateLastLoginParams{
+		ClerkID:    userID,
+		LoggedInAt: pgtype.Timestamptz{Time: loggedInAt, Valid: true},
+	})
+}
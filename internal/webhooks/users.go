@@ -0,0 +1,192 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ClerkUser is the data object of user.* events.
+type ClerkUser struct {
+	ID                    string `json:"id"`
+	Username              string `json:"username"`
+	FirstName             string `json:"first_name"`
+	LastName              string `json:"last_name"`
+	PrimaryEmailAddressID string `json:"primary_email_address_id"`
+	EmailAddresses        []struct {
+		ID           string `json:"id"`
+		EmailAddress string `json:"email_address"`
+	} `json:"email_addresses"`
+	// PublicMetadata is only writable through Clerk's Backend API. Users
+	// invited via POST /admin/users carry their intended role here.
+	PublicMetadata json.RawMessage `json:"public_metadata"`
+	// UpdatedAt is Clerk's modification time in milliseconds; it lets stale
+	// deliveries be skipped.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// ClerkUserFromAPI converts a user fetched from Clerk's Backend API. The API
+// and webhooks share the user JSON shape.
+func ClerkUserFromAPI(u *clerk.User) (ClerkUser, error) {
+	var out ClerkUser
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// MetadataRole returns public_metadata.role, or "" when it is absent or not
+// a string. Metadata is free-form, so it is decoded leniently.
+func (u ClerkUser) MetadataRole() string {
+	var md struct {
+		Role string `json:"role"`
+	}
+	_ = json.Unmarshal(u.PublicMetadata, &md)
+	return md.Role
+}
+
+// DisplayName derives the users.name column: first + last name, falling back
+// to the username and finally "User".
+func (u ClerkUser) DisplayName() string {
+	name := strings.TrimSpace(strings.TrimSpace(u.FirstName) + " " + strings.TrimSpace(u.LastName))
+	if name == "" {
+		name = strings.TrimSpace(u.Username)
+	}
+	if name == "" {
+		name = "User"
+	}
+	return name
+}
+
+// PrimaryEmail returns the primary address, or the first non-empty one.
+func (u ClerkUser) PrimaryEmail() string {
+	if u.PrimaryEmailAddressID != "" {
+		for _, e := range u.EmailAddresses {
+			if e.ID == u.PrimaryEmailAddressID && strings.TrimSpace(e.EmailAddress) != "" {
+				return strings.ToLower(strings.TrimSpace(e.EmailAddress))
+			}
+		}
+	}
+	for _, e := range u.EmailAddresses {
+		if strings.TrimSpace(e.EmailAddress) != "" {
+			return strings.ToLower(strings.TrimSpace(e.EmailAddress))
+		}
+	}
+	return ""
+}
+
+// UpsertParams maps the Clerk user onto the users row.
+func (u ClerkUser) UpsertParams() db.UpsertUserWithRoleParams {
+	params := db.UpsertUserWithRoleParams{
+		ClerkID:   strings.TrimSpace(u.ID),
+		Username:  ToText(u.Username),
+		Name:      u.DisplayName(),
+		Email:     ToText(u.PrimaryEmail()),
+		FirstName: ToText(u.FirstName),
+		LastName:  ToText(u.LastName),
+	}
+	if u.UpdatedAt > 0 {
+		params.ClerkUpdatedAt = pgtype.Timestamptz{Time: time.UnixMilli(u.UpdatedAt), Valid: true}
+	}
+	switch role := u.MetadataRole(); role {
+	case "user", "admin", "superadmin":
+		params.InitialRole = ToText(role)
+	}
+	return params
+}
+
+// SealEmail prepares an email column value for storage: it returns the
+// address encrypted with keys together with its blind index. With a nil
+// keyring the address is stored in plaintext and has no index.
+func SealEmail(keys *crypto.Keyring, email pgtype.Text) (sealed, bidx pgtype.Text, err error) {
+	sealed, err = keys.EncryptText(email)
+	return sealed, keys.BlindIndexText(email), err
+}
+
+// UserEventData is the data object of outgoing user.* events.
+type UserEventData struct {
+	ClerkID   string `json:"clerk_id"`
+	Name      string `json:"name,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// userHandlers apply each event in one transaction: the user row, its
+// activity record and any outbox deliveries are committed together or not at
+// all, so a retry never re-announces a change or misses one.
+type userHandlers struct {
+	pool   *pgxpool.Pool
+	outbox *Outbox
+	keys   *crypto.Keyring
+}
+
+// upsert mirrors the user and re-emits the event ("user.created" or
+// "user.updated") to our own subscribers.
+func (h *userHandlers) upsert(ctx context.Context, eventType string, u ClerkUser) error {
+	params := u.UpsertParams()
+	data := UserEventData{
+		ClerkID:   params.ClerkID,
+		Name:      params.Name,
+		FirstName: params.FirstName.String,
+		LastName:  params.LastName.String,
+		Email:     params.Email.String,
+		Username:  params.Username.String,
+	}
+	var err error
+	if params.Email, params.EmailBidx, err = SealEmail(h.keys, params.Email); err != nil {
+		return err
+	}
+	return db.WithTx(ctx, h.pool, func(q *db.Queries) error {
+		n, err := q.UpsertUserWithRole(ctx, params)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// A stale delivery, or the user was erased: nothing changed, so
+			// there is nothing to record or re-emit.
+			return nil
+		}
+		if err := recordUserEvent(ctx, q, params.ClerkID, eventType); err != nil {
+			return err
+		}
+		return h.outbox.WithTx(q).Emit(ctx, eventType, data)
+	})
+}
+
+func (h *userHandlers) delete(ctx context.Context, eventType string, u ClerkUser) error {
+	id := strings.TrimSpace(u.ID)
+	return db.WithTx(ctx, h.pool, func(q *db.Queries) error {
+		if err := q.SoftDeleteUserByClerkID(ctx, id); err != nil {
+			return err
+		}
+		if err := recordUserEvent(ctx, q, id, eventType); err != nil {
+			return err
+		}
+		return h.outbox.WithTx(q).Emit(ctx, eventType, UserEventData{ClerkID: id})
+	})
+}
+
+func (h *userHandlers) setBanned(ctx context.Context, eventType string, u ClerkUser) error {
+	id := strings.TrimSpace(u.ID)
+	return db.WithTx(ctx, h.pool, func(q *db.Queries) error {
+		if _, err := q.SetUserBanned(ctx, db.SetUserBannedParams{
+			ClerkID: id,
+			Banned:  eventType == "user.banned",
+		}); err != nil {
+			return err
+		}
+		return recordUserEvent(ctx, q, id, eventType)
+	})
+}
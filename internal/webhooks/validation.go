@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// Error codes recorded in webhook_events.error_code for dead-lettered
+// events.
+const (
+	CodeMalformedJSON = "malformed_json"
+	CodeMissingField  = "missing_field"
+	CodeInvalidEmail  = "invalid_email"
+)
+
+// ValidationError describes why a payload was rejected. It wraps
+// ErrBadPayload so the worker dead-letters it without retrying.
+type ValidationError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Code + ": " + e.Message
+	}
+	return e.Code + ": " + e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error { return ErrBadPayload }
+
+// ErrorCode returns the machine-readable code for err, or "" when err is not
+// a payload problem.
+func ErrorCode(err error) string {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Code
+	}
+	if errors.Is(err, ErrBadPayload) {
+		return CodeMalformedJSON
+	}
+	return ""
+}
+
+// validator is implemented by payload structs that check their own required
+// fields. On runs it before the handler.
+type validator interface {
+	Validate(eventType string) error
+}
+
+func requireField(field, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return &ValidationError{Code: CodeMissingField, Field: field, Message: "is required"}
+	}
+	return nil
+}
+
+func requireEmail(field, value string) error {
+	if _, err := mail.ParseAddress(strings.TrimSpace(value)); err != nil {
+		return &ValidationError{Code: CodeInvalidEmail, Field: field, Message: "must be a valid address"}
+	}
+	return nil
+}
+
+func (u ClerkUser) Validate(eventType string) error {
+	if err := requireField("data.id", u.ID); err != nil {
+		return err
+	}
+	if eventType == "user.deleted" {
+		return nil
+	}
+	for _, e := range u.EmailAddresses {
+		if err := requireEmail("data.email_addresses.email_address", e.EmailAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s ClerkSession) Validate(string) error {
+	return requireField("data.user_id", s.UserID)
+}
+
+func (o ClerkOrganization) Validate(string) error {
+	return requireField("data.id", o.ID)
+}
+
+func (m ClerkMembership) Validate(eventType string) error {
+	if err := requireField("data.id", m.ID); err != nil {
+		return err
+	}
+	if eventType == "organizationMembership.deleted" {
+		return nil
+	}
+	if err := requireField("data.organization.id", m.Organization.ID); err != nil {
+		return err
+	}
+	return requireField("data.public_user_data.user_id", m.PublicUserData.UserID)
+}
@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"backend/internal/crypto"
+	"backend/internal/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// processedRetention is how long processed svix-ids are remembered for
+// de-duplication. Svix stops retrying a message well within this window.
+const processedRetention = 7 * 24 * time.Hour
+
+type WorkerConfig struct {
+	// PollInterval is how often the queue is checked when not notified.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events claimed per poll.
+	BatchSize int
+	// Lease is how long a claimed event is reserved before another worker
+	// may pick it up again.
+	Lease time.Duration
+	// MaxAttempts is the number of processing attempts before an event is
+	// dead-lettered.
+	MaxAttempts int
+	// BaseBackoff and MaxBackoff bound the exponential retry delay.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: 5 * time.Second,
+		BatchSize:    50,
+		Lease:        5 * time.Minute,
+		MaxAttempts:  8,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+// Worker drains the webhook_events queue in the background. Payloads are
+// stored sealed with keys and opened before dispatch.
+type Worker struct {
+	q       *db.Queries
+	d       *Dispatcher
+	cfg     WorkerConfig
+	metrics *Metrics
+	keys    *crypto.Keyring
+	wake    chan struct{}
+}
+
+func NewWorker(q *db.Queries, d *Dispatcher, cfg WorkerConfig, metrics *Metrics, keys *crypto.Keyring) *Worker {
+	return &Worker{q: q, d: d, cfg: cfg, metrics: metrics, keys: keys, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the worker after a new event was queued. It never blocks.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes due events until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	poll := time.NewTicker(w.cfg.PollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		w.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-poll.C:
+		case <-purge.C:
+			cutoff := pgtype.Timestamptz{Time: time.Now().Add(-processedRetention), Valid: true}
+			if _, err := w.q.DeleteProcessedWebhooksBefore(ctx, cutoff); err != nil {
+				log.Printf("webhooks: purging processed svix-ids failed: %v", err)
+			}
+		}
+	}
+}
+
+// drain claims and processes batches until the queue has nothing due.
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := w.q.ClaimDueWebhookEvents(ctx, db.ClaimDueWebhookEventsParams{
+			LeaseSeconds: int32(w.cfg.Lease / time.Second),
+			BatchSize:    int32(w.cfg.BatchSize),
+		})
+		if err != nil {
+			log.Printf("webhooks: claiming events failed: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+		for _, evt := range events {
+			w.handle(ctx, evt)
+		}
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, evt db.ClaimDueWebhookEventsRow) {
+	start := time.Now()
+	payload, err := OpenPayload(w.keys, evt.Payload)
+	if err == nil {
+		err = w.d.Dispatch(ctx, payload)
+	}
+	elapsed := time.Since(start)
+	if err == nil {
+		w.metrics.Processed(evt.EventType, elapsed)
+		if err := w.q.MarkWebhookEventProcessed(ctx, evt.ID); err != nil {
+			log.Printf("webhooks: marking event %d processed failed: %v", evt.ID, err)
+			return
+		}
+		if err := w.q.MarkWebhookProcessed(ctx, db.MarkWebhookProcessedParams{
+			SvixID:    evt.SvixID,
+			EventType: evt.EventType,
+		}); err != nil {
+			log.Printf("webhooks: recording svix-id %s failed: %v", evt.SvixID, err)
+		}
+		return
+	}
+
+	// Invalid payloads will never succeed; dead-letter them right away with
+	// their validation error code.
+	dead := errors.Is(err, ErrBadPayload) || int(evt.Attempts) >= w.cfg.MaxAttempts
+	w.metrics.Failed(evt.EventType, dead, elapsed)
+	if dead {
+		log.Printf("webhooks: event %d (%s) dead-lettered after %d attempts: %v", evt.ID, evt.EventType, evt.Attempts, err)
+		if err := w.q.MarkWebhookEventDead(ctx, db.MarkWebhookEventDeadParams{
+			ID:        evt.ID,
+			Error:     ToText(err.Error()),
+			ErrorCode: ToText(ErrorCode(err)),
+		}); err != nil {
+			log.Printf("webhooks: dead-lettering event %d failed: %v", evt.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(backoff(w.cfg.BaseBackoff, w.cfg.MaxBackoff, int(evt.Attempts)))
+	if err := w.q.MarkWebhookEventFailed(ctx, db.MarkWebhookEventFailedParams{
+		ID:            evt.ID,
+		Error:         ToText(err.Error()),
+		NextAttemptAt: pgtype.Timestamptz{Time: next, Valid: true},
+	}); err != nil {
+		log.Printf("webhooks: recording failure of event %d failed: %v", evt.ID, err)
+	}
+}
+
+// backoff returns base * 2^(attempt-1), capped at ceiling.
+func backoff(base, ceiling time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= ceiling {
+			return ceiling
+		}
+	}
+	return d
+}
@@ -2,338 +2,397 @@ package main
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
+	"backend/internal/auth/jwks"
+	"backend/internal/config"
 	"backend/internal/db"
+	httpapi "backend/internal/http"
+	"backend/internal/logging"
+	"backend/internal/pglisten"
+	"backend/internal/privacy"
+	"backend/internal/report"
+	"backend/internal/requestid"
+	"backend/internal/storage"
+	"backend/internal/tracing"
+	"backend/internal/webhooks"
+	"backend/migrations"
 
 	clerkSDK "github.com/clerk/clerk-sdk-go/v2"
-	"github.com/clerk/clerk-sdk-go/v2/jwt"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
-	"golang.org/x/time/rate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 )
 
-// User is kept in sync with db.ListUsersRow as an API contract reference.
-// The /users handler serializes db.ListUsersRow directly; this struct is not
-// used for actual responses.
-type User struct {
-	ClerkID     string `json:"clerk_id,omitempty"`
-	Name        string `json:"name"`
-	FirstName   string `json:"first_name,omitempty"`
-	LastName    string `json:"last_name,omitempty"`
-	Email       string `json:"email,omitempty"`
-	Username    string `json:"username,omitempty"`
-	Role        string `json:"role"`
-	IsActive    bool   `json:"is_active"`
-	CreatedAt   string `json:"created_at,omitempty"`
-	UpdatedAt   string `json:"updated_at,omitempty"`
-	DeletedAt   string `json:"deleted_at,omitempty"`
-	LastLoginAt string `json:"last_login_at,omitempty"`
-}
-
-type ClerkWebhookEvent struct {
-	Type string `json:"type"`
-	Data struct {
-		ID                    string `json:"id"`
-		Username              string `json:"username"`
-		FirstName             string `json:"first_name"`
-		LastName              string `json:"last_name"`
-		PrimaryEmailAddressID string `json:"primary_email_address_id"`
-		EmailAddresses        []struct {
-			ID           string `json:"id"`
-			EmailAddress string `json:"email_address"`
-		} `json:"email_addresses"`
-	} `json:"data"`
-}
-
-func toText(s string) pgtype.Text {
-	s = strings.TrimSpace(s)
-	return pgtype.Text{String: s, Valid: s != ""}
-}
+func main() {
+	_ = godotenv.Load()
 
-func pickClerkEmail(evt ClerkWebhookEvent) string {
-	if evt.Data.PrimaryEmailAddressID != "" {
-		for _, e := range evt.Data.EmailAddresses {
-			if e.ID == evt.Data.PrimaryEmailAddressID && strings.TrimSpace(e.EmailAddress) != "" {
-				return strings.ToLower(strings.TrimSpace(e.EmailAddress))
-			}
-		}
+	// Every setting is validated up front; a bad deploy lists all of its
+	// problems at once instead of failing on them one restart at a time.
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	for _, e := range evt.Data.EmailAddresses {
-		if strings.TrimSpace(e.EmailAddress) != "" {
-			return strings.ToLower(strings.TrimSpace(e.EmailAddress))
-		}
+	// The level can change at runtime; see reloadSettings.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.Log.Level)
+	logging.Setup(logLevel, cfg.Log.Format)
+	slog.Info("config: loaded", "config", cfg)
+
+	// Panics and 5xx responses are reported only when SENTRY_DSN is set.
+	reporting, err := report.Init(cfg.Sentry)
+	if err != nil {
+		panic(err)
 	}
-	return ""
-}
-
-// Minimal Svix verification for Clerk webhooks.
-func verifySvix(body []byte, secret, svixID, svixTimestamp, svixSignature string) bool {
-	if secret == "" || svixID == "" || svixTimestamp == "" || svixSignature == "" {
-		return false
+	if reporting {
+		log.Printf("report: sending errors to Sentry")
+		defer report.Flush(2 * time.Second)
 	}
 
-	parts := strings.SplitN(secret, "_", 2)
-	if len(parts) != 2 {
-		return false
-	}
-	key, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		return false
+	// Release mode drops gin's per-route debug output.
+	gin.SetMode(cfg.GinMode)
+
+	clerkSDK.SetKey(cfg.Clerk.SecretKey)
+	// Clerk API calls made while serving a request forward its X-Request-ID.
+	clerkSDK.SetBackend(clerkSDK.NewBackend(&clerkSDK.BackendConfig{
+		HTTPClient: &http.Client{Timeout: 5 * time.Second, Transport: requestid.Transport{}},
+	}))
+
+	dsn := cfg.Database.URL
+	// WEBHOOK_DEV_MODE additionally accepts webhooks.DevSecret so payloads
+	// signed by cmd/devhooks can be posted without a Clerk tunnel.
+	webhookSecrets := cfg.Clerk.WebhookSecrets
+	if cfg.Clerk.WebhookDevMode {
+		log.Printf("webhooks: dev mode enabled, accepting the local dev secret")
+		webhookSecrets = append(webhookSecrets, webhooks.DevSecret)
 	}
 
-	msg := svixID + "." + svixTimestamp + "." + string(body)
-	mac := hmac.New(sha256.New, key)
-	_, _ = mac.Write([]byte(msg))
-	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	for _, token := range strings.Split(svixSignature, " ") {
-		p := strings.SplitN(token, ",", 2) // e.g. "v1,abc..."
-		if len(p) == 2 && p[0] == "v1" && hmac.Equal([]byte(p[1]), []byte(expected)) {
-			return true
+	// Object storage is optional; without S3_BUCKET the avatar endpoints are
+	// not registered.
+	var store *storage.S3
+	if cfg.Storage != nil {
+		s, err := storage.New(*cfg.Storage)
+		if err != nil {
+			panic(err)
 		}
+		store = s
 	}
-	return false
-}
 
-type clientLimiter struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
-
-type limiterStore struct {
-	mu      sync.Mutex
-	clients map[string]*clientLimiter
-	r       rate.Limit
-	burst   int
-}
-
-func newLimiterStore(r rate.Limit, burst int) *limiterStore {
-	ls := &limiterStore{
-		clients: make(map[string]*clientLimiter),
-		r:       r,
-		burst:   burst,
+	// PII_ENCRYPTION_KEYS is optional; without it email and phone are
+	// stored in plaintext.
+	piiKeys := cfg.PIIKeys
+	if piiKeys != nil {
+		log.Printf("pii: encrypting with key %q", piiKeys.ActiveKeyID())
 	}
 
-	go func() {
-		t := time.NewTicker(2 * time.Minute)
-		defer t.Stop()
-		for range t.C {
-			ls.mu.Lock()
-			for ip, c := range ls.clients {
-				if time.Since(c.lastSeen) > 10*time.Minute {
-					delete(ls.clients, ip)
-				}
-			}
-			ls.mu.Unlock()
+	// Tracing is exported only when an OTLP endpoint is configured; see
+	// tracing.Setup for the OTEL_* variables it reads.
+	shutdownTracing, tracingEnabled, err := tracing.Setup(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("tracing: shutdown: %v", err)
 		}
 	}()
+	if tracingEnabled {
+		log.Printf("tracing: exporting spans over OTLP")
+	}
 
-	return ls
-}
+	// Queries slower than DB_SLOW_QUERY_THRESHOLD are logged; DB_LOG_QUERIES
+	// logs every query, for debugging.
+	tracer := &db.QueryTracer{
+		SlowThreshold: cfg.Database.SlowQueryThreshold,
+		Verbose:       cfg.Database.LogQueries,
+	}
+	// Every query is also a span under its request's trace.
+	queryTracer := multitracer.New(tracer, db.NewOTelTracer())
+
+	// DB_STATEMENT_TIMEOUT caps every statement, both on the server
+	// (statement_timeout) and through the query's context.
+	statementTimeout := cfg.Database.StatementTimeout
+	statementTimeoutMS := strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+
+	migrateOpts := cfg.Database.Migrations
+	// AUTO_MIGRATE applies pending migrations before anything touches the
+	// schema, for deployments without a separate cmd/migrate step.
+	if cfg.Database.AutoMigrate {
+		version, err := migrations.Up(dsn, migrateOpts)
+		if err != nil {
+			panic("auto-migrate failed: " + err.Error())
+		}
+		log.Printf("migrate: schema at version %d", version)
+	}
 
-func (ls *limiterStore) get(ip string) *rate.Limiter {
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	// Created after migrations, which may run far longer, so the timeout only
+	// covers connecting and checking the schema.
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
 
-	if c, ok := ls.clients[ip]; ok {
-		c.lastSeen = time.Now()
-		return c.limiter
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		panic(err)
 	}
+	poolCfg.ConnConfig.Tracer = queryTracer
+	poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = statementTimeoutMS
+	if err := cfg.Database.Pool.Apply(poolCfg); err != nil {
+		panic(err)
+	}
+	log.Printf("db: pool %s", db.DescribePool(poolCfg))
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		panic(err)
+	}
+	defer pool.Close()
 
-	lim := rate.NewLimiter(ls.r, ls.burst)
-	ls.clients[ip] = &clientLimiter{limiter: lim, lastSeen: time.Now()}
-	return lim
-}
-
-func rateLimitMiddleware(ls *limiterStore) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		if !ls.get(ip).Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
-			c.Abort()
-			return
-		}
-		c.Next()
+	if err := pool.Ping(ctx); err != nil {
+		panic(err)
+	}
+	// Fail fast on a database that is missing tables or columns the
+	// queries use, rather than on the first request that touches them.
+	if err := db.CheckSchema(ctx, pool); err != nil {
+		panic(err)
 	}
-}
 
-// clerkAuthMiddleware verifies the Clerk session JWT and enforces that the
-// caller has role "superadmin" or "admin" in the database.
-func clerkAuthMiddleware(q *db.Queries) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
-			return
-		}
+	prometheus.MustRegister(db.NewPoolCollector("primary", pool))
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+	// Queries on the pool retry transient failures; DB_RETRY_ATTEMPTS=1
+	// disables retries.
+	q := db.New(db.NewTimeoutDB(db.NewRetryPool(pool, cfg.Database.Retry), statementTimeout))
 
-		// Verify JWT against Clerk's JWKS endpoint.
-		claims, err := jwt.Verify(c.Request.Context(), &jwt.VerifyParams{Token: token})
+	// DATABASE_REPLICA_URL is optional. An unreachable replica does not stop
+	// startup; reads fall back to the primary until it answers.
+	var replica *pgxpool.Pool
+	if v := cfg.Database.ReplicaURL; v != "" {
+		replicaCfg, err := pgxpool.ParseConfig(v)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
-			return
+			panic("DATABASE_REPLICA_URL is invalid: " + err.Error())
 		}
-
-		clerkID := claims.Subject
-
-		if clerkID == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token subject"})
-			return
+		replicaCfg.ConnConfig.Tracer = queryTracer
+		replicaCfg.ConnConfig.RuntimeParams["statement_timeout"] = statementTimeoutMS
+		if err := cfg.Database.Pool.Apply(replicaCfg); err != nil {
+			panic(err)
 		}
-
-		// Look up the caller's role in the database.
-		role, err := q.GetUserRole(c.Request.Context(), clerkID)
+		replica, err = pgxpool.NewWithConfig(ctx, replicaCfg)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user not found or inactive"})
-			return
+			panic(err)
 		}
-
-		if role != "superadmin" && role != "admin" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
-			return
+		defer replica.Close()
+		if err := replica.Ping(ctx); err != nil {
+			log.Printf("db: initial replica ping failed: %v", err)
 		}
-
-		// Store caller identity in context for downstream handlers.
-		c.Set("clerk_id", clerkID)
-		c.Set("role", role)
-		c.Next()
+		prometheus.MustRegister(db.NewPoolCollector("replica", replica))
 	}
-}
 
-func main() {
-	_ = godotenv.Load()
+	// Background goroutines run until the HTTP server has drained on
+	// shutdown, and are waited for before the pools close.
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var workers sync.WaitGroup
 
-	clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
-	if clerkSecretKey == "" {
-		panic("CLERK_SECRET_KEY is not set")
+	keys := jwks.New(jwks.ClerkFetcher, time.Hour)
+	if err := keys.Start(background); err != nil {
+		log.Printf("jwks: initial fetch failed, will retry: %v", err)
 	}
-	clerkSDK.SetKey(clerkSecretKey)
 
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		panic("DATABASE_URL is required")
-	}
-	webhookSecret := os.Getenv("CLERK_WEBHOOK_SECRET")
-	if webhookSecret == "" {
-		panic("CLERK_WEBHOOK_SECRET is required")
+	dispatcher := webhooks.NewDispatcher()
+	webhooks.RegisterClerkHandlers(dispatcher, pool, webhooks.NewOutbox(q, piiKeys), piiKeys)
+	webhookMetrics := webhooks.NewMetrics(prometheus.DefaultRegisterer, dispatcher)
+	worker := webhooks.NewWorker(q, dispatcher, webhooks.DefaultWorkerConfig(), webhookMetrics, piiKeys)
+	workers.Go(func() { worker.Run(background) })
+	deliverer := webhooks.NewDeliverer(q, nil, webhooks.DefaultWorkerConfig(), piiKeys)
+	workers.Go(func() { deliverer.Run(background) })
+
+	// Webhooks queued by another replica wake this replica's worker too. A
+	// missed notification only delays processing until the next poll.
+	listener := pglisten.New(dsn)
+	listener.Handle("webhook_events", func(string) { worker.Notify() })
+	listener.OnReconnect(worker.Notify)
+	workers.Go(func() { listener.Run(background) })
+
+	// REDIS_URL is optional; without it rate limits are per replica.
+	var redisClient *redis.Client
+	if v := cfg.RedisURL; v != "" {
+		opts, err := redis.ParseURL(v)
+		if err != nil {
+			panic("REDIS_URL is invalid: " + err.Error())
+		}
+		redisClient = redis.NewClient(opts)
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			log.Printf("redis: initial ping failed, rate limits fall back to in-memory: %v", err)
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
-	defer cancel()
+	eraser := privacy.NewEraser(pool, q, store)
+	workers.Go(func() { eraser.Run(background) })
 
-	pool, err := pgxpool.New(ctx, dsn)
+	schemaVersion, err := migrations.Latest()
 	if err != nil {
 		panic(err)
 	}
-	defer pool.Close()
 
-	if err := pool.Ping(ctx); err != nil {
-		panic(err)
+	// The log level, rate limits, feature flags and maintenance mode are
+	// re-read from the environment on SIGHUP or POST
+	// /admin/settings/reload. The .env file then takes precedence over the
+	// environment, which cannot change after start.
+	reloadSettings := func() (httpapi.RuntimeSettings, error) {
+		_ = godotenv.Overload()
+		cfg, err := config.Load()
+		if err != nil {
+			return httpapi.RuntimeSettings{}, err
+		}
+		return cfg.Runtime(), nil
 	}
 
-	q := db.New(pool)
-
-	r := gin.Default()
-	r.Use(rateLimitMiddleware(newLimiterStore(10, 20))) // 10 req/sec per IP, burst 20
-
-	r.GET("/health", func(c *gin.Context) {
-		var v int
-		if err := pool.QueryRow(c.Request.Context(), "SELECT 1").Scan(&v); err != nil {
-			c.JSON(http.StatusOK, gin.H{"status": "degraded", "db": "down"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "db": "up"})
+	r := httpapi.NewRouter(httpapi.Config{
+		Pool:                  pool,
+		Queries:               q,
+		ReadPool:              db.NewReadPool(pool, replica),
+		SchemaVersion:         schemaVersion,
+		MigrationsTable:       migrateOpts.TableName(),
+		JWKS:                  keys,
+		WebhookWorker:         worker,
+		WebhookListener:       listener,
+		WebhookMetrics:        webhookMetrics,
+		WebhookSecrets:        webhookSecrets,
+		WebhookTolerance:      cfg.Clerk.WebhookTolerance,
+		LogLevel:              logLevel,
+		Features:              cfg.Features,
+		Maintenance:           cfg.Maintenance,
+		ReloadSettings:        reloadSettings,
+		InternalSigningSecret: []byte(cfg.InternalSigningSecret),
+		TrustedProxies:        cfg.HTTP.TrustedProxies,
+		CORS:                  cfg.HTTP.CORS,
+		RateLimits:            cfg.HTTP.RateLimits,
+		Redis:                 redisClient,
+		RateLimitMetrics:      httpapi.NewRateLimitMetrics(prometheus.DefaultRegisterer),
+		AccessLog:             cfg.HTTP.AccessLog,
+		HTTPMetrics:           httpapi.NewHTTPMetrics(prometheus.DefaultRegisterer),
+		SlowRequests:          httpapi.NewSlowRequests(cfg.HTTP.SlowRequestThreshold),
+		Metrics:               cfg.HTTP.Metrics,
+		Storage:               store,
+		Eraser:                eraser,
+		PIIKeys:               piiKeys,
+		MaxInFlight:           cfg.HTTP.MaxInFlight,
+		RequestTimeouts:       cfg.HTTP.RequestTimeouts,
+		Compression:           cfg.HTTP.Compression,
+		MaxBodyBytes:          cfg.HTTP.MaxBodyBytes,
+		WebhookMaxBodyBytes:   cfg.HTTP.WebhookMaxBodyBytes,
+		AvatarMaxBodyBytes:    cfg.HTTP.AvatarMaxBodyBytes,
 	})
 
-	r.GET("/users", clerkAuthMiddleware(q), func(c *gin.Context) {
-		users, err := q.ListUsers(c.Request.Context())
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve users"})
-			return
-		}
-		if users == nil {
-			users = []db.ListUsersRow{}
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go func() {
+		for range hangups {
+			if err := r.Settings.Reload(context.Background()); err != nil {
+				slog.Error("settings: reload failed, keeping the current settings", "error", err)
+			}
 		}
-		c.JSON(http.StatusOK, users)
-	})
+	}()
 
-	r.POST("/webhooks/clerk", func(c *gin.Context) {
-		body, err := io.ReadAll(c.Request.Body)
+	// DEBUG_ADDR, e.g. 127.0.0.1:6060, additionally serves pprof and expvar
+	// without authentication on a loopback-only listener.
+	var debugSrv *http.Server
+	if addr := cfg.HTTP.DebugAddr; addr != "" {
+		debugSrv, err = httpapi.NewDebugServer(addr)
 		if err != nil {
-			c.Status(http.StatusBadRequest)
-			return
-		}
-
-		if !verifySvix(
-			body,
-			webhookSecret,
-			c.GetHeader("svix-id"),
-			c.GetHeader("svix-timestamp"),
-			c.GetHeader("svix-signature"),
-		) {
-			c.Status(http.StatusUnauthorized)
-			return
-		}
-
-		var evt ClerkWebhookEvent
-		if err := json.Unmarshal(body, &evt); err != nil {
-			c.Status(http.StatusBadRequest)
-			return
+			panic("DEBUG_ADDR: " + err.Error())
 		}
+		go func() {
+			log.Printf("debug: serving pprof on %s", addr)
+			if err := debugSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("debug: listener stopped: %v", err)
+			}
+		}()
+	}
 
-		name := strings.TrimSpace(strings.TrimSpace(evt.Data.FirstName) + " " + strings.TrimSpace(evt.Data.LastName))
-		if name == "" {
-			name = strings.TrimSpace(evt.Data.Username)
-		}
-		if name == "" {
-			name = "User"
+	// On SIGTERM or SIGINT readiness fails first; after SHUTDOWN_DELAY, long
+	// enough for the load balancer to notice, the server stops accepting
+	// connections and waits up to SHUTDOWN_TIMEOUT for in-flight requests.
+	// A second signal exits immediately.
+	shutdownDelay := cfg.HTTP.ShutdownDelay
+	shutdownTimeout := cfg.HTTP.ShutdownTimeout
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignals()
+
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.HTTP.Port),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+	}
+	serveErr := make(chan error, 1)
+	// With TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS the API serves HTTPS
+	// itself, for deployments without a terminating proxy.
+	var challengeSrv *http.Server
+	if cfg.HTTP.TLS.Enabled() {
+		challengeSrv, err = cfg.HTTP.TLS.Configure(srv)
+		if err != nil {
+			panic(err)
 		}
-		email := pickClerkEmail(evt)
+		go func() { serveErr <- srv.ListenAndServeTLS("", "") }()
+		log.Printf("http: listening on %s with TLS", srv.Addr)
+	} else {
+		go func() { serveErr <- srv.ListenAndServe() }()
+		log.Printf("http: listening on %s", srv.Addr)
+	}
+	if challengeSrv != nil {
+		go func() { serveErr <- challengeSrv.ListenAndServe() }()
+		log.Printf("http: answering ACME challenges on %s", challengeSrv.Addr)
+	}
 
-		switch evt.Type {
-		case "user.created", "user.updated":
-			if strings.TrimSpace(evt.Data.ID) == "" {
-				c.JSON(http.StatusOK, gin.H{"ok": true, "ignored": "missing id", "type": evt.Type})
-				return
-			}
-			if err := q.UpsertUserWithRole(c.Request.Context(), db.UpsertUserWithRoleParams{
-				ClerkID:   strings.TrimSpace(evt.Data.ID),
-				Username:  toText(evt.Data.Username),
-				Name:      name,
-				Email:     toText(email),
-				FirstName: toText(evt.Data.FirstName),
-				LastName:  toText(evt.Data.LastName),
-			}); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-		case "user.deleted":
-			if strings.TrimSpace(evt.Data.ID) != "" {
-				if err := q.SoftDeleteUserByClerkID(c.Request.Context(), strings.TrimSpace(evt.Data.ID)); err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-					return
-				}
-			}
-		}
+	select {
+	case err := <-serveErr:
+		panic(err)
+	case <-signals.Done():
+	}
+	stopSignals()
+	log.Printf("shutdown: draining")
 
-		c.JSON(http.StatusOK, gin.H{"ok": true, "type": evt.Type})
-	})
+	r.Health.Drain()
+	time.Sleep(shutdownDelay)
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("shutdown: requests still in flight after %s, closing: %v", shutdownTimeout, err)
+		_ = srv.Close()
+	}
+	if challengeSrv != nil {
+		_ = challengeSrv.Close()
+	}
+	if debugSrv != nil {
+		_ = debugSrv.Close()
+	}
 
-	if err := r.Run(":8080"); err != nil {
-		panic(err)
+	// Then the background work, and only then the connections it uses;
+	// the pools close in the deferred calls above.
+	r.Close()
+	stopBackground()
+	workers.Wait()
+	if redisClient != nil {
+		_ = redisClient.Close()
 	}
+	log.Printf("shutdown: complete")
 }
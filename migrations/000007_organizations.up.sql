@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    clerk_org_id TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    slug         TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at   TIMESTAMPTZ
+);
+
+-- Memberships reference users and organizations by Clerk ID without foreign
+-- keys: Clerk does not guarantee webhook delivery order, so a membership can
+-- arrive before the user or organization it points to.
+CREATE TABLE IF NOT EXISTS memberships (
+    clerk_membership_id TEXT PRIMARY KEY,
+    clerk_org_id        TEXT NOT NULL,
+    clerk_user_id       TEXT NOT NULL,
+    role                TEXT NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS memberships_org_user_uq ON memberships(clerk_org_id, clerk_user_id);
+CREATE INDEX IF NOT EXISTS memberships_user_idx ON memberships(clerk_user_id);
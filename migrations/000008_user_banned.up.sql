@@ -0,0 +1,2 @@
+-- banned_at is NULL for users in good standing.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+-- One row per successfully processed Clerk webhook delivery, keyed by the
+-- svix-id header. Rows older than the retention window are purged by the API.
+CREATE TABLE IF NOT EXISTS processed_webhooks (
+    svix_id      TEXT PRIMARY KEY,
+    event_type   TEXT NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS processed_webhooks_processed_at_idx ON processed_webhooks(processed_at);
@@ -0,0 +1,15 @@
+-- Every verified Clerk webhook payload, kept for audit and re-processing.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id           BIGSERIAL PRIMARY KEY,
+    svix_id      TEXT NOT NULL UNIQUE,
+    event_type   TEXT NOT NULL,
+    payload      JSONB NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'received' CHECK (status IN ('received', 'processed', 'failed')),
+    error        TEXT,
+    attempts     INTEGER NOT NULL DEFAULT 1,
+    received_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_status_idx ON webhook_events(status, received_at);
+CREATE INDEX IF NOT EXISTS webhook_events_type_idx ON webhook_events(event_type, received_at);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS webhook_events_due_idx;
+
+UPDATE webhook_events SET status = 'failed' WHERE status IN ('processing', 'dead');
+ALTER TABLE webhook_events DROP CONSTRAINT IF EXISTS webhook_events_status_check;
+ALTER TABLE webhook_events ADD CONSTRAINT webhook_events_status_check
+    CHECK (status IN ('received', 'processed', 'failed'));
+
+ALTER TABLE webhook_events ALTER COLUMN attempts SET DEFAULT 1;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS next_attempt_at;
@@ -0,0 +1,17 @@
+-- webhook_events doubles as the processing queue: the API inserts rows and a
+-- background worker claims due rows, retrying with backoff until they are
+-- processed or moved to the dead-letter state.
+ALTER TABLE webhook_events DROP CONSTRAINT IF EXISTS webhook_events_status_check;
+ALTER TABLE webhook_events ADD CONSTRAINT webhook_events_status_check
+    CHECK (status IN ('received', 'processing', 'processed', 'failed', 'dead'));
+
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- attempts now counts processing attempts rather than deliveries.
+ALTER TABLE webhook_events ALTER COLUMN attempts SET DEFAULT 0;
+UPDATE webhook_events SET attempts = 0 WHERE status = 'received';
+
+DROP INDEX IF EXISTS webhook_events_status_idx;
+CREATE INDEX IF NOT EXISTS webhook_events_status_idx ON webhook_events(status, received_at);
+CREATE INDEX IF NOT EXISTS webhook_events_due_idx ON webhook_events(next_attempt_at)
+    WHERE status IN ('received', 'processing', 'failed');
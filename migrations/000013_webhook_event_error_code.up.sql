@@ -0,0 +1,2 @@
+-- Machine-readable reason for dead-lettered events, e.g. 'missing_field'.
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS error_code TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS webhook_events_ordering_idx;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS occurred_at;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS ordering_key;
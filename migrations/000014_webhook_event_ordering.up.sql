@@ -0,0 +1,13 @@
+-- Events touching the same Clerk object (usually a user) share an
+-- ordering_key. The worker only claims the oldest unfinished event per key,
+-- so e.g. a retried user.created always runs before the user.updated behind
+-- it. occurred_at is Clerk's own event timestamp and decides which is oldest.
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS ordering_key TEXT;
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMPTZ;
+
+UPDATE webhook_events SET occurred_at = received_at WHERE occurred_at IS NULL;
+ALTER TABLE webhook_events ALTER COLUMN occurred_at SET DEFAULT NOW();
+ALTER TABLE webhook_events ALTER COLUMN occurred_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS webhook_events_ordering_idx ON webhook_events(ordering_key, occurred_at, id)
+    WHERE status IN ('received', 'processing', 'failed');
@@ -0,0 +1,8 @@
+-- Trigram indexes back the substring search on /users?q=.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS users_name_trgm_idx ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_email_trgm_idx ON users USING GIN (email gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_username_trgm_idx ON users USING GIN (username gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users(created_at DESC, clerk_id DESC) WHERE deleted_at IS NULL;
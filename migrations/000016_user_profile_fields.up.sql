@@ -0,0 +1,3 @@
+-- Profile fields owned by this service rather than synced from Clerk.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone  TEXT;
@@ -0,0 +1,3 @@
+-- avatar_key is the object key in the storage bucket; download URLs are
+-- presigned on demand rather than stored.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key TEXT;
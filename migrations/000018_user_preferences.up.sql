@@ -0,0 +1,2 @@
+-- Client settings, validated by the API against httpapi.Preferences.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferences JSONB NOT NULL DEFAULT '{}'::jsonb;
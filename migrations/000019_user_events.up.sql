@@ -0,0 +1,13 @@
+-- Activity log of significant changes to a user, for support staff.
+-- actor is "clerk" for webhook-driven changes, the acting user's clerk_id,
+-- or "api_key:<id>".
+CREATE TABLE IF NOT EXISTS user_events (
+    id         BIGSERIAL PRIMARY KEY,
+    clerk_id   TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    actor      TEXT NOT NULL,
+    details    JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS user_events_clerk_id_idx ON user_events(clerk_id, id DESC);
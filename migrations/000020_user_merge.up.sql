@@ -0,0 +1,3 @@
+-- merged_into is set on the duplicate when two accounts are merged. Such a
+-- row stays soft-deleted even if Clerk keeps sending updates for it.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS merged_into TEXT;
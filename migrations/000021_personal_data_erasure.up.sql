@@ -0,0 +1,19 @@
+-- erased_at marks users whose personal data was anonymized on request. Such
+-- rows are never repopulated by Clerk syncs.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
+
+-- Erasure requests are processed in the background by privacy.Eraser.
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id           BIGSERIAL PRIMARY KEY,
+    clerk_id     TEXT NOT NULL,
+    requested_by TEXT NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed')),
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+-- At most one open request per user; repeated requests return the open one.
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending_uq ON erasure_requests(clerk_id)
+    WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- version is bumped by every profile write so clients can send it back
+-- (If-Match) and have a concurrent change rejected instead of overwritten.
+-- clerk_updated_at is Clerk's own updated_at of the last applied sync, which
+-- lets an older webhook or backfill row be skipped.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS clerk_updated_at TIMESTAMPTZ;
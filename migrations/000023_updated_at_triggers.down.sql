@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_updated_at_idx;
+DROP TRIGGER IF EXISTS webhook_subscriptions_set_updated_at ON webhook_subscriptions;
+DROP TRIGGER IF EXISTS memberships_set_updated_at ON memberships;
+DROP TRIGGER IF EXISTS organizations_set_updated_at ON organizations;
+DROP TRIGGER IF EXISTS users_set_updated_at ON users;
+DROP FUNCTION IF EXISTS set_updated_at();
@@ -0,0 +1,27 @@
+-- Queries set updated_at explicitly; the trigger is a safety net so a write
+-- that forgets it still bumps the column the ETags and sync rely on.
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_set_updated_at ON users;
+CREATE TRIGGER users_set_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS organizations_set_updated_at ON organizations;
+CREATE TRIGGER organizations_set_updated_at BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS memberships_set_updated_at ON memberships;
+CREATE TRIGGER memberships_set_updated_at BEFORE UPDATE ON memberships
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS webhook_subscriptions_set_updated_at ON webhook_subscriptions;
+CREATE TRIGGER webhook_subscriptions_set_updated_at BEFORE UPDATE ON webhook_subscriptions
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- UsersFingerprint reads MAX(updated_at) on every GET /users.
+CREATE INDEX IF NOT EXISTS users_updated_at_idx ON users(updated_at);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS webhook_events_notify ON webhook_events;
+DROP FUNCTION IF EXISTS notify_webhook_event();
+DROP TRIGGER IF EXISTS users_notify_changed ON users;
+DROP FUNCTION IF EXISTS notify_user_changed();
@@ -0,0 +1,25 @@
+-- NOTIFY channels consumed through internal/pglisten:
+--   user_changed    payload is the clerk_id of an inserted, updated or deleted user
+--   webhook_events  payload is the id of a newly queued Clerk webhook, so every
+--                   replica's worker wakes up, not just the one that received it
+CREATE OR REPLACE FUNCTION notify_user_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('user_changed', COALESCE(NEW.clerk_id, OLD.clerk_id));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_notify_changed ON users;
+CREATE TRIGGER users_notify_changed AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_changed();
+
+CREATE OR REPLACE FUNCTION notify_webhook_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('webhook_events', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS webhook_events_notify ON webhook_events;
+CREATE TRIGGER webhook_events_notify AFTER INSERT ON webhook_events
+    FOR EACH ROW EXECUTE FUNCTION notify_webhook_event();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_email_bidx_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS email_bidx;
@@ -0,0 +1,6 @@
+-- email and phone may hold values encrypted by internal/crypto, which cannot
+-- be compared in SQL. email_bidx is the blind index of the email (a keyed
+-- HMAC of the normalized address) used for exact lookups instead.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_bidx TEXT;
+
+CREATE INDEX IF NOT EXISTS users_email_bidx_idx ON users (email_bidx) WHERE deleted_at IS NULL;
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"errors"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// New returns a migrator that applies the embedded migrations to the
+// database at dsn.
+func New(dsn string) (*migrate.Migrate, error) {
+	src, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithSourceInstance("iofs", src, dsn)
+}
+
+// Up applies every pending embedded migration and returns the resulting
+// version. The postgres driver holds an advisory lock while migrating, so
+// replicas starting together do not race: one migrates, the others wait up
+// to opts.LockTimeout for the lock and then find nothing left to do.
+func Up(dsn string, opts Options) (uint, error) {
+	m, err := Open("", dsn, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, LockError(err)
+	}
+	version, _, err := m.Version()
+	return version, err
+}
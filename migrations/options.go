@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// DefaultTable is golang-migrate's version table.
+const DefaultTable = "schema_migrations"
+
+// ErrLocked is returned when another migration holds the lock for longer
+// than Options.LockTimeout, typically because two deploy jobs overlap.
+var ErrLocked = errors.New("migrations: another migration is running (timed out waiting for its advisory lock)")
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options tune how migrations run against the database.
+type Options struct {
+	// Table records the applied version; DefaultTable when empty. The
+	// advisory lock is derived from it, so runs sharing a table exclude
+	// each other.
+	Table string
+	// LockTimeout bounds the wait for the advisory lock held by a
+	// concurrent run.
+	LockTimeout time.Duration
+	// StatementTimeout caps each migration statement; zero means none.
+	StatementTimeout time.Duration
+}
+
+// LoadOptions reads MIGRATIONS_TABLE, MIGRATIONS_LOCK_TIMEOUT (default 15s)
+// and MIGRATIONS_STATEMENT_TIMEOUT (default none).
+func LoadOptions() (Options, error) {
+	o := Options{Table: os.Getenv("MIGRATIONS_TABLE"), LockTimeout: migrate.DefaultLockTimeout}
+	for name, dst := range map[string]*time.Duration{
+		"MIGRATIONS_LOCK_TIMEOUT":      &o.LockTimeout,
+		"MIGRATIONS_STATEMENT_TIMEOUT": &o.StatementTimeout,
+	} {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return o, fmt.Errorf("%s must be a positive duration, e.g. 30s", name)
+			}
+			*dst = d
+		}
+	}
+	return o, o.validate()
+}
+
+func (o Options) validate() error {
+	if o.Table != "" && !tableName.MatchString(o.Table) {
+		return fmt.Errorf("migrations: table %q must be a plain identifier", o.Table)
+	}
+	return nil
+}
+
+// TableName returns Table or its default.
+func (o Options) TableName() string {
+	if o.Table == "" {
+		return DefaultTable
+	}
+	return o.Table
+}
+
+// databaseURL adds the postgres driver's x- parameters for o to dsn.
+func (o Options) databaseURL(dsn string) (string, error) {
+	if err := o.validate(); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", errors.New("migrations: DATABASE_URL must be a postgres:// URL")
+	}
+	q := u.Query()
+	if o.Table != "" {
+		q.Set("x-migrations-table", o.Table)
+	}
+	if o.StatementTimeout > 0 {
+		q.Set("x-statement-timeout", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Open returns a migrator for sourceURL, or for the embedded migrations
+// when sourceURL is empty, configured with o.
+func Open(sourceURL, dsn string, o Options) (*migrate.Migrate, error) {
+	dbURL, err := o.databaseURL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	var m *migrate.Migrate
+	if sourceURL == "" {
+		m, err = New(dbURL)
+	} else {
+		m, err = migrate.New(sourceURL, dbURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.LockTimeout > 0 {
+		m.LockTimeout = o.LockTimeout
+	}
+	return m, nil
+}
+
+// LockError translates golang-migrate's lock timeout into ErrLocked.
+func LockError(err error) error {
+	if errors.Is(err, migrate.ErrLockTimeout) {
+		return ErrLocked
+	}
+	return err
+}
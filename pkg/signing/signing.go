@@ -0,0 +1,90 @@
+// Package signing implements HMAC signing for internal service-to-service
+// requests. Sibling services use SignRequest (or Transport) to sign outgoing
+// calls; the backend verifies them with Verify.
+//
+// The signature is an HMAC-SHA256 over "METHOD\nPATH\nTIMESTAMP\nBODY",
+// base64-encoded and sent in the X-Internal-Signature header alongside the
+// unix timestamp in X-Internal-Timestamp.
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Internal-Signature"
+	HeaderTimestamp = "X-Internal-Timestamp"
+)
+
+// Sign returns the base64-encoded signature for the given request parts.
+func Sign(secret []byte, method, path, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n"))
+	_, _ = mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature against the request parts and rejects timestamps
+// further than tolerance from now.
+func Verify(secret []byte, method, path, timestamp string, body []byte, signature string, tolerance time.Duration, now time.Time) bool {
+	if len(secret) == 0 || timestamp == "" || signature == "" {
+		return false
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return false
+	}
+	expected := Sign(secret, method, path, timestamp, body)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// SignRequest signs req in place. The body is read and replaced so the
+// request can still be sent.
+func SignRequest(req *http.Request, secret []byte, now time.Time) error {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		_ = req.Body.Close()
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(secret, req.Method, req.URL.Path, timestamp, body))
+	return nil
+}
+
+// Transport is an http.RoundTripper that signs every outgoing request.
+type Transport struct {
+	Secret []byte
+	Base   http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := SignRequest(req, t.Secret, time.Now()); err != nil {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
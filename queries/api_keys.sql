@@ -0,0 +1,7 @@
+-- name: GetActiveAPIKeyByHash :one
+SELECT id, name, scopes
+FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys SET last_used_at = NOW() WHERE id = $1;
@@ -0,0 +1,28 @@
+-- name: UpsertOrganization :exec
+INSERT INTO organizations (clerk_org_id, name, slug, created_at, updated_at)
+VALUES ($1, $2, $3, NOW(), NOW())
+ON CONFLICT (clerk_org_id) DO UPDATE
+SET name       = EXCLUDED.name,
+    slug       = EXCLUDED.slug,
+    deleted_at = NULL,
+    updated_at = NOW();
+
+-- name: SoftDeleteOrganization :exec
+UPDATE organizations
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE clerk_org_id = $1;
+
+-- name: DeleteMembershipsByOrganization :exec
+DELETE FROM memberships WHERE clerk_org_id = $1;
+
+-- name: UpsertMembership :exec
+INSERT INTO memberships (clerk_membership_id, clerk_org_id, clerk_user_id, role, created_at, updated_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW())
+ON CONFLICT (clerk_membership_id) DO UPDATE
+SET clerk_org_id  = EXCLUDED.clerk_org_id,
+    clerk_user_id = EXCLUDED.clerk_user_id,
+    role          = EXCLUDED.role,
+    updated_at    = NOW();
+
+-- name: DeleteMembership :exec
+DELETE FROM memberships WHERE clerk_membership_id = $1;
@@ -0,0 +1,102 @@
+-- name: GetUserDataExport :one
+SELECT
+    clerk_id,
+    name,
+    email,
+    username,
+    first_name,
+    last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    locale,
+    phone,
+    avatar_key
+FROM users
+WHERE clerk_id = $1 AND erased_at IS NULL;
+
+-- name: ListMembershipsByUser :many
+SELECT m.clerk_membership_id, m.clerk_org_id, o.name AS org_name, m.role, m.created_at, m.updated_at
+FROM memberships m
+LEFT JOIN organizations o ON o.clerk_org_id = m.clerk_org_id
+WHERE m.clerk_user_id = $1
+ORDER BY m.created_at;
+
+-- name: ListAllUserEvents :many
+SELECT id, clerk_id, event_type, actor, details, created_at
+FROM user_events
+WHERE clerk_id = $1
+ORDER BY id;
+
+-- name: CreateErasureRequest :one
+INSERT INTO erasure_requests (clerk_id, requested_by)
+VALUES ($1, $2)
+ON CONFLICT (clerk_id) WHERE status = 'pending'
+DO UPDATE SET clerk_id = EXCLUDED.clerk_id
+RETURNING id, status, created_at;
+
+-- name: ClaimErasureRequest :one
+-- Must run inside the transaction that performs the erasure; the row lock
+-- keeps other workers away until it commits or rolls back.
+SELECT id, clerk_id, attempts
+FROM erasure_requests
+WHERE status = 'pending'
+ORDER BY id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CompleteErasureRequest :exec
+UPDATE erasure_requests
+SET status = 'done', error = NULL, completed_at = NOW()
+WHERE id = $1;
+
+-- name: FailErasureRequest :exec
+-- Requests stay pending for another attempt until max_attempts is reached.
+UPDATE erasure_requests
+SET attempts     = attempts + 1,
+    error        = sqlc.arg(error),
+    status       = CASE WHEN attempts + 1 >= sqlc.arg(max_attempts)::int THEN 'failed' ELSE 'pending' END,
+    completed_at = CASE WHEN attempts + 1 >= sqlc.arg(max_attempts)::int THEN NOW() ELSE NULL END
+WHERE id = sqlc.arg(id);
+
+-- name: AnonymizeUser :one
+-- Returns the avatar key the row had, so the object can be deleted too.
+WITH old AS (
+    SELECT o.clerk_id, o.avatar_key FROM users o WHERE o.clerk_id = sqlc.arg(clerk_id)::text FOR UPDATE
+)
+UPDATE users u
+SET name        = 'Deleted user',
+    email       = NULL,
+    email_bidx  = NULL,
+    username    = NULL,
+    first_name  = NULL,
+    last_name   = NULL,
+    locale      = NULL,
+    phone       = NULL,
+    avatar_key  = NULL,
+    preferences = '{}'::jsonb,
+    is_active   = FALSE,
+    deleted_at  = COALESCE(u.deleted_at, NOW()),
+    erased_at   = NOW(),
+    updated_at  = NOW()
+FROM old
+WHERE u.clerk_id = old.clerk_id
+RETURNING old.avatar_key;
+
+-- name: ScrubUserEventDetails :exec
+UPDATE user_events SET details = '{}'::jsonb WHERE clerk_id = $1;
+
+-- name: ScrubWebhookEventPayloads :execrows
+-- Raw Clerk payloads carry names and email addresses; only the type is kept.
+UPDATE webhook_events
+SET payload = jsonb_build_object('type', event_type)
+WHERE ordering_key = sqlc.arg(clerk_id)::text OR payload->'data'->>'id' = sqlc.arg(clerk_id)::text;
+
+-- name: ScrubWebhookDeliveryPayloads :execrows
+UPDATE webhook_deliveries
+SET payload = jsonb_set(payload, '{data}', jsonb_build_object('clerk_id', sqlc.arg(clerk_id)::text))
+WHERE payload->'data'->>'clerk_id' = sqlc.arg(clerk_id)::text;
@@ -0,0 +1,15 @@
+-- name: InsertUserEvent :exec
+INSERT INTO user_events (clerk_id, event_type, actor, details)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListUserEvents :many
+-- Newest first; before_id is the cursor from the previous page.
+SELECT id, clerk_id, event_type, actor, details, created_at
+FROM user_events
+WHERE clerk_id = sqlc.arg(clerk_id)
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id)::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountUserEvents :one
+SELECT COUNT(*) FROM user_events WHERE clerk_id = $1;
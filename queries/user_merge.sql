@@ -0,0 +1,47 @@
+-- name: LockUsersForMerge :many
+-- Locks both rows in a stable order so concurrent merges cannot deadlock.
+SELECT clerk_id, deleted_at, merged_into
+FROM users
+WHERE clerk_id = ANY(sqlc.arg(clerk_ids)::text[])
+ORDER BY clerk_id
+FOR UPDATE;
+
+-- name: DropOverlappingMemberships :execrows
+-- Memberships of the duplicate in organizations the survivor already
+-- belongs to cannot be moved without violating memberships_org_user_uq.
+DELETE FROM memberships d
+WHERE d.clerk_user_id = sqlc.arg(duplicate_id)
+  AND EXISTS (
+      SELECT 1 FROM memberships s
+      WHERE s.clerk_user_id = sqlc.arg(survivor_id) AND s.clerk_org_id = d.clerk_org_id
+  );
+
+-- name: MoveMemberships :execrows
+UPDATE memberships
+SET clerk_user_id = sqlc.arg(survivor_id), updated_at = NOW()
+WHERE clerk_user_id = sqlc.arg(duplicate_id);
+
+-- name: MoveUserEvents :execrows
+UPDATE user_events
+SET clerk_id = sqlc.arg(survivor_id)
+WHERE clerk_id = sqlc.arg(duplicate_id);
+
+-- name: InheritUserProfile :exec
+-- Fills the survivor's empty optional fields from the duplicate.
+UPDATE users s
+SET email      = COALESCE(s.email, d.email),
+    email_bidx = CASE WHEN s.email IS NULL THEN d.email_bidx ELSE s.email_bidx END,
+    avatar_key = COALESCE(s.avatar_key, d.avatar_key),
+    locale     = COALESCE(s.locale, d.locale),
+    phone      = COALESCE(s.phone, d.phone),
+    updated_at = NOW()
+FROM users d
+WHERE s.clerk_id = sqlc.arg(survivor_id) AND d.clerk_id = sqlc.arg(duplicate_id);
+
+-- name: MarkUserMerged :exec
+UPDATE users
+SET merged_into = sqlc.arg(survivor_id)::text,
+    deleted_at  = NOW(),
+    is_active   = FALSE,
+    updated_at  = NOW()
+WHERE clerk_id = sqlc.arg(duplicate_id);
@@ -1,22 +1,35 @@
--- name: UpsertUserWithRole :exec
-INSERT INTO users (clerk_id, username, name, email, first_name, last_name, role, is_active, created_at, updated_at)
+-- name: UpsertUserWithRole :execrows
+-- initial_role only applies when the row is inserted; an existing user's
+-- role is never changed by a sync. Rows merged into another user stay
+-- deleted, and erased rows are not touched at all. A row older than the
+-- last applied one (by Clerk's updated_at) is stale and skipped. Skipped
+-- rows report 0 rows affected.
+-- email_bidx must be the blind index of email and is kept with it.
+INSERT INTO users (clerk_id, username, name, email, first_name, last_name, role, is_active, created_at, updated_at, clerk_updated_at, email_bidx)
 VALUES (
   $1, $2, $3, $4, $5, $6,
   CASE WHEN NOT EXISTS (SELECT 1 FROM users WHERE deleted_at IS NULL)
        THEN 'superadmin'
-       ELSE 'user'
+       ELSE COALESCE(sqlc.narg(initial_role)::text, 'user')
   END,
-  TRUE, NOW(), NOW()
+  TRUE, NOW(), NOW(), sqlc.narg(clerk_updated_at), sqlc.narg(email_bidx)
 )
 ON CONFLICT (clerk_id) DO UPDATE
-SET username   = EXCLUDED.username,
-    name       = EXCLUDED.name,
-    first_name = EXCLUDED.first_name,
-    last_name  = EXCLUDED.last_name,
-    email      = COALESCE(EXCLUDED.email, users.email),
-    is_active  = TRUE,
-    deleted_at = NULL,
-    updated_at = NOW();
+SET username         = EXCLUDED.username,
+    name             = EXCLUDED.name,
+    first_name       = EXCLUDED.first_name,
+    last_name        = EXCLUDED.last_name,
+    email            = COALESCE(EXCLUDED.email, users.email),
+    email_bidx       = CASE WHEN EXCLUDED.email IS NULL THEN users.email_bidx ELSE EXCLUDED.email_bidx END,
+    is_active        = users.merged_into IS NULL,
+    deleted_at       = CASE WHEN users.merged_into IS NULL THEN NULL ELSE users.deleted_at END,
+    clerk_updated_at = COALESCE(EXCLUDED.clerk_updated_at, users.clerk_updated_at),
+    version          = users.version + 1,
+    updated_at       = NOW()
+WHERE users.erased_at IS NULL
+  AND (EXCLUDED.clerk_updated_at IS NULL
+       OR users.clerk_updated_at IS NULL
+       OR EXCLUDED.clerk_updated_at >= users.clerk_updated_at);
 
 -- name: ListUsers :many
 SELECT
@@ -31,23 +44,288 @@ SELECT
     created_at,
     updated_at,
     deleted_at,
-    last_login_at
+    last_login_at,
+    banned_at
 FROM users
 WHERE deleted_at IS NULL
 ORDER BY created_at DESC, clerk_id DESC;
 
--- name: DeleteUserByClerkID :exec
-DELETE FROM users WHERE clerk_id = $1;
+-- name: ListUsersPage :many
+-- Keyset pagination over (created_at, clerk_id) when a cursor is given;
+-- row_offset supports plain offset paging for clients that need page numbers.
+-- q is matched as a substring, so callers must escape LIKE wildcards.
+-- Encrypted emails only match exactly, through q_bidx (the blind index of
+-- the unescaped q).
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone
+FROM users
+WHERE deleted_at IS NULL
+  AND (sqlc.narg(q)::text IS NULL
+       OR name ILIKE '%' || sqlc.narg(q)::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || sqlc.narg(q)::text || '%')
+       OR email_bidx = sqlc.narg(q_bidx)::text
+       OR username ILIKE '%' || sqlc.narg(q)::text || '%')
+  AND (sqlc.narg(has_email)::boolean IS NULL OR (COALESCE(email, '') <> '') = sqlc.narg(has_email)::boolean)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at > sqlc.narg(created_after)::timestamptz)
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz)
+  AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+       OR (created_at, clerk_id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_clerk_id)::text))
+ORDER BY created_at DESC, clerk_id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountUsers :one
+-- Takes the same filters as ListUsersPage.
+SELECT COUNT(*) FROM users
+WHERE deleted_at IS NULL
+  AND (sqlc.narg(q)::text IS NULL
+       OR name ILIKE '%' || sqlc.narg(q)::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || sqlc.narg(q)::text || '%')
+       OR email_bidx = sqlc.narg(q_bidx)::text
+       OR username ILIKE '%' || sqlc.narg(q)::text || '%')
+  AND (sqlc.narg(has_email)::boolean IS NULL OR (COALESCE(email, '') <> '') = sqlc.narg(has_email)::boolean)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at > sqlc.narg(created_after)::timestamptz)
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz);
+
+-- name: ListUsersPageIncludingDeleted :many
+-- Admin-only twin of ListUsersPage that also returns soft-deleted users.
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone
+FROM users
+WHERE (sqlc.narg(q)::text IS NULL
+       OR name ILIKE '%' || sqlc.narg(q)::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || sqlc.narg(q)::text || '%')
+       OR email_bidx = sqlc.narg(q_bidx)::text
+       OR username ILIKE '%' || sqlc.narg(q)::text || '%')
+  AND (sqlc.narg(has_email)::boolean IS NULL OR (COALESCE(email, '') <> '') = sqlc.narg(has_email)::boolean)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at > sqlc.narg(created_after)::timestamptz)
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz)
+  AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+       OR (created_at, clerk_id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_clerk_id)::text))
+ORDER BY created_at DESC, clerk_id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountUsersIncludingDeleted :one
+-- Takes the same filters as ListUsersPageIncludingDeleted.
+SELECT COUNT(*) FROM users
+WHERE (sqlc.narg(q)::text IS NULL
+       OR name ILIKE '%' || sqlc.narg(q)::text || '%'
+       OR (email NOT LIKE 'enc:%' AND email ILIKE '%' || sqlc.narg(q)::text || '%')
+       OR email_bidx = sqlc.narg(q_bidx)::text
+       OR username ILIKE '%' || sqlc.narg(q)::text || '%')
+  AND (sqlc.narg(has_email)::boolean IS NULL OR (COALESCE(email, '') <> '') = sqlc.narg(has_email)::boolean)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at > sqlc.narg(created_after)::timestamptz)
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before)::timestamptz);
+
+-- name: GetUserByClerkID :one
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version
+FROM users
+WHERE clerk_id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserByClerkIDIncludingDeleted :one
+-- Admin-only twin of GetUserByClerkID that also finds soft-deleted users.
+SELECT
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version
+FROM users
+WHERE clerk_id = $1;
+
+-- name: UpdateUserProfile :one
+-- Partial update: NULL leaves a column unchanged, an empty string clears the
+-- optional ones. With expected_version set, a row whose version differs is
+-- left alone and no row is returned.
+UPDATE users
+SET name       = COALESCE(sqlc.narg(name)::text, name),
+    username   = CASE WHEN sqlc.narg(username)::text IS NULL THEN username ELSE NULLIF(sqlc.narg(username)::text, '') END,
+    locale     = CASE WHEN sqlc.narg(locale)::text IS NULL THEN locale ELSE NULLIF(sqlc.narg(locale)::text, '') END,
+    phone      = CASE WHEN sqlc.narg(phone)::text IS NULL THEN phone ELSE NULLIF(sqlc.narg(phone)::text, '') END,
+    version    = version + 1,
+    updated_at = NOW()
+WHERE clerk_id = sqlc.arg(clerk_id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version)::bigint)
+RETURNING
+    COALESCE(clerk_id, '')::text      AS clerk_id,
+    name,
+    COALESCE(email, '')::text         AS email,
+    COALESCE(username, '')::text      AS username,
+    COALESCE(first_name, '')::text    AS first_name,
+    COALESCE(last_name, '')::text     AS last_name,
+    role,
+    is_active,
+    created_at,
+    updated_at,
+    deleted_at,
+    last_login_at,
+    banned_at,
+    COALESCE(locale, '')::text        AS locale,
+    COALESCE(phone, '')::text         AS phone,
+    version;
 
 -- name: SoftDeleteUserByClerkID :exec
+-- Users are never hard-deleted; list and get queries skip soft-deleted rows.
 UPDATE users
 SET deleted_at = NOW(), is_active = FALSE, updated_at = NOW()
 WHERE clerk_id = $1;
 
--- name: UpdateLastLogin :exec
+-- name: RestoreUser :execrows
 UPDATE users
-SET last_login_at = NOW(), updated_at = NOW()
+SET deleted_at = NULL, merged_into = NULL, is_active = TRUE, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: GetUserSyncState :one
+-- Raw columns compared by cmd/sync-clerk, including soft-deleted rows.
+SELECT clerk_id, name, email, username, first_name, last_name, deleted_at, banned_at
+FROM users
 WHERE clerk_id = $1;
 
+-- name: ListActiveClerkIDs :many
+SELECT clerk_id FROM users WHERE deleted_at IS NULL ORDER BY clerk_id;
+
+-- name: EmailInUse :one
+-- Plaintext rows match on the address, encrypted ones on the blind index.
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE deleted_at IS NULL
+      AND (lower(email) = lower(sqlc.arg(email)::text) OR email_bidx = sqlc.narg(email_bidx)::text)
+);
+
+-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE clerk_id = $1);
+
+-- name: UpdateLastLogin :exec
+-- Events can be processed late or out of order, so last_login_at only ever
+-- moves forward.
+UPDATE users
+SET last_login_at = GREATEST(last_login_at, sqlc.arg(logged_in_at)::timestamptz),
+    updated_at    = NOW()
+WHERE clerk_id = sqlc.arg(clerk_id);
+
 -- name: GetUserRole :one
-SELECT role FROM users WHERE clerk_id = $1 AND is_active = TRUE AND deleted_at IS NULL;
+SELECT role FROM users WHERE clerk_id = $1 AND is_active = TRUE AND deleted_at IS NULL AND banned_at IS NULL;
+
+-- name: ListUsersByOrganization :many
+SELECT
+    COALESCE(u.clerk_id, '')::text      AS clerk_id,
+    u.name,
+    COALESCE(u.email, '')::text         AS email,
+    COALESCE(u.username, '')::text      AS username,
+    COALESCE(u.first_name, '')::text    AS first_name,
+    COALESCE(u.last_name, '')::text     AS last_name,
+    u.role,
+    u.is_active,
+    u.created_at,
+    u.updated_at,
+    u.deleted_at,
+    u.last_login_at,
+    u.banned_at
+FROM users u
+JOIN memberships m ON m.clerk_user_id = u.clerk_id
+WHERE m.clerk_org_id = $1 AND u.deleted_at IS NULL
+ORDER BY u.created_at DESC, u.clerk_id DESC;
+
+-- name: SetUserBanned :execrows
+UPDATE users
+SET banned_at  = CASE WHEN sqlc.arg(banned)::boolean THEN COALESCE(banned_at, NOW()) ELSE NULL END,
+    updated_at = NOW()
+WHERE clerk_id = $1;
+
+-- name: SetUserAvatar :execrows
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserAvatarKey :one
+SELECT avatar_key FROM users WHERE clerk_id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserPreferences :one
+SELECT preferences FROM users WHERE clerk_id = $1 AND deleted_at IS NULL;
+
+-- name: SetUserPreferences :execrows
+UPDATE users
+SET preferences = $2, updated_at = NOW()
+WHERE clerk_id = $1 AND deleted_at IS NULL;
+
+-- name: UsersFingerprint :one
+-- Cheap change detector for ETags: any insert, update or soft delete bumps
+-- either the count or the latest updated_at.
+SELECT
+    COUNT(*) FILTER (WHERE deleted_at IS NULL) AS active_count,
+    COALESCE(MAX(updated_at), 'epoch'::timestamptz)::timestamptz AS last_updated_at
+FROM users;
+
+-- name: ListUserPIIPage :many
+-- Raw encrypted columns for cmd/rotate-pii, keyset-paginated by clerk_id.
+SELECT clerk_id, email, phone
+FROM users
+WHERE clerk_id > sqlc.arg(after)::text
+  AND (email IS NOT NULL OR phone IS NOT NULL)
+ORDER BY clerk_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: RewriteUserPII :execrows
+-- Replaces email and phone with re-encrypted values, unless either changed
+-- since it was read. It is not a profile change, so version is left alone.
+UPDATE users
+SET email      = sqlc.narg(new_email)::text,
+    email_bidx = sqlc.narg(email_bidx)::text,
+    phone      = sqlc.narg(new_phone)::text
+WHERE clerk_id = sqlc.arg(clerk_id)
+  AND email IS NOT DISTINCT FROM sqlc.narg(old_email)::text
+  AND phone IS NOT DISTINCT FROM sqlc.narg(old_phone)::text;
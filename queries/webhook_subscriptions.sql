@@ -0,0 +1,65 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, secret, event_types, active, created_at, updated_at)
+VALUES ($1, $2, $3, TRUE, NOW(), NOW())
+RETURNING id, url, event_types, active, created_at, updated_at;
+
+-- name: ListWebhookSubscriptions :many
+SELECT id, url, event_types, active, created_at, updated_at
+FROM webhook_subscriptions
+ORDER BY id;
+
+-- name: GetWebhookSubscription :one
+SELECT id, url, event_types, active, created_at, updated_at
+FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url         = COALESCE(sqlc.narg(url), url),
+    event_types = COALESCE(sqlc.narg(event_types)::text[], event_types),
+    active      = COALESCE(sqlc.narg(active), active),
+    secret      = COALESCE(sqlc.narg(secret), secret),
+    updated_at  = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING id, url, event_types, active, created_at, updated_at;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+-- Fans one event out to every active subscriber interested in its type.
+INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+SELECT id, sqlc.arg(event_type)::text, sqlc.arg(payload)::jsonb
+FROM webhook_subscriptions
+WHERE active AND (cardinality(event_types) = 0 OR sqlc.arg(event_type)::text = ANY(event_types));
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET status          = 'delivering',
+    attempts        = d.attempts + 1,
+    next_attempt_at = NOW() + (sqlc.arg(lease_seconds)::int * INTERVAL '1 second')
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id
+  AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status IN ('pending', 'delivering', 'failed') AND next_attempt_at <= NOW()
+    ORDER BY id
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event_type, d.payload, d.attempts, s.url, s.secret;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', last_error = NULL, response_status = $2, delivered_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = 'failed', last_error = $2, response_status = $3, next_attempt_at = $4
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryDead :exec
+UPDATE webhook_deliveries
+SET status = 'dead', last_error = $2, response_status = $3
+WHERE id = $1;
@@ -0,0 +1,105 @@
+-- name: IsWebhookProcessed :one
+SELECT EXISTS (SELECT 1 FROM processed_webhooks WHERE svix_id = $1);
+
+-- name: MarkWebhookProcessed :exec
+INSERT INTO processed_webhooks (svix_id, event_type, processed_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (svix_id) DO NOTHING;
+
+-- name: DeleteProcessedWebhooksBefore :execrows
+DELETE FROM processed_webhooks WHERE processed_at < $1;
+
+-- name: InsertWebhookEvent :one
+-- Returns no row when the svix-id is already queued; the worker owns it.
+INSERT INTO webhook_events (svix_id, event_type, payload, ordering_key, occurred_at, status, received_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, 'received', NOW(), NOW())
+ON CONFLICT (svix_id) DO NOTHING
+RETURNING id;
+
+-- name: ClaimDueWebhookEvents :many
+-- Claimed rows get a lease: if the worker dies mid-batch they become due
+-- again once next_attempt_at passes. An event is only claimed when no older
+-- event with the same ordering_key is still unfinished, which serializes
+-- processing per Clerk object.
+UPDATE webhook_events
+SET status          = 'processing',
+    attempts        = attempts + 1,
+    next_attempt_at = NOW() + (sqlc.arg(lease_seconds)::int * INTERVAL '1 second')
+WHERE id IN (
+    SELECT e.id FROM webhook_events e
+    WHERE e.status IN ('received', 'processing', 'failed') AND e.next_attempt_at <= NOW()
+      AND (e.ordering_key IS NULL OR NOT EXISTS (
+          SELECT 1 FROM webhook_events prev
+          WHERE prev.ordering_key = e.ordering_key
+            AND prev.status IN ('received', 'processing', 'failed')
+            AND (prev.occurred_at, prev.id) < (e.occurred_at, e.id)
+      ))
+    ORDER BY e.occurred_at, e.id
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, svix_id, event_type, payload, attempts;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET status = 'processed', error = NULL, error_code = NULL, processed_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET status = 'failed', error = $2, next_attempt_at = $3
+WHERE id = $1;
+
+-- name: MarkWebhookEventDead :exec
+UPDATE webhook_events
+SET status = 'dead', error = $2, error_code = $3, processed_at = NOW()
+WHERE id = $1;
+
+-- name: ListWebhookEvents :many
+-- Newest first; before_id is the cursor from the previous page.
+SELECT id, svix_id, event_type, status, error, error_code, attempts, received_at, processed_at, next_attempt_at
+FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id)::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountWebhookEvents :one
+SELECT COUNT(*)
+FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status));
+
+-- name: GetWebhookEvent :one
+SELECT id, svix_id, event_type, payload, status, error, error_code, attempts, received_at, processed_at, next_attempt_at
+FROM webhook_events
+WHERE id = $1;
+
+-- name: RequeueWebhookEvent :execrows
+-- Only settled events can be replayed; rows still queued or in flight are
+-- left to the worker.
+UPDATE webhook_events
+SET status          = 'received',
+    attempts        = 0,
+    error           = NULL,
+    error_code      = NULL,
+    processed_at    = NULL,
+    next_attempt_at = NOW()
+WHERE id = $1 AND status IN ('failed', 'dead', 'processed');
+
+-- name: CountDeadWebhookEvents :one
+SELECT COUNT(*) FROM webhook_events
+WHERE status = 'dead'
+  AND processed_at >= sqlc.arg(since)
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type));
+
+-- name: RequeueDeadWebhookEvents :execrows
+UPDATE webhook_events
+SET status          = 'received',
+    attempts        = 0,
+    error           = NULL,
+    error_code      = NULL,
+    processed_at    = NULL,
+    next_attempt_at = NOW()
+WHERE status = 'dead'
+  AND processed_at >= sqlc.arg(since)
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type));
@@ -0,0 +1,12 @@
+-- Demo users for local development. Their Clerk IDs do not exist in Clerk,
+-- so they are for API keys and direct queries, not for signing in.
+--
+-- Note that with these rows present the first real user to sign in is not
+-- made superadmin; promote yourself with
+--   UPDATE users SET role = 'superadmin' WHERE clerk_id = '<your id>';
+INSERT INTO users (clerk_id, name, email, username, first_name, last_name, role)
+VALUES
+    ('user_demo_superadmin', 'Demo Superadmin', 'superadmin@example.com', 'demo_superadmin', 'Demo', 'Superadmin', 'superadmin'),
+    ('user_demo_admin',      'Demo Admin',      'admin@example.com',      'demo_admin',      'Demo', 'Admin',      'admin'),
+    ('user_demo_user',       'Demo User',       'user@example.com',       'demo_user',       'Demo', 'User',       'user')
+ON CONFLICT (clerk_id) DO NOTHING;
@@ -0,0 +1,9 @@
+INSERT INTO organizations (clerk_org_id, name, slug)
+VALUES ('org_demo', 'Demo Organization', 'demo')
+ON CONFLICT (clerk_org_id) DO NOTHING;
+
+INSERT INTO memberships (clerk_membership_id, clerk_org_id, clerk_user_id, role)
+VALUES
+    ('orgmem_demo_admin', 'org_demo', 'user_demo_admin', 'org:admin'),
+    ('orgmem_demo_user',  'org_demo', 'user_demo_user',  'org:member')
+ON CONFLICT (clerk_membership_id) DO NOTHING;
@@ -0,0 +1,6 @@
+-- The raw key is "dev-api-key"; send it as X-API-Key. Never seed this
+-- outside local development.
+INSERT INTO api_keys (name, key_hash, scopes)
+VALUES ('local development', '6e1e4e1b8f8b36d08901cdb51b97841dfe20f5efd2fd2fd00768971408c46274',
+        '{users:read,users:write,webhooks:replay,webhooks:manage,audit:read}')
+ON CONFLICT (key_hash) DO NOTHING;
@@ -0,0 +1,10 @@
+// Package seeds embeds the data that stands up a non-production database:
+// one directory per environment, applied by `cmd/migrate seed --env <env>`
+// in file name order. Every file must be idempotent, since seeding is
+// re-run freely, and may assume the schema is fully migrated.
+package seeds
+
+import "embed"
+
+//go:embed */*.sql
+var FS embed.FS
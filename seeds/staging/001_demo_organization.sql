@@ -0,0 +1,6 @@
+-- Staging users come from the staging Clerk instance through webhooks; only
+-- the shared QA organization is seeded. Clerk's own organization of the
+-- same ID replaces the name and slug on its first webhook.
+INSERT INTO organizations (clerk_org_id, name, slug)
+VALUES ('org_qa', 'QA', 'qa')
+ON CONFLICT (clerk_org_id) DO NOTHING;